@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"shopedia-api/internal/repository"
+	utils "shopedia-api/internal/util"
+
+	"github.com/joho/godotenv"
+)
+
+// Admin command untuk kelola JWT signing keys
+//
+//	go run ./cmd/jwtkeys list
+//	go run ./cmd/jwtkeys rotate [-alg EdDSA|RS256] [-retain 24h]
+//
+// Rotasi membuat key aktif baru; key lama tetap memverifikasi token sampai -retain,
+// dan API server mengambil key baru dalam ±1 menit tanpa restart.
+func main() {
+	// Load .env file (for local development)
+	godotenv.Load()
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	db, err := repository.ConnectDB(os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "list":
+		rows, err := db.Query(ctx, `
+			SELECT kid, algorithm, status, created_at, verify_until
+			FROM jwt_keys ORDER BY created_at DESC`)
+		if err != nil {
+			log.Fatalf("List keys failed: %v", err)
+		}
+		defer rows.Close()
+
+		fmt.Printf("%-36s  %-6s  %-8s  %-20s  %s\n", "KID", "ALG", "STATUS", "CREATED", "VERIFY UNTIL")
+		for rows.Next() {
+			var kid, alg, status string
+			var createdAt time.Time
+			var verifyUntil *time.Time
+			if err := rows.Scan(&kid, &alg, &status, &createdAt, &verifyUntil); err != nil {
+				log.Fatalf("Scan key failed: %v", err)
+			}
+			until := "-"
+			if verifyUntil != nil {
+				until = verifyUntil.Format(time.RFC3339)
+			}
+			fmt.Printf("%-36s  %-6s  %-8s  %-20s  %s\n", kid, alg, status, createdAt.Format(time.RFC3339), until)
+		}
+
+	case "rotate":
+		fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+		alg := fs.String("alg", utils.SigningAlgorithm(), "signing algorithm: EdDSA or RS256")
+		retain := fs.Duration("retain", utils.DefaultKeyRetention, "berapa lama key lama tetap bisa memverifikasi token")
+		fs.Parse(os.Args[2:])
+
+		if *retain < utils.AccessTokenTTL {
+			log.Fatalf("-retain must be at least %s (access token lifetime)", utils.AccessTokenTTL)
+		}
+
+		kid, err := utils.RotateSigningKey(ctx, db, *alg, *retain)
+		if err != nil {
+			log.Fatalf("Rotate key failed: %v", err)
+		}
+		log.Printf("New active key %s (%s); previous key verifies tokens for %s", kid, *alg, *retain)
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jwtkeys list | jwtkeys rotate [-alg EdDSA|RS256] [-retain 24h]")
+	os.Exit(2)
+}
@@ -64,8 +64,12 @@ func main() {
 	mux.HandleFunc(queue.TypeSendOTP, handler.HandleSendOTP)
 	mux.HandleFunc(queue.TypeSendWelcome, handler.HandleSendWelcome)
 	mux.HandleFunc(queue.TypeSendPasswordReset, handler.HandleSendPasswordReset)
+	mux.HandleFunc(queue.TypeSendInvite, handler.HandleSendInvite)
+	mux.HandleFunc(queue.TypeSendShopInvite, handler.HandleSendShopInvite)
+	mux.HandleFunc(queue.TypeSendLoginAlert, handler.HandleSendLoginAlert)
 	mux.HandleFunc(queue.TypeNotification, handler.HandleNotification)
 	mux.HandleFunc(queue.TypeProductIndexing, handler.HandleProductIndexing)
+	mux.HandleFunc(queue.TypeFinanceExport, handler.HandleFinanceExport)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
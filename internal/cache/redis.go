@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -17,19 +18,28 @@ var ctx = context.Background()
 const (
 	PrefixCategory    = "cache:category:"
 	PrefixCategories  = "cache:categories"
+	PrefixBanners     = "cache:banners"
 	PrefixSession     = "session:"
 	PrefixOTP         = "otp:"
 	PrefixRateLimit   = "ratelimit:"
 	PrefixResetToken  = "reset_token:"
+	PrefixLoginFail   = "login_fail:"
+	PrefixLoginLock   = "login_lock:"
+	PrefixAuthContext = "authctx:"
+	PrefixAuthVersion = "authver:"
 )
 
 // Default TTLs
 const (
 	TTLCategories  = 1 * time.Hour
+	TTLBanners     = 1 * time.Hour
 	TTLSession     = 24 * time.Hour
 	TTLOTP         = 5 * time.Minute
 	TTLResetToken  = 1 * time.Hour
 	TTLRateLimit   = 1 * time.Minute
+	TTLLoginFail   = 24 * time.Hour
+	TTLAuthContext = 10 * time.Minute
+	TTLAuthVersion = 24 * time.Hour
 )
 
 // InitRedis initializes the Redis client
@@ -127,6 +137,29 @@ func InvalidateCategories() error {
 	return Delete(PrefixCategories)
 }
 
+// ============================================
+// Banners Cache
+// ============================================
+
+// SetBanners caches the active banners list. ttl is capped at TTLBanners so the
+// caller can expire the key exactly at the next schedule boundary.
+func SetBanners(banners interface{}, ttl time.Duration) error {
+	if ttl <= 0 || ttl > TTLBanners {
+		ttl = TTLBanners
+	}
+	return Set(PrefixBanners, banners, ttl)
+}
+
+// GetBanners retrieves cached active banners
+func GetBanners(dest interface{}) error {
+	return Get(PrefixBanners, dest)
+}
+
+// InvalidateBanners removes banners from cache
+func InvalidateBanners() error {
+	return Delete(PrefixBanners)
+}
+
 // ============================================
 // Session Cache
 // ============================================
@@ -135,7 +168,7 @@ type SessionData struct {
 	UserID    int       `json:"user_id"`
 	JTI       string    `json:"jti"`
 	Email     string    `json:"email"`
-	Role      string    `json:"role"`
+	Roles     []string  `json:"roles"`
 	Scope     string    `json:"scope"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -234,6 +267,107 @@ func DeleteOTP(email string) error {
 	return Delete(PrefixOTP + email)
 }
 
+// ============================================
+// Login Lockout
+// ============================================
+
+// IncrementLoginFailures increases failed login count for an email (window TTLLoginFail)
+func IncrementLoginFailures(email string) (int, error) {
+	key := PrefixLoginFail + email
+	count, err := Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		Client.Expire(ctx, key, TTLLoginFail)
+	}
+	return int(count), nil
+}
+
+// SetLoginLock locks login for an email until the given time
+func SetLoginLock(email string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return Client.Set(ctx, PrefixLoginLock+email, until.Unix(), ttl).Err()
+}
+
+// GetLoginLock returns lock expiry for an email (zero time if not locked)
+func GetLoginLock(email string) (time.Time, error) {
+	unix, err := Client.Get(ctx, PrefixLoginLock+email).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// ClearLoginFailures removes failed login count and lock for an email
+func ClearLoginFailures(email string) error {
+	return Client.Del(ctx, PrefixLoginFail+email, PrefixLoginLock+email).Err()
+}
+
+// ============================================
+// Auth Context Cache
+// ============================================
+
+const authVersionGlobal = "global"
+
+// GetAuthContext reads cached auth context together with the current user and
+// global versions in one round trip. found is false on cache miss.
+func GetAuthContext(userID int, dest interface{}) (found bool, userVersion, globalVersion int64, err error) {
+	id := strconv.Itoa(userID)
+	vals, err := Client.MGet(ctx, PrefixAuthContext+id, PrefixAuthVersion+id, PrefixAuthVersion+authVersionGlobal).Result()
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	userVersion = parseVersion(vals[1])
+	globalVersion = parseVersion(vals[2])
+
+	data, ok := vals[0].(string)
+	if !ok {
+		return false, userVersion, globalVersion, nil
+	}
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return false, userVersion, globalVersion, nil
+	}
+	return true, userVersion, globalVersion, nil
+}
+
+// SetAuthContext stores auth context for a user
+func SetAuthContext(userID int, data interface{}) error {
+	return Set(PrefixAuthContext+strconv.Itoa(userID), data, TTLAuthContext)
+}
+
+// BumpUserAuthVersion invalidates cached auth context of one user
+func BumpUserAuthVersion(userID int) error {
+	key := PrefixAuthVersion + strconv.Itoa(userID)
+	pipe := Client.TxPipeline()
+	pipe.Incr(ctx, key)
+	// Version key hidup lebih lama dari cache entry, jadi reset ke 0 setelah expire aman
+	pipe.Expire(ctx, key, TTLAuthVersion)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// BumpGlobalAuthVersion invalidates cached auth context of all users
+// (role/permission definitions changed)
+func BumpGlobalAuthVersion() error {
+	return Client.Incr(ctx, PrefixAuthVersion+authVersionGlobal).Err()
+}
+
+func parseVersion(v interface{}) int64 {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
 // ============================================
 // Password Reset Token Cache
 // ============================================
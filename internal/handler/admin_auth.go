@@ -3,7 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
-	"net/smtp"
+	"log"
 	"os"
 	"time"
 
@@ -11,6 +11,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
+
+	"shopedia-api/internal/queue"
+	utils "shopedia-api/internal/util"
 )
 
 func AdminRegisterHandler(db *pgxpool.Pool) fiber.Handler {
@@ -85,31 +88,48 @@ func InviteUserHandler(db *pgxpool.Pool) fiber.Handler {
 
 		ctx := context.Background()
 
-		_, err := db.Exec(ctx,
-			`INSERT INTO users (email, is_active, is_invited, invited_at) VALUES ($1, FALSE, TRUE, NOW())`,
-			input.Email)
+		// User, role, invite token dan outbox email ditulis dalam satu transaksi
+		tx, err := db.Begin(ctx)
 		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "User insert failed")
+			return fiber.ErrInternalServerError
 		}
+		defer tx.Rollback(ctx)
 
 		var userID int
-		_ = db.QueryRow(ctx, `SELECT id FROM users WHERE email=$1`, input.Email).Scan(&userID)
+		err = tx.QueryRow(ctx,
+			`INSERT INTO users (email, is_active, is_invited, invited_at) VALUES ($1, FALSE, TRUE, NOW()) RETURNING id`,
+			input.Email).Scan(&userID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "User insert failed")
+		}
 
-		_, err = db.Exec(ctx, `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2)`, userID, input.RoleID)
+		_, err = tx.Exec(ctx, `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2)`, userID, input.RoleID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "user_roles insert failed")
 		}
 
 		inviteToken := uuid.New().String()
 		expires := time.Now().Add(24 * time.Hour)
-		_, err = db.Exec(ctx, `
+		_, err = tx.Exec(ctx, `
 			INSERT INTO invite_tokens (user_id, token, expires_at) VALUES ($1, $2, $3)`,
 			userID, inviteToken, expires)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "invite_tokens insert failed")
 		}
 
-		sendInvite(input.Email, inviteToken)
+		inviteLink := fmt.Sprintf("%s/accept-invite?token=%s", os.Getenv("FRONTEND_URL"), inviteToken)
+		task, err := queue.NewSendInviteTask(input.Email, inviteLink)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if err := queue.AddToOutbox(ctx, tx, task, "default"); err != nil {
+			log.Printf("Invite outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
 
 		return c.JSON(fiber.Map{"message": "Invite sent"})
 	}
@@ -130,7 +150,7 @@ func AcceptInviteHandler(db *pgxpool.Pool) fiber.Handler {
 		var userID int
 		err := db.QueryRow(ctx, `
 			SELECT user_id FROM invite_tokens 
-			WHERE token=$1 AND is_used=FALSE AND expires_at > NOW()`,
+			WHERE token=$1 AND is_used=FALSE AND expires_at > NOW() AND shop_member_id IS NULL`,
 			input.InviteToken).Scan(&userID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired invite token")
@@ -141,10 +161,11 @@ func AcceptInviteHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.ErrInternalServerError
 		}
 
-		_, err = db.Exec(ctx, `UPDATE users SET password_hash=$1, is_active=TRUE WHERE id=$2`, string(hash), userID)
+		_, err = db.Exec(ctx, `UPDATE users SET password_hash=$1, is_active=TRUE, email_verified_at=COALESCE(email_verified_at, NOW()) WHERE id=$2`, string(hash), userID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Update user password failed")
 		}
+		utils.InvalidateAuthContext(userID)
 		_, err = db.Exec(ctx, `UPDATE invite_tokens SET is_used=TRUE WHERE token=$1`, input.InviteToken)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Update invite_token failed")
@@ -153,11 +174,3 @@ func AcceptInviteHandler(db *pgxpool.Pool) fiber.Handler {
 		return c.JSON(fiber.Map{"message": "Account activated, you can login now"})
 	}
 }
-
-func sendInvite(email, token string) {
-	auth := smtp.PlainAuth("", os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"), os.Getenv("SMTP_HOST"))
-	to := []string{email}
-	msg := []byte("Subject: Admin Invite\n\nAccept your invite: https://yourapp.com/accept-invite?token=" + token)
-	smtp.SendMail(fmt.Sprintf("%s:%s", os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT")), auth,
-		"noreply@myapp.com", to, msg)
-}
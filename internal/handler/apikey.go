@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	utils "shopedia-api/internal/util"
+)
+
+const (
+	maxAPIKeysPerUser  = 10
+	defaultAPIKeyDays  = 90
+	maxAPIKeyDays      = 365
+	defaultAPIKeyLimit = 60
+	maxAPIKeyRateLimit = 600
+)
+
+// ============================================
+// API Key Response Types
+// ============================================
+
+type APIKeyResponse struct {
+	UUID       string     `json:"uuid"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ============================================
+// API Key Handlers
+// ============================================
+
+// ListMyAPIKeysHandler - GET /api/app/my/api-keys - list API key milik user
+func ListMyAPIKeysHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT uuid, name, prefix, scopes, rate_limit, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+			FROM api_keys
+			WHERE user_id = $1
+			ORDER BY created_at DESC`, userID)
+		if err != nil {
+			log.Printf("List API keys error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		keys := []APIKeyResponse{}
+		for rows.Next() {
+			var k APIKeyResponse
+			if err := rows.Scan(&k.UUID, &k.Name, &k.Prefix, &k.Scopes, &k.RateLimit, &k.ExpiresAt,
+				&k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt, &k.CreatedAt); err != nil {
+				continue
+			}
+			keys = append(keys, k)
+		}
+
+		return c.JSON(fiber.Map{"data": keys, "available_scopes": utils.APIKeyScopes})
+	}
+}
+
+// CreateMyAPIKeyHandler - POST /api/app/my/api-keys - buat API key; key hanya ditampilkan sekali
+func CreateMyAPIKeyHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+			RateLimit     int      `json:"rate_limit"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		input.Name = strings.TrimSpace(input.Name)
+		if input.Name == "" || len(input.Name) > 100 {
+			return fiber.NewError(fiber.StatusBadRequest, "Name is required (max 100 characters)")
+		}
+		if len(input.Scopes) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "At least one scope is required")
+		}
+		for _, scope := range input.Scopes {
+			if !utils.ValidAPIKeyScope(scope) {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid scope: "+scope)
+			}
+		}
+		if input.ExpiresInDays == 0 {
+			input.ExpiresInDays = defaultAPIKeyDays
+		}
+		if input.ExpiresInDays < 1 || input.ExpiresInDays > maxAPIKeyDays {
+			return fiber.NewError(fiber.StatusBadRequest, "expires_in_days must be between 1 and 365")
+		}
+		if input.RateLimit == 0 {
+			input.RateLimit = defaultAPIKeyLimit
+		}
+		if input.RateLimit < 1 || input.RateLimit > maxAPIKeyRateLimit {
+			return fiber.NewError(fiber.StatusBadRequest, "rate_limit must be between 1 and 600 requests per minute")
+		}
+
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		var activeKeys int
+		err := db.QueryRow(ctx, `
+			SELECT COUNT(*) FROM api_keys
+			WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()`, userID).Scan(&activeKeys)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if activeKeys >= maxAPIKeysPerUser {
+			return fiber.NewError(fiber.StatusBadRequest, "Maximum number of active API keys reached")
+		}
+
+		key, prefix, hash, err := utils.GenerateAPIKey()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		var k APIKeyResponse
+		err = db.QueryRow(ctx, `
+			INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, rate_limit, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING uuid, name, prefix, scopes, rate_limit, expires_at, created_at`,
+			userID, input.Name, prefix, hash, input.Scopes, input.RateLimit,
+			time.Now().AddDate(0, 0, input.ExpiresInDays)).
+			Scan(&k.UUID, &k.Name, &k.Prefix, &k.Scopes, &k.RateLimit, &k.ExpiresAt, &k.CreatedAt)
+		if err != nil {
+			log.Printf("Create API key error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "API key created, store it now - it will not be shown again",
+			"key":     key,
+			"data":    k,
+		})
+	}
+}
+
+// RevokeMyAPIKeyHandler - DELETE /api/app/my/api-keys/:uuid - revoke API key
+func RevokeMyAPIKeyHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		tag, err := db.Exec(ctx, `
+			UPDATE api_keys SET revoked_at = NOW()
+			WHERE uuid::TEXT = $1 AND user_id = $2 AND revoked_at IS NULL`,
+			c.Params("uuid"), userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if tag.RowsAffected() == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "API key not found")
+		}
+
+		return c.JSON(fiber.Map{"message": "API key revoked"})
+	}
+}
@@ -3,16 +3,16 @@ package handler
 import (
 	"context"
 	"crypto/rand"
-	"fmt"
-	"net/smtp"
-	"os"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 
 	"shopedia-api/internal/cache"
+	"shopedia-api/internal/queue"
 	utils "shopedia-api/internal/util"
 )
 
@@ -31,9 +31,16 @@ func RegisterHandler(db *pgxpool.Pool) fiber.Handler {
 
 		ctx := context.Background()
 
+		// User, role, OTP dan outbox email ditulis dalam satu transaksi
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
 		// Cek apakah user sudah ada
 		var isActive bool
-		err := db.QueryRow(ctx, "SELECT is_active FROM users WHERE email = $1", input.Email).Scan(&isActive)
+		err = tx.QueryRow(ctx, "SELECT is_active FROM users WHERE email = $1", input.Email).Scan(&isActive)
 		if err == nil {
 			if isActive {
 				return fiber.NewError(fiber.StatusBadRequest, "Email already registered")
@@ -45,7 +52,7 @@ func RegisterHandler(db *pgxpool.Pool) fiber.Handler {
 			if hashErr != nil {
 				return fiber.ErrInternalServerError
 			}
-			_, err = db.Exec(ctx, "INSERT INTO users (email, full_name, password_hash, is_active) VALUES ($1, $2, $3, FALSE)",
+			_, err = tx.Exec(ctx, "INSERT INTO users (email, full_name, password_hash, is_active) VALUES ($1, $2, $3, FALSE)",
 				input.Email, input.Fullname, string(hash))
 			if err != nil {
 				return fiber.ErrInternalServerError
@@ -54,21 +61,14 @@ func RegisterHandler(db *pgxpool.Pool) fiber.Handler {
 
 		// Add roles
 		var userID int
-		err = db.QueryRow(ctx, "SELECT id FROM users WHERE email=$1", input.Email).Scan(&userID)
+		var userUUID string
+		err = tx.QueryRow(ctx, "SELECT id, uuid FROM users WHERE email=$1", input.Email).Scan(&userID, &userUUID)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
 
-		var roleID int
-		err = db.QueryRow(ctx, `SELECT id FROM roles WHERE name='end_user'`).Scan(&roleID)
-		if err != nil || roleID == 0 {
-			return fiber.NewError(fiber.StatusInternalServerError, "Role 'end_user' not found")
-		}
-
-		_, err = db.Exec(ctx, `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-			userID, roleID)
-		if err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "insert user roles failed")
+		if err := assignEndUserRole(ctx, tx, userID); err != nil {
+			return err
 		}
 
 		// Buat OTP - Check Redis first
@@ -85,36 +85,39 @@ func RegisterHandler(db *pgxpool.Pool) fiber.Handler {
 			}
 		}
 
+		var otp string
 		if !otpSent {
 			// Generate new OTP
-			otp := generateOTP()
+			otp = generateOTP()
 			expiresAt = time.Now().Add(cache.TTLOTP)
 
-			// Store in Redis (primary)
-			if cache.Client != nil {
-				if err := cache.SetOTP(input.Email, otp); err != nil {
-					return fiber.NewError(fiber.StatusInternalServerError, "Failed to store OTP")
-				}
-			}
-
 			// Store in database (backup)
-			_, err = db.Exec(ctx, `
+			_, err = tx.Exec(ctx, `
 				INSERT INTO otp_codes (user_id, otp_code, expires_at) VALUES ($1, $2, $3)`,
 				userID, otp, expiresAt)
 			if err != nil {
 				return fiber.NewError(fiber.StatusInternalServerError, "Insert otp code failed")
 			}
 
-			sendOTP(input.Email, otp)
+			if err := enqueueOTPEmail(ctx, tx, input.Email, otp, input.Fullname); err != nil {
+				log.Printf("Register outbox error: %v", err)
+				return fiber.ErrInternalServerError
+			}
 		}
 
-		// Generate register_access_token dengan JTI
-		var userUUID string
-		err = db.QueryRow(ctx, "SELECT uuid FROM users WHERE id=$1", userID).Scan(&userUUID)
-		if err != nil {
+		if err := tx.Commit(ctx); err != nil {
 			return fiber.ErrInternalServerError
 		}
 
+		// Store in Redis (primary) setelah commit, supaya OTP yang tidak pernah
+		// terkirim tidak menahan request OTP berikutnya
+		if !otpSent && cache.Client != nil {
+			if err := cache.SetOTP(input.Email, otp); err != nil {
+				log.Printf("Register cache OTP error: %v", err)
+			}
+		}
+
+		// Generate register_access_token dengan JTI
 		tokenString, _, err := utils.GenerateRegisterToken(userID, userUUID, expiresAt)
 		if err != nil {
 			return fiber.ErrInternalServerError
@@ -127,6 +130,22 @@ func RegisterHandler(db *pgxpool.Pool) fiber.Handler {
 	}
 }
 
+// assignEndUserRole - role default user app (register & social login)
+func assignEndUserRole(ctx context.Context, tx pgx.Tx, userID int) error {
+	var roleID int
+	err := tx.QueryRow(ctx, `SELECT id FROM roles WHERE name='end_user'`).Scan(&roleID)
+	if err != nil || roleID == 0 {
+		return fiber.NewError(fiber.StatusInternalServerError, "Role 'end_user' not found")
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		userID, roleID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "insert user roles failed")
+	}
+	return nil
+}
+
 func VerifyOTPHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		type Input struct {
@@ -192,10 +211,11 @@ func VerifyOTPHandler(db *pgxpool.Pool) fiber.Handler {
 		}
 
 		// Activate user
-		_, err = db.Exec(ctx, `UPDATE users SET is_active=TRUE WHERE id=$1`, userID)
+		_, err = db.Exec(ctx, `UPDATE users SET is_active=TRUE, email_verified_at=COALESCE(email_verified_at, NOW()) WHERE id=$1`, userID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "update user is_active failed")
 		}
+		utils.InvalidateAuthContext(userID)
 
 		return c.JSON(fiber.Map{"message": "OTP verified, account activated"})
 	}
@@ -226,8 +246,8 @@ func RequestNewOTPHandler(db *pgxpool.Pool) fiber.Handler {
 		ctx := context.Background()
 
 		// Get user email
-		var email string
-		err = db.QueryRow(ctx, `SELECT email FROM users WHERE id=$1`, userID).Scan(&email)
+		var email, fullName string
+		err = db.QueryRow(ctx, `SELECT email, COALESCE(full_name, '') FROM users WHERE id=$1`, userID).Scan(&email, &fullName)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
@@ -245,22 +265,35 @@ func RequestNewOTPHandler(db *pgxpool.Pool) fiber.Handler {
 		otp := generateOTP()
 		expiresAt := time.Now().Add(cache.TTLOTP)
 
-		// Store in Redis (primary)
-		if cache.Client != nil {
-			if err := cache.SetOTP(email, otp); err != nil {
-				return fiber.NewError(fiber.StatusInternalServerError, "Failed to store OTP")
-			}
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
 		}
+		defer tx.Rollback(ctx)
 
 		// Store in database (backup)
-		_, err = db.Exec(ctx, `
+		_, err = tx.Exec(ctx, `
 			INSERT INTO otp_codes (user_id, otp_code, expires_at) VALUES ($1, $2, $3)`,
 			userID, otp, expiresAt)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Insert otp code failed")
 		}
 
-		sendOTP(email, otp)
+		if err := enqueueOTPEmail(ctx, tx, email, otp, fullName); err != nil {
+			log.Printf("Request OTP outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		// Store in Redis (primary)
+		if cache.Client != nil {
+			if err := cache.SetOTP(email, otp); err != nil {
+				log.Printf("Request OTP cache error: %v", err)
+			}
+		}
 
 		return c.JSON(fiber.Map{"expired_otp_at": expiresAt.Format(time.RFC3339)})
 	}
@@ -277,10 +310,11 @@ func generateOTP() string {
 	return string(result)
 }
 
-func sendOTP(email, otp string) {
-	auth := smtp.PlainAuth("", os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"), os.Getenv("SMTP_HOST"))
-	to := []string{email}
-	msg := []byte("Subject: OTP Code\n\nYour OTP code is: " + otp)
-	smtp.SendMail(fmt.Sprintf("%s:%s", os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT")), auth,
-		"noreply@myapp.com", to, msg)
+// enqueueOTPEmail - tulis task email OTP ke outbox dalam tx yang sama dengan otp_codes
+func enqueueOTPEmail(ctx context.Context, tx pgx.Tx, email, otp, name string) error {
+	task, err := queue.NewSendOTPTask(email, otp, name)
+	if err != nil {
+		return err
+	}
+	return queue.AddToOutbox(ctx, tx, task, "critical")
 }
@@ -0,0 +1,422 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"shopedia-api/internal/cache"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Banner Response Types
+// ============================================
+
+type BannerResponse struct {
+	UUID      string     `json:"uuid"`
+	Title     string     `json:"title"`
+	ImageURL  string     `json:"image_url"`
+	TargetURL *string    `json:"target_url"`
+	Position  string     `json:"position"`
+	Priority  int        `json:"priority"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// nextBannerBoundary - durasi sampai ada banner yang mulai/berakhir tayang,
+// dipakai sebagai TTL cache agar list public selalu sesuai jadwal
+func nextBannerBoundary(ctx context.Context, db *pgxpool.Pool) time.Duration {
+	var seconds *float64
+	err := db.QueryRow(ctx, `
+		SELECT EXTRACT(EPOCH FROM MIN(boundary) - NOW())::FLOAT8 FROM (
+			SELECT starts_at AS boundary FROM banners
+			WHERE deleted_at IS NULL AND is_active = TRUE AND starts_at > NOW()
+			UNION ALL
+			SELECT ends_at FROM banners
+			WHERE deleted_at IS NULL AND is_active = TRUE AND ends_at > NOW()
+		) b`).Scan(&seconds)
+	if err != nil || seconds == nil {
+		return cache.TTLBanners
+	}
+	return time.Duration(*seconds*float64(time.Second)) + time.Second
+}
+
+// invalidateBanners - hapus cache banner public setelah perubahan data
+func invalidateBanners() {
+	if cache.Client != nil {
+		if err := cache.InvalidateBanners(); err != nil {
+			log.Printf("Failed to invalidate banners cache: %v", err)
+		}
+	}
+}
+
+// validateBannerSchedule - ends_at harus setelah starts_at
+func validateBannerSchedule(startsAt, endsAt *time.Time) error {
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return fiber.NewError(fiber.StatusBadRequest, "ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// ============================================
+// Public Banner Handlers
+// ============================================
+
+// ListPublicBannersHandler - GET /banners - banner yang sedang tayang (cached)
+func ListPublicBannersHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		position := c.Query("position", "")
+
+		filter := func(banners []BannerResponse) []BannerResponse {
+			if position == "" {
+				return banners
+			}
+			filtered := []BannerResponse{}
+			for _, b := range banners {
+				if b.Position == position {
+					filtered = append(filtered, b)
+				}
+			}
+			return filtered
+		}
+
+		// Try to get from cache first (list kosong juga di-cache)
+		var cachedBanners []BannerResponse
+		if cache.Client != nil {
+			if err := cache.GetBanners(&cachedBanners); err == nil && cachedBanners != nil {
+				c.Set("X-Cache", "HIT")
+				return c.JSON(fiber.Map{
+					"banners": filter(cachedBanners),
+				})
+			}
+		}
+
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT uuid, title, image_url, target_url, position, priority, starts_at, ends_at,
+				is_active, created_at, updated_at
+			FROM banners
+			WHERE deleted_at IS NULL AND is_active = TRUE
+				AND (starts_at IS NULL OR starts_at <= NOW())
+				AND (ends_at IS NULL OR ends_at > NOW())
+			ORDER BY position, priority DESC, created_at DESC`)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		banners := []BannerResponse{}
+		for rows.Next() {
+			var b BannerResponse
+			err := rows.Scan(&b.UUID, &b.Title, &b.ImageURL, &b.TargetURL, &b.Position, &b.Priority,
+				&b.StartsAt, &b.EndsAt, &b.IsActive, &b.CreatedAt, &b.UpdatedAt)
+			if err != nil {
+				continue
+			}
+			banners = append(banners, b)
+		}
+		rows.Close()
+
+		// Cache the result sampai batas jadwal berikutnya
+		if cache.Client != nil {
+			if err := cache.SetBanners(banners, nextBannerBoundary(ctx, db)); err != nil {
+				log.Printf("Failed to cache banners: %v", err)
+			}
+		}
+
+		c.Set("X-Cache", "MISS")
+		return c.JSON(fiber.Map{
+			"banners": filter(banners),
+		})
+	}
+}
+
+// ============================================
+// Admin Banner Handlers (Dashboard)
+// ============================================
+
+// ListBannersHandler - GET /banners - list semua banner (termasuk terjadwal/expired)
+func ListBannersHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		position := c.Query("position", "")
+		activeFilter := c.Query("is_active", "")
+
+		baseQuery := `FROM banners WHERE deleted_at IS NULL`
+		args := []interface{}{}
+		argCount := 0
+
+		if position != "" {
+			argCount++
+			baseQuery += ` AND position = $` + strconv.Itoa(argCount)
+			args = append(args, position)
+		}
+
+		if activeFilter != "" {
+			argCount++
+			baseQuery += ` AND is_active = $` + strconv.Itoa(argCount)
+			args = append(args, activeFilter == "true")
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT uuid, title, image_url, target_url, position, priority, starts_at, ends_at,
+			is_active, created_at, updated_at ` +
+			baseQuery + ` ORDER BY position, priority DESC, created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		banners := []BannerResponse{}
+		for rows.Next() {
+			var b BannerResponse
+			err := rows.Scan(&b.UUID, &b.Title, &b.ImageURL, &b.TargetURL, &b.Position, &b.Priority,
+				&b.StartsAt, &b.EndsAt, &b.IsActive, &b.CreatedAt, &b.UpdatedAt)
+			if err != nil {
+				continue
+			}
+			banners = append(banners, b)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       banners,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// GetBannerHandler - GET /banners/:uuid - detail banner
+func GetBannerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bannerUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		var b BannerResponse
+		err := db.QueryRow(ctx, `
+			SELECT uuid, title, image_url, target_url, position, priority, starts_at, ends_at,
+				is_active, created_at, updated_at
+			FROM banners WHERE uuid = $1 AND deleted_at IS NULL`,
+			bannerUUID).Scan(&b.UUID, &b.Title, &b.ImageURL, &b.TargetURL, &b.Position, &b.Priority,
+			&b.StartsAt, &b.EndsAt, &b.IsActive, &b.CreatedAt, &b.UpdatedAt)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(b)
+	}
+}
+
+// CreateBannerHandler - POST /banners - buat banner baru
+func CreateBannerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Title     string     `json:"title"`
+			ImageURL  string     `json:"image_url"`
+			TargetURL *string    `json:"target_url"`
+			Position  string     `json:"position"`
+			Priority  int        `json:"priority"`
+			StartsAt  *time.Time `json:"starts_at"`
+			EndsAt    *time.Time `json:"ends_at"`
+			IsActive  *bool      `json:"is_active"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Title == "" || input.ImageURL == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Title and image URL are required")
+		}
+		if err := validateBannerSchedule(input.StartsAt, input.EndsAt); err != nil {
+			return err
+		}
+
+		if input.Position == "" {
+			input.Position = "home_top"
+		}
+
+		isActive := true
+		if input.IsActive != nil {
+			isActive = *input.IsActive
+		}
+
+		ctx := context.Background()
+
+		var bannerUUID string
+		err := db.QueryRow(ctx, `
+			INSERT INTO banners (title, image_url, target_url, position, priority, starts_at, ends_at, is_active, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING uuid`,
+			input.Title, input.ImageURL, input.TargetURL, input.Position, input.Priority,
+			input.StartsAt, input.EndsAt, isActive, userID).Scan(&bannerUUID)
+		if err != nil {
+			log.Printf("CreateBanner error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		invalidateBanners()
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Banner created successfully",
+			"uuid":    bannerUUID,
+		})
+	}
+}
+
+// UpdateBannerHandler - PUT /banners/:uuid - update banner (field yang dikirim saja, null = kosongkan)
+func UpdateBannerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bannerUUID := c.Params("uuid")
+
+		type Input struct {
+			Title     *string    `json:"title"`
+			ImageURL  *string    `json:"image_url"`
+			TargetURL *string    `json:"target_url"`
+			Position  *string    `json:"position"`
+			Priority  *int       `json:"priority"`
+			StartsAt  *time.Time `json:"starts_at"`
+			EndsAt    *time.Time `json:"ends_at"`
+			IsActive  *bool      `json:"is_active"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		// target_url / starts_at / ends_at yang dikirim null secara eksplisit dikosongkan
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(c.Body(), &raw); err != nil {
+			return fiber.ErrBadRequest
+		}
+		isNull := func(field string) bool {
+			v, ok := raw[field]
+			return ok && string(v) == "null"
+		}
+
+		ctx := context.Background()
+
+		var b BannerResponse
+		var bannerID int
+		err := db.QueryRow(ctx, `
+			SELECT id, title, image_url, target_url, position, priority, starts_at, ends_at, is_active
+			FROM banners WHERE uuid = $1 AND deleted_at IS NULL`,
+			bannerUUID).Scan(&bannerID, &b.Title, &b.ImageURL, &b.TargetURL, &b.Position, &b.Priority,
+			&b.StartsAt, &b.EndsAt, &b.IsActive)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if input.Title != nil {
+			b.Title = *input.Title
+		}
+		if input.ImageURL != nil {
+			b.ImageURL = *input.ImageURL
+		}
+		if input.TargetURL != nil || isNull("target_url") {
+			b.TargetURL = input.TargetURL
+		}
+		if input.Position != nil {
+			b.Position = *input.Position
+		}
+		if input.Priority != nil {
+			b.Priority = *input.Priority
+		}
+		if input.StartsAt != nil || isNull("starts_at") {
+			b.StartsAt = input.StartsAt
+		}
+		if input.EndsAt != nil || isNull("ends_at") {
+			b.EndsAt = input.EndsAt
+		}
+		if input.IsActive != nil {
+			b.IsActive = *input.IsActive
+		}
+
+		if b.Title == "" || b.ImageURL == "" || b.Position == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Title, image URL and position cannot be empty")
+		}
+		if err := validateBannerSchedule(b.StartsAt, b.EndsAt); err != nil {
+			return err
+		}
+
+		_, err = db.Exec(ctx, `
+			UPDATE banners SET title = $1, image_url = $2, target_url = $3, position = $4, priority = $5,
+				starts_at = $6, ends_at = $7, is_active = $8, updated_at = NOW()
+			WHERE id = $9`,
+			b.Title, b.ImageURL, b.TargetURL, b.Position, b.Priority, b.StartsAt, b.EndsAt, b.IsActive, bannerID)
+		if err != nil {
+			log.Printf("UpdateBanner error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		invalidateBanners()
+
+		return c.JSON(fiber.Map{"message": "Banner updated successfully"})
+	}
+}
+
+// DeleteBannerHandler - DELETE /banners/:uuid - soft delete banner
+func DeleteBannerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bannerUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		result, err := db.Exec(ctx, `
+			UPDATE banners SET deleted_at = NOW(), updated_at = NOW()
+			WHERE uuid = $1 AND deleted_at IS NULL`, bannerUUID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if result.RowsAffected() == 0 {
+			return fiber.ErrNotFound
+		}
+
+		invalidateBanners()
+
+		return c.JSON(fiber.Map{"message": "Banner deleted successfully"})
+	}
+}
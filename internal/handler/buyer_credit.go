@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Buyer Credit Response Types
+// ============================================
+
+type BuyerCreditResponse struct {
+	Balance   int64      `json:"balance"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type CreditEntryResponse struct {
+	UUID            string    `json:"uuid"`
+	Amount          int64     `json:"amount"`
+	Type            string    `json:"type"` // in, out
+	Description     *string   `json:"description"`
+	TransactionUUID *string   `json:"transaction_uuid"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ============================================
+// Buyer Credit Helper Functions
+// ============================================
+
+// creditBuyer - tambah saldo refund buyer + tulis entry 'in' di buyer_credit_logs (harus dalam DB transaction).
+// Terpisah dari creditBalance: saldo refund bukan saldo penjualan dan tidak bisa ditarik lewat payout.
+func creditBuyer(ctx context.Context, tx pgx.Tx, userID int, amount int64, transactionID *int, description string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO buyer_credits (user_id, amount) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET amount = buyer_credits.amount + EXCLUDED.amount, updated_at = NOW()`,
+		userID, amount)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO buyer_credit_logs (user_id, transaction_id, amount, type, description)
+		VALUES ($1, $2, $3, 'in', $4)`,
+		userID, transactionID, amount, description)
+	return err
+}
+
+// ============================================
+// Buyer Credit Handlers (App)
+// ============================================
+
+// GetMyCreditsHandler - GET /credits - saldo refund buyer
+func GetMyCreditsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		var credit BuyerCreditResponse
+		err := db.QueryRow(ctx, `SELECT amount, updated_at FROM buyer_credits WHERE user_id = $1`,
+			userID).Scan(&credit.Balance, &credit.UpdatedAt)
+		if err != nil && err != pgx.ErrNoRows {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(credit)
+	}
+}
+
+// ListMyCreditLedgerHandler - GET /credits/ledger - riwayat mutasi saldo refund buyer
+func ListMyCreditLedgerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) FROM buyer_credit_logs WHERE user_id = $1`, userID).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		rows, err := db.Query(ctx, `
+			SELECT cl.uuid, cl.amount, cl.type, cl.description, t.uuid, cl.created_at
+			FROM buyer_credit_logs cl
+			LEFT JOIN transactions t ON cl.transaction_id = t.id
+			WHERE cl.user_id = $1
+			ORDER BY cl.created_at DESC, cl.id DESC LIMIT $2 OFFSET $3`,
+			userID, limit, offset)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		entries := []CreditEntryResponse{}
+		for rows.Next() {
+			var e CreditEntryResponse
+			if err := rows.Scan(&e.UUID, &e.Amount, &e.Type, &e.Description, &e.TransactionUUID, &e.CreatedAt); err != nil {
+				continue
+			}
+			entries = append(entries, e)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       entries,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Cart Response Types
+// ============================================
+
+type CartItemResponse struct {
+	UUID        string             `json:"uuid"`
+	Product     ProductResponse    `json:"product"`
+	Variant     *ProductVariantRef `json:"variant,omitempty"`
+	Qty         int                `json:"qty"`
+	UnitPrice   int64              `json:"unit_price"` // harga varian jika ada, selain itu harga produk
+	Subtotal    int64              `json:"subtotal"`
+	IsAvailable bool               `json:"is_available"`
+	Issue       *string            `json:"issue,omitempty"` // deleted, blocked, inactive, variant_unavailable, out_of_stock, insufficient_stock
+	AddedAt     time.Time          `json:"added_at"`
+}
+
+type CartResponse struct {
+	Items          []CartItemResponse `json:"items"`
+	TotalItems     int                `json:"total_items"`
+	TotalQty       int                `json:"total_qty"`
+	TotalPrice     int64              `json:"total_price"` // hanya item yang available
+	HasUnavailable bool               `json:"has_unavailable"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// getOrCreateCartID - ambil cart milik user, buat baru jika belum ada
+func getOrCreateCartID(ctx context.Context, db *pgxpool.Pool, userID int) (int, error) {
+	var cartID int
+	err := db.QueryRow(ctx, `
+		INSERT INTO carts (user_id) VALUES ($1)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id`, userID).Scan(&cartID)
+	return cartID, err
+}
+
+// cartItemIssue - revalidasi item terhadap data products terbaru
+func cartItemIssue(isDeleted bool, status string, isActive bool, stock int, qty int) *string {
+	issue := ""
+	switch {
+	case isDeleted:
+		issue = "deleted"
+	case status == "blocked":
+		issue = "blocked"
+	case status != "active" || !isActive:
+		issue = "inactive"
+	case stock <= 0:
+		issue = "out_of_stock"
+	case stock < qty:
+		issue = "insufficient_stock"
+	}
+	if issue == "" {
+		return nil
+	}
+	return &issue
+}
+
+// ============================================
+// Cart Handlers (App)
+// ============================================
+
+// GetCartHandler - GET /cart - list item cart dengan harga & stock terbaru
+func GetCartHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT ci.uuid, ci.qty, ci.created_at, (p.deleted_at IS NOT NULL),
+				p.uuid, p.title, p.slug, p.description,
+				COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock, p.has_variants,
+				pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
+				p.status, p.is_active, p.created_at, p.updated_at,
+				v.uuid::TEXT, v.sku, v.options::TEXT, v.price, v.stock, (v.is_active AND v.deleted_at IS NULL)
+			FROM cart_items ci
+			JOIN carts ca ON ci.cart_id = ca.id
+			JOIN products p ON ci.product_id = p.id
+			LEFT JOIN product_variants v ON ci.variant_id = v.id
+			LEFT JOIN product_categories pc ON p.category_id = pc.id
+			LEFT JOIN users u ON p.owner_user_id = u.id
+			WHERE ca.user_id = $1
+			ORDER BY ci.created_at DESC`, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		cart := CartResponse{Items: []CartItemResponse{}}
+		for rows.Next() {
+			var item CartItemResponse
+			var isDeleted bool
+			var imagesJSON string
+			var catUUID, catName, catIcon *string
+			var ownerUUID, ownerName string
+			var variantUUID, variantSKU, variantOptions *string
+			var variantPrice *int64
+			var variantStock *int
+			var variantActive *bool
+
+			p := &item.Product
+			err := rows.Scan(&item.UUID, &item.Qty, &item.AddedAt, &isDeleted,
+				&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock, &p.HasVariants,
+				&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
+				&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+				&variantUUID, &variantSKU, &variantOptions, &variantPrice, &variantStock, &variantActive)
+			if err != nil {
+				continue
+			}
+
+			if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
+				p.Images = []string{}
+			}
+
+			if catUUID != nil {
+				p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
+			}
+			p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
+
+			// Harga & stock dari varian yang dipilih; produk bervarian tanpa varian aktif tidak bisa dibeli
+			stock := p.Stock
+			item.UnitPrice = p.Price
+			if p.HasVariants {
+				item.Variant = variantRef(variantUUID, variantSKU, variantOptions)
+			}
+			if item.Variant != nil {
+				item.UnitPrice, stock = *variantPrice, *variantStock
+			}
+
+			item.Subtotal = item.UnitPrice * int64(item.Qty)
+			item.Issue = cartItemIssue(isDeleted, p.Status, p.IsActive, stock, item.Qty)
+			if item.Issue == nil && p.HasVariants && (item.Variant == nil || !*variantActive) {
+				issue := "variant_unavailable"
+				item.Issue = &issue
+			}
+			item.IsAvailable = item.Issue == nil
+
+			cart.TotalItems++
+			cart.TotalQty += item.Qty
+			if item.IsAvailable {
+				cart.TotalPrice += item.Subtotal
+			} else {
+				cart.HasUnavailable = true
+			}
+
+			cart.Items = append(cart.Items, item)
+		}
+
+		return c.JSON(cart)
+	}
+}
+
+// AddCartItemHandler - POST /cart/items - tambah produk / varian ke cart (qty ditambahkan jika sudah ada)
+func AddCartItemHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			ProductUUID string `json:"product_uuid"`
+			VariantUUID string `json:"variant_uuid"` // wajib untuk produk bervarian
+			Qty         int    `json:"qty"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.ProductUUID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Product UUID is required")
+		}
+		if input.Qty == 0 {
+			input.Qty = 1
+		}
+		if input.Qty < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Quantity must be at least 1")
+		}
+
+		ctx := context.Background()
+
+		var productID, stock int
+		var hasVariants, ownShop bool
+		err := db.QueryRow(ctx, `
+			SELECT p.id, p.stock, p.has_variants,
+				(p.owner_user_id = $2 OR EXISTS (
+					SELECT 1 FROM shop_members sm
+					WHERE sm.shop_id = p.shop_id AND sm.user_id = $2 AND sm.status = 'active'
+				))
+			FROM products p
+			WHERE p.uuid = $1 AND p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE`,
+			input.ProductUUID, userID).Scan(&productID, &stock, &hasVariants, &ownShop)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Product not found")
+		}
+
+		var variantID *int
+		if hasVariants {
+			if input.VariantUUID == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Variant UUID is required for this product")
+			}
+			var id int
+			err := db.QueryRow(ctx, `
+				SELECT id, stock FROM product_variants
+				WHERE uuid::TEXT = $1 AND product_id = $2 AND deleted_at IS NULL AND is_active = TRUE`,
+				input.VariantUUID, productID).Scan(&id, &stock)
+			if err != nil {
+				return fiber.NewError(fiber.StatusNotFound, "Variant not found")
+			}
+			variantID = &id
+		} else if input.VariantUUID != "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Product has no variants")
+		}
+
+		if ownShop {
+			return fiber.NewError(fiber.StatusBadRequest, "Cannot add your own product to cart")
+		}
+
+		cartID, err := getOrCreateCartID(ctx, db, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		var currentQty int
+		_ = db.QueryRow(ctx, `
+			SELECT qty FROM cart_items WHERE cart_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3`,
+			cartID, productID, variantID).Scan(&currentQty)
+
+		if currentQty+input.Qty > stock {
+			return fiber.NewError(fiber.StatusConflict, "Insufficient stock")
+		}
+
+		var itemUUID string
+		var qty int
+		err = db.QueryRow(ctx, `
+			INSERT INTO cart_items (cart_id, product_id, variant_id, qty) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (cart_id, product_id, (COALESCE(variant_id, 0))) DO UPDATE SET qty = cart_items.qty + EXCLUDED.qty, updated_at = NOW()
+			RETURNING uuid, qty`,
+			cartID, productID, variantID, input.Qty).Scan(&itemUUID, &qty)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Item added to cart",
+			"uuid":    itemUUID,
+			"qty":     qty,
+		})
+	}
+}
+
+// UpdateCartItemHandler - PUT /cart/items/:uuid - ubah qty item cart
+func UpdateCartItemHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		itemUUID := c.Params("uuid")
+
+		type Input struct {
+			Qty int `json:"qty"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Qty < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Quantity must be at least 1")
+		}
+
+		ctx := context.Background()
+
+		var itemID, stock int
+		err := db.QueryRow(ctx, `
+			SELECT ci.id, CASE WHEN p.has_variants THEN COALESCE(v.stock, 0) ELSE p.stock END FROM cart_items ci
+			JOIN carts ca ON ci.cart_id = ca.id
+			JOIN products p ON ci.product_id = p.id
+			LEFT JOIN product_variants v ON ci.variant_id = v.id
+			WHERE ci.uuid = $1 AND ca.user_id = $2`,
+			itemUUID, userID).Scan(&itemID, &stock)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if input.Qty > stock {
+			return fiber.NewError(fiber.StatusConflict, "Insufficient stock")
+		}
+
+		_, err = db.Exec(ctx, `UPDATE cart_items SET qty = $1, updated_at = NOW() WHERE id = $2`, input.Qty, itemID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "Cart item updated successfully"})
+	}
+}
+
+// RemoveCartItemHandler - DELETE /cart/items/:uuid - hapus item dari cart
+func RemoveCartItemHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		itemUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		result, err := db.Exec(ctx, `
+			DELETE FROM cart_items ci USING carts ca
+			WHERE ci.cart_id = ca.id AND ci.uuid = $1 AND ca.user_id = $2`,
+			itemUUID, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if result.RowsAffected() == 0 {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(fiber.Map{"message": "Cart item removed successfully"})
+	}
+}
+
+// ClearCartHandler - DELETE /cart - kosongkan cart
+func ClearCartHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		_, err := db.Exec(ctx, `
+			DELETE FROM cart_items ci USING carts ca
+			WHERE ci.cart_id = ca.id AND ca.user_id = $1`, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "Cart cleared successfully"})
+	}
+}
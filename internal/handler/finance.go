@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"shopedia-api/internal/queue"
+	"shopedia-api/internal/report"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Export dengan baris lebih dari ini diproses worker dan link download dikirim via email
+const financeExportSyncLimit = 5000
+
+// ============================================
+// Finance Response Types
+// ============================================
+
+type FinanceSummaryRow struct {
+	PeriodStart  string `json:"period_start"` // YYYY-MM-DD
+	GMV          int64  `json:"gmv"`
+	OrderCount   int    `json:"order_count"`
+	RefundAmount int64  `json:"refund_amount"`
+	RefundCount  int    `json:"refund_count"`
+	PayoutAmount int64  `json:"payout_amount"`
+	PayoutCount  int    `json:"payout_count"`
+	NetSales     int64  `json:"net_sales"` // gmv - refund_amount
+}
+
+type FinanceExportResponse struct {
+	UUID         string     `json:"uuid"`
+	Format       string     `json:"format"`
+	Status       string     `json:"status"` // pending, completed, failed
+	FileName     *string    `json:"file_name"`
+	ErrorMessage *string    `json:"error_message"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// parseFinanceFilter - baca from, to, category, seller dari query string (default 30 hari terakhir)
+func parseFinanceFilter(c *fiber.Ctx, ctx context.Context, db *pgxpool.Pool) (report.FinanceFilter, error) {
+	var f report.FinanceFilter
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	f.To = today
+	if to := c.Query("to", ""); to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+		f.To = parsed
+	}
+
+	f.From = f.To.AddDate(0, 0, -29)
+	if from := c.Query("from", ""); from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+		f.From = parsed
+	}
+
+	if f.From.After(f.To) {
+		return f, fiber.NewError(fiber.StatusBadRequest, "from must be before or equal to to")
+	}
+
+	if categoryUUID := c.Query("category", ""); categoryUUID != "" {
+		var categoryID int
+		err := db.QueryRow(ctx, `SELECT id FROM product_categories WHERE uuid = $1`, categoryUUID).Scan(&categoryID)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusNotFound, "Category not found")
+		}
+		f.CategoryID = &categoryID
+	}
+
+	if sellerUUID := c.Query("seller", ""); sellerUUID != "" {
+		var sellerID int
+		err := db.QueryRow(ctx, `SELECT id FROM users WHERE uuid = $1`, sellerUUID).Scan(&sellerID)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusNotFound, "Seller not found")
+		}
+		f.SellerID = &sellerID
+	}
+
+	return f, nil
+}
+
+// ============================================
+// Admin Finance Handlers (Dashboard)
+// ============================================
+
+// FinanceSummaryHandler - GET /finance/summary - GMV, order, refund & payout per day/week/month
+func FinanceSummaryHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		period := c.Query("period", "day")
+		if period != "day" && period != "week" && period != "month" {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid period. Must be 'day', 'week', or 'month'")
+		}
+
+		f, err := parseFinanceFilter(c, ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if period == "day" && f.To.Sub(f.From) > 366*24*time.Hour {
+			return fiber.NewError(fiber.StatusBadRequest, "Daily summary is limited to 366 days")
+		}
+
+		// Payout tidak terkait produk, sehingga hanya difilter berdasarkan seller
+		rows, err := db.Query(ctx, `
+			WITH buckets AS (
+				SELECT generate_series(date_trunc($5, $1::TIMESTAMP), date_trunc($5, $2::TIMESTAMP - INTERVAL '1 day'),
+					('1 ' || $5)::INTERVAL) AS bucket
+			),
+			sales AS (
+				SELECT date_trunc($5, t.created_at) AS bucket, SUM(t.total_price) AS gmv, COUNT(DISTINCT t.order_id) AS orders
+				FROM transactions t
+				JOIN products p ON t.product_id = p.id
+				WHERE t.created_at >= $1 AND t.created_at < $2
+					AND t.status IN `+report.SettledStatuses+`
+					AND ($3::INT IS NULL OR p.category_id = $3)
+					AND ($4::INT IS NULL OR t.seller_user_id = $4)
+				GROUP BY 1
+			),
+			refunded AS (
+				SELECT date_trunc($5, r.created_at) AS bucket, SUM(r.amount) AS amount, COUNT(*) AS total
+				FROM refunds r
+				JOIN transactions t ON r.transaction_id = t.id
+				JOIN products p ON t.product_id = p.id
+				WHERE r.created_at >= $1 AND r.created_at < $2
+					AND ($3::INT IS NULL OR p.category_id = $3)
+					AND ($4::INT IS NULL OR t.seller_user_id = $4)
+				GROUP BY 1
+			),
+			paid AS (
+				SELECT date_trunc($5, po.reviewed_at) AS bucket, SUM(po.amount) AS amount, COUNT(*) AS total
+				FROM payouts po
+				WHERE po.status = 'approved' AND po.reviewed_at >= $1 AND po.reviewed_at < $2
+					AND ($4::INT IS NULL OR po.user_id = $4)
+				GROUP BY 1
+			)
+			SELECT b.bucket, COALESCE(s.gmv, 0), COALESCE(s.orders, 0),
+				COALESCE(r.amount, 0), COALESCE(r.total, 0),
+				COALESCE(pd.amount, 0), COALESCE(pd.total, 0)
+			FROM buckets b
+			LEFT JOIN sales s ON s.bucket = b.bucket
+			LEFT JOIN refunded r ON r.bucket = b.bucket
+			LEFT JOIN paid pd ON pd.bucket = b.bucket
+			ORDER BY b.bucket`,
+			f.From, f.To.AddDate(0, 0, 1), f.CategoryID, f.SellerID, period)
+		if err != nil {
+			log.Printf("FinanceSummary query error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		data := []FinanceSummaryRow{}
+		var totals FinanceSummaryRow
+		for rows.Next() {
+			var row FinanceSummaryRow
+			var bucket time.Time
+			err := rows.Scan(&bucket, &row.GMV, &row.OrderCount, &row.RefundAmount, &row.RefundCount,
+				&row.PayoutAmount, &row.PayoutCount)
+			if err != nil {
+				continue
+			}
+			row.PeriodStart = bucket.Format("2006-01-02")
+			row.NetSales = row.GMV - row.RefundAmount
+
+			totals.GMV += row.GMV
+			totals.OrderCount += row.OrderCount
+			totals.RefundAmount += row.RefundAmount
+			totals.RefundCount += row.RefundCount
+			totals.PayoutAmount += row.PayoutAmount
+			totals.PayoutCount += row.PayoutCount
+			totals.NetSales += row.NetSales
+
+			data = append(data, row)
+		}
+		totals.PeriodStart = f.From.Format("2006-01-02")
+
+		return c.JSON(fiber.Map{
+			"period": period,
+			"from":   f.From.Format("2006-01-02"),
+			"to":     f.To.Format("2006-01-02"),
+			"data":   data,
+			"totals": totals,
+		})
+	}
+}
+
+// FinanceExportHandler - GET /finance/export - stream CSV/XLSX, export besar diproses worker
+func FinanceExportHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		format := c.Query("format", "csv")
+		if format != "csv" && format != "xlsx" {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid format. Must be 'csv' or 'xlsx'")
+		}
+
+		f, err := parseFinanceFilter(c, ctx, db)
+		if err != nil {
+			return err
+		}
+
+		totalRows, err := report.CountFinanceRows(ctx, db, f)
+		if err != nil {
+			log.Printf("FinanceExport count error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if totalRows <= financeExportSyncLimit {
+			c.Set(fiber.HeaderContentType, report.FinanceExportContentType(format))
+			c.Attachment(report.FinanceExportFileName(format, f))
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				if err := report.WriteFinanceExport(context.Background(), db, w, format, f); err != nil {
+					log.Printf("FinanceExport stream error: %v", err)
+				}
+			})
+			return nil
+		}
+
+		// Export besar: simpan request lalu proses di worker
+		var email string
+		if err := db.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		payload := queue.FinanceExportPayload{
+			Email:      email,
+			Format:     format,
+			From:       f.From.Format("2006-01-02"),
+			To:         f.To.Format("2006-01-02"),
+			CategoryID: f.CategoryID,
+			SellerID:   f.SellerID,
+		}
+		filters, _ := json.Marshal(fiber.Map{
+			"from":        payload.From,
+			"to":          payload.To,
+			"category_id": f.CategoryID,
+			"seller_id":   f.SellerID,
+		})
+
+		err = db.QueryRow(ctx, `
+			INSERT INTO finance_exports (requested_by, format, filters)
+			VALUES ($1, $2, $3)
+			RETURNING id, uuid`,
+			userID, format, filters).Scan(&payload.ExportID, &payload.ExportUUID)
+		if err != nil {
+			log.Printf("FinanceExport insert error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		task, err := queue.NewFinanceExportTask(payload)
+		if err == nil {
+			_, err = queue.Enqueue(task, asynq.Queue("low"))
+		}
+		if err != nil {
+			log.Printf("FinanceExport enqueue error: %v", err)
+			db.Exec(ctx, `UPDATE finance_exports SET status = 'failed', error_message = $1 WHERE id = $2`,
+				"failed to enqueue export", payload.ExportID)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":    "Export is being processed, download link will be sent to " + email,
+			"uuid":       payload.ExportUUID,
+			"status":     "pending",
+			"total_rows": totalRows,
+		})
+	}
+}
+
+// GetFinanceExportHandler - GET /finance/exports/:uuid - status export async milik user
+func GetFinanceExportHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		exportUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		var e FinanceExportResponse
+		err := db.QueryRow(ctx, `
+			SELECT uuid, format, status, file_name, error_message, created_at, completed_at, expires_at
+			FROM finance_exports
+			WHERE uuid = $1 AND requested_by = $2 AND expires_at > NOW()`,
+			exportUUID, userID).Scan(&e.UUID, &e.Format, &e.Status, &e.FileName, &e.ErrorMessage,
+			&e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(e)
+	}
+}
+
+// DownloadFinanceExportHandler - GET /finance/exports/:uuid/download - unduh file export async
+func DownloadFinanceExportHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		exportUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		var format, status string
+		var fileName *string
+		var fileData []byte
+		err := db.QueryRow(ctx, `
+			SELECT format, status, file_name, file_data
+			FROM finance_exports
+			WHERE uuid = $1 AND requested_by = $2 AND expires_at > NOW()`,
+			exportUUID, userID).Scan(&format, &status, &fileName, &fileData)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "completed" || fileName == nil {
+			return fiber.NewError(fiber.StatusConflict, "Export is not ready, current status: "+status)
+		}
+
+		c.Set(fiber.HeaderContentType, report.FinanceExportContentType(format))
+		c.Attachment(*fileName)
+		return c.Send(fileData)
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	utils "shopedia-api/internal/util"
+)
+
+// JWKSHandler - GET /.well-known/jwks.json - public key aktif + retired yang masih berlaku
+func JWKSHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(fiber.Map{"keys": utils.JWKS()})
+	}
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"context"
-	"time"
+	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 
@@ -15,8 +17,9 @@ import (
 func LoginHandler(db *pgxpool.Pool, mode string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		type Input struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
+			Email      string `json:"email"`
+			Password   string `json:"password"`
+			DeviceName string `json:"device_name"`
 		}
 		var input Input
 		if err := c.BodyParser(&input); err != nil {
@@ -25,41 +28,38 @@ func LoginHandler(db *pgxpool.Pool, mode string) fiber.Handler {
 
 		ctx := context.Background()
 
+		// Akun dikunci sementara karena terlalu banyak gagal login (per email, bukan per IP)
+		if until, locked := utils.LoginLockedUntil(ctx, db, input.Email); locked {
+			loginRejected(c, db, 0, input.Email, mode, "locked", input.DeviceName)
+			return loginLocked(c, until)
+		}
+
 		var userID int
 		var passwordHash string
 		var isActive bool
 
 		err := db.QueryRow(ctx,
-			`SELECT id, password_hash, is_active FROM users WHERE email=$1`,
+			`SELECT id, COALESCE(password_hash, ''), is_active FROM users WHERE email=$1`,
 			input.Email).Scan(&userID, &passwordHash, &isActive)
 		if err != nil {
-			return fiber.ErrUnauthorized
+			return loginFailed(c, db, 0, input.Email, mode, "invalid_credentials", input.DeviceName, fiber.ErrUnauthorized)
 		}
 
 		if !isActive {
+			loginRejected(c, db, userID, input.Email, mode, "inactive", input.DeviceName)
 			return fiber.NewError(fiber.StatusUnauthorized, "Account not active")
 		}
 
 		// Verify password terlebih dahulu sebelum cek role
 		if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(input.Password)) != nil {
-			return fiber.ErrUnauthorized
+			return loginFailed(c, db, userID, input.Email, mode, "invalid_credentials", input.DeviceName, fiber.ErrUnauthorized)
 		}
 
-		// Ambil role user
-		var role string
-		err = db.QueryRow(ctx,
-			`SELECT r.name FROM user_roles ur
-			 JOIN roles r ON ur.role_id = r.id
-			 WHERE ur.user_id = $1 LIMIT 1`, userID).Scan(&role)
+		// Ambil role user dan validasi berdasarkan mode
+		roles, err := loginRoles(ctx, db, userID, mode)
 		if err != nil {
-			return fiber.ErrForbidden
-		}
-
-		// Validasi role berdasarkan mode
-		if mode == "app" && role != "end_user" {
-			return fiber.ErrForbidden
-		} else if mode == "admin" && (role != "admin" && role != "super_admin") {
-			return fiber.ErrForbidden
+			loginRejected(c, db, userID, input.Email, mode, "forbidden_role", input.DeviceName)
+			return err
 		}
 
 		// Ambil user UUID
@@ -69,22 +69,118 @@ func LoginHandler(db *pgxpool.Pool, mode string) fiber.Handler {
 			return fiber.ErrInternalServerError
 		}
 
-		// Generate Access Token dengan JTI
-		expires := time.Now().Add(24 * time.Hour)
-		tokenString, jti, err := utils.GenerateAccessToken(userID, userUUID, []string{role}, expires)
-		if err != nil {
-			return fiber.ErrInternalServerError
+		// Login dashboard: 2FA aktif atau diwajibkan role → verifikasi TOTP dulu
+		if mode == "admin" {
+			enabled, required, err := mfaStatus(ctx, db, userID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			if enabled || required {
+				return mfaChallenge(c, userID, userUUID, enabled)
+			}
 		}
 
-		// Set active session (revoke token lama jika ada)
-		err = utils.SetActiveSession(ctx, db, userID, jti, expires, input.Email, role)
+		resp, err := completeLogin(c, db, userID, userUUID, input.Email, roles, mode, input.DeviceName)
 		if err != nil {
-			return fiber.ErrInternalServerError
+			return err
 		}
+		return c.JSON(resp)
+	}
+}
+
+// completeLogin - terbitkan access + refresh token dan simpan session device
+func completeLogin(c *fiber.Ctx, db *pgxpool.Pool, userID int, userUUID, email string, roles *userRoles, mode, device string) (fiber.Map, error) {
+	ctx := context.Background()
+
+	// Generate access token + refresh token (family baru)
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+
+	familyID := uuid.New().String()
+	pair, err := issueTokenPair(ctx, tx, userID, userUUID, roles, mode, familyID)
+	if err != nil {
+		log.Printf("Login issue token error: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	// Catat login + email peringatan jika device / negara baru
+	if err := recordLoginSuccess(ctx, tx, c, userID, email, mode, device); err != nil {
+		log.Printf("Login record event error: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET last_login = NOW() WHERE id = $1`, userID); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
 
-		return c.JSON(fiber.Map{
-			"access_token": tokenString,
-			"expires_at":   expires.Format(time.RFC3339),
-		})
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+
+	utils.ResetLoginFailures(ctx, db, userID, email)
+
+	// Simpan session device ini (session terlama di atas batas role di-revoke)
+	err = utils.CreateSession(ctx, db, userID, pair.AccessJTI, pair.AccessExpiresAt, email, roles.Roles, utils.SessionInfo{
+		FamilyID:   familyID,
+		Mode:       mode,
+		DeviceName: deviceName(device, c.Get("User-Agent")),
+		UserAgent:  c.Get("User-Agent"),
+		IPAddress:  c.IP(),
+	})
+	if err != nil {
+		log.Printf("Login create session error: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	resp := pair.response()
+	resp["roles"] = roles.Roles
+	resp["scopes"] = roles.Scopes
+	return resp, nil
+}
+
+// userRoles - semua role user beserta scope-nya, dimasukkan ke access token
+type userRoles struct {
+	Roles  []string
+	Scopes []string
+}
+
+// loginRoles - ambil semua role user dan validasi terhadap mode login:
+// "app" butuh role scope app (end_user, seller, ...), "admin" butuh role scope dashboard
+func loginRoles(ctx context.Context, q querier, userID int, mode string) (*userRoles, error) {
+	roles := &userRoles{}
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(ARRAY_AGG(r.name ORDER BY r.id), '{}'),
+		        COALESCE(ARRAY_AGG(DISTINCT r.scope) FILTER (WHERE r.scope IS NOT NULL), '{}')
+		 FROM user_roles ur
+		 JOIN roles r ON ur.role_id = r.id
+		 WHERE ur.user_id = $1 AND r.deleted_at IS NULL`, userID).Scan(&roles.Roles, &roles.Scopes)
+	if err != nil {
+		return nil, fiber.ErrForbidden
+	}
+
+	scope := utils.LoginScope(mode)
+	for _, s := range roles.Scopes {
+		if s == scope {
+			return roles, nil
+		}
+	}
+	return nil, fiber.ErrForbidden
+}
+
+// deviceName - nama device dari client, fallback ke User-Agent
+func deviceName(name, userAgent string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = userAgent
+	}
+	if name == "" {
+		return "Unknown device"
+	}
+	if len(name) > 100 {
+		name = name[:100]
 	}
+	return name
 }
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/queue"
+	utils "shopedia-api/internal/util"
+)
+
+// ============================================
+// Login Events & Lockout
+// ============================================
+
+// clientCountry - kode negara dari header proxy/CDN (GEOIP_COUNTRY_HEADER, default CF-IPCountry)
+func clientCountry(c *fiber.Ctx) string {
+	header := os.Getenv("GEOIP_COUNTRY_HEADER")
+	if header == "" {
+		header = "CF-IPCountry"
+	}
+	country := strings.ToUpper(strings.TrimSpace(c.Get(header)))
+	if len(country) != 2 || country == "XX" || country == "T1" {
+		return ""
+	}
+	return country
+}
+
+// recordLoginEvent - catat percobaan login; userID 0 → dicari dari email (NULL jika tidak terdaftar)
+func recordLoginEvent(ctx context.Context, q queue.Execer, c *fiber.Ctx, userID int, email, mode string, success bool, reason, device string) error {
+	var failureReason *string
+	if reason != "" {
+		failureReason = &reason
+	}
+	var country *string
+	if cc := clientCountry(c); cc != "" {
+		country = &cc
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO login_events (user_id, email, mode, success, failure_reason, ip_address, user_agent, device_name, country)
+		VALUES (COALESCE(NULLIF($1, 0), (SELECT id FROM users WHERE LOWER(email) = LOWER($2))), $2, $3, $4, $5, $6, $7, $8, $9)`,
+		userID, email, mode, success, failureReason, c.IP(), c.Get("User-Agent"), deviceName(device, c.Get("User-Agent")), country)
+	return err
+}
+
+// loginFailed - catat gagal login yang dihitung untuk lockout (password / kode 2FA salah)
+func loginFailed(c *fiber.Ctx, db *pgxpool.Pool, userID int, email, mode, reason, device string, errResp error) error {
+	ctx := context.Background()
+
+	if err := recordLoginEvent(ctx, db, c, userID, email, mode, false, reason, device); err != nil {
+		log.Printf("Record login event error: %v", err)
+	}
+
+	if until := utils.RecordLoginFailure(ctx, db, userID, email); !until.IsZero() {
+		return loginLocked(c, until)
+	}
+	return errResp
+}
+
+// loginRejected - catat login yang ditolak tanpa menambah hitungan lockout
+func loginRejected(c *fiber.Ctx, db *pgxpool.Pool, userID int, email, mode, reason, device string) {
+	if err := recordLoginEvent(context.Background(), db, c, userID, email, mode, false, reason, device); err != nil {
+		log.Printf("Record login event error: %v", err)
+	}
+}
+
+// loginLocked - response 429 selama akun dikunci
+func loginLocked(c *fiber.Ctx, until time.Time) error {
+	seconds := int(time.Until(until).Seconds()) + 1
+	c.Set("Retry-After", strconv.Itoa(seconds))
+	return fiber.NewError(fiber.StatusTooManyRequests,
+		fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
+}
+
+// recordLoginSuccess - catat login sukses; login dari device / negara yang belum pernah
+// dipakai sebelumnya → email peringatan lewat outbox (login pertama tidak dikirimi email)
+func recordLoginSuccess(ctx context.Context, tx pgx.Tx, c *fiber.Ctx, userID int, email, mode, device string) error {
+	device = deviceName(device, c.Get("User-Agent"))
+	country := clientCountry(c)
+
+	var hasHistory, hasCountryHistory, knownDevice, knownCountry bool
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) > 0,
+		       COALESCE(BOOL_OR(country IS NOT NULL), FALSE),
+		       COALESCE(BOOL_OR(device_name = $2), FALSE),
+		       COALESCE(BOOL_OR(country = $3), FALSE)
+		FROM login_events
+		WHERE user_id = $1 AND success = TRUE`,
+		userID, device, country).Scan(&hasHistory, &hasCountryHistory, &knownDevice, &knownCountry)
+	if err != nil {
+		return err
+	}
+
+	if err := recordLoginEvent(ctx, tx, c, userID, email, mode, true, "", device); err != nil {
+		return err
+	}
+
+	newDevice := !knownDevice
+	newCountry := country != "" && hasCountryHistory && !knownCountry
+	if !hasHistory || (!newDevice && !newCountry) {
+		return nil
+	}
+
+	var name string
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(full_name, '') FROM users WHERE id = $1`, userID).Scan(&name); err != nil {
+		return err
+	}
+
+	task, err := queue.NewSendLoginAlertTask(queue.SendLoginAlertPayload{
+		Email:      email,
+		Name:       name,
+		DeviceName: device,
+		IPAddress:  c.IP(),
+		Country:    country,
+		LoginAt:    time.Now().Format("02 Jan 2006 15:04 MST"),
+		NewDevice:  newDevice,
+		NewCountry: newCountry,
+	})
+	if err != nil {
+		return err
+	}
+	return queue.AddToOutbox(ctx, tx, task, "default")
+}
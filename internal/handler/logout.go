@@ -35,7 +35,6 @@ func LogoutHandler(db *pgxpool.Pool) fiber.Handler {
 }
 
 // LogoutAllHandler - revoke semua token user (force logout dari semua device)
-// Dengan Single Active Session, ini sama saja dengan LogoutHandler
 func LogoutAllHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := c.Locals("userID").(int)
@@ -48,8 +47,9 @@ func LogoutAllHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.ErrInternalServerError
 		}
 
-		// Clear active session
-		err = utils.ClearActiveSession(c.Context(), db, userID, jti)
+		// Clear semua session + refresh token user; access token device lain
+		// langsung ditolak JWTProtected karena session-nya sudah tidak ada
+		err = utils.ClearActiveSession(c.Context(), db, userID, "")
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
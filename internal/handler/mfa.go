@@ -0,0 +1,516 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	utils "shopedia-api/internal/util"
+)
+
+const mfaPendingTTL = 5 * time.Minute
+
+var errInvalidMFACode = fiber.NewError(fiber.StatusUnauthorized, "Invalid 2FA code")
+
+// ============================================
+// MFA Helpers
+// ============================================
+
+// mfaStatus - apakah TOTP user sudah aktif, dan apakah salah satu role-nya mewajibkan 2FA
+func mfaStatus(ctx context.Context, q querier, userID int) (bool, bool, error) {
+	var enabled, required bool
+	err := q.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT is_enabled FROM user_totp WHERE user_id = $1), FALSE),
+			EXISTS(
+				SELECT 1 FROM user_roles ur
+				JOIN roles r ON ur.role_id = r.id
+				WHERE ur.user_id = $1 AND r.mfa_required = TRUE AND r.deleted_at IS NULL
+			)`, userID).Scan(&enabled, &required)
+	return enabled, required, err
+}
+
+// mfaChallenge - response login tahap 1: password valid, lanjut verifikasi / enrollment 2FA
+func mfaChallenge(c *fiber.Ctx, userID int, userUUID string, enabled bool) error {
+	expires := time.Now().Add(mfaPendingTTL)
+	token, _, err := utils.GenerateMFAPendingToken(userID, userUUID, expires)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{
+		"mfa_required":       true,
+		"mfa_setup_required": !enabled,
+		"mfa_token":          token,
+		"expires_at":         expires.Format(time.RFC3339),
+	})
+}
+
+// parseMFAToken - validasi mfa_pending token dari body login 2FA
+func parseMFAToken(tokenString string) (*utils.TokenClaims, error) {
+	claims, err := utils.ParseToken(tokenString)
+	if err != nil {
+		return nil, fiber.ErrUnauthorized
+	}
+	if claims.Type != utils.TokenTypeMFA {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token type")
+	}
+	return claims, nil
+}
+
+// verifySecondFactor - cek kode TOTP atau recovery code (sekali pakai) dalam tx
+func verifySecondFactor(ctx context.Context, tx pgx.Tx, userID int, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		tag, err := tx.Exec(ctx, `
+			UPDATE mfa_recovery_codes SET used_at = NOW()
+			WHERE id = (
+				SELECT id FROM mfa_recovery_codes
+				WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+				LIMIT 1
+			)`, userID, utils.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if tag.RowsAffected() == 0 {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	var secret string
+	var lastStep int64
+	err := tx.QueryRow(ctx, `
+		SELECT secret, last_used_step FROM user_totp
+		WHERE user_id = $1 AND is_enabled = TRUE
+		FOR UPDATE`, userID).Scan(&secret, &lastStep)
+	if err != nil {
+		return errInvalidMFACode
+	}
+
+	return useTOTPCode(ctx, tx, userID, secret, lastStep, code)
+}
+
+// useTOTPCode - validasi kode dan simpan time step-nya; kode yang sama tidak bisa dipakai dua kali
+func useTOTPCode(ctx context.Context, tx pgx.Tx, userID int, secret string, lastStep int64, code string) error {
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return errInvalidMFACode
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE user_totp SET last_used_step = $1, updated_at = NOW() WHERE user_id = $2`,
+		step, userID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	return nil
+}
+
+// replaceRecoveryCodes - hapus recovery code lama dan buat set baru
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int) ([]string, error) {
+	codes, hashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	for _, h := range hashes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// beginTOTPSetup - buat secret baru (belum aktif sampai dikonfirmasi)
+func beginTOTPSetup(ctx context.Context, db *pgxpool.Pool, userID int) (fiber.Map, error) {
+	var email string
+	var enabled bool
+	err := db.QueryRow(ctx, `
+		SELECT u.email, COALESCE(t.is_enabled, FALSE)
+		FROM users u LEFT JOIN user_totp t ON t.user_id = u.id
+		WHERE u.id = $1`, userID).Scan(&email, &enabled)
+	if err != nil {
+		return nil, fiber.ErrNotFound
+	}
+	if enabled {
+		return nil, fiber.NewError(fiber.StatusConflict, "2FA already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+
+	_, err = db.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret, is_enabled)
+		VALUES ($1, $2, FALSE)
+		ON CONFLICT (user_id) DO UPDATE SET secret = $2, is_enabled = FALSE, last_used_step = 0,
+			confirmed_at = NULL, updated_at = NOW()`,
+		userID, secret)
+	if err != nil {
+		log.Printf("Setup TOTP error: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	return fiber.Map{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(email, secret),
+	}, nil
+}
+
+// confirmTOTPSetup - aktifkan TOTP dengan kode pertama, return recovery codes
+func confirmTOTPSetup(ctx context.Context, db *pgxpool.Pool, userID int, code string) ([]string, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+
+	var secret string
+	var lastStep int64
+	err = tx.QueryRow(ctx, `
+		SELECT secret, last_used_step FROM user_totp
+		WHERE user_id = $1 AND is_enabled = FALSE
+		FOR UPDATE`, userID).Scan(&secret, &lastStep)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "No pending 2FA setup")
+	}
+
+	if err := useTOTPCode(ctx, tx, userID, secret, lastStep, code); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_totp SET is_enabled = TRUE, confirmed_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		log.Printf("Confirm TOTP error: %v", err)
+		return nil, fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+	return codes, nil
+}
+
+// mfaLoginUser - validasi mfa_token dan status user untuk login tahap 2
+func mfaLoginUser(ctx context.Context, db *pgxpool.Pool, mfaToken string) (int, string, string, error) {
+	claims, err := parseMFAToken(mfaToken)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	var email string
+	var isActive, isBanned, isDeleted bool
+	err = db.QueryRow(ctx, `
+		SELECT email, is_active, COALESCE(is_banned, FALSE), (deleted_at IS NOT NULL)
+		FROM users WHERE id = $1`,
+		claims.UserID).Scan(&email, &isActive, &isBanned, &isDeleted)
+	if err != nil || !isActive || isBanned || isDeleted {
+		return 0, "", "", fiber.NewError(fiber.StatusUnauthorized, "Account is not active")
+	}
+
+	return claims.UserID, claims.UserUUID, email, nil
+}
+
+// ============================================
+// Login 2FA Handlers (pakai mfa_token, tanpa JWT)
+// ============================================
+
+// VerifyMFALoginHandler - POST /api/admin/login/2fa - login tahap 2 dengan kode TOTP / recovery code
+func VerifyMFALoginHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			MFAToken     string `json:"mfa_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+			DeviceName   string `json:"device_name"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+		if input.Code == "" && input.RecoveryCode == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "code or recovery_code is required")
+		}
+
+		ctx := context.Background()
+
+		userID, userUUID, email, err := mfaLoginUser(ctx, db, input.MFAToken)
+		if err != nil {
+			return err
+		}
+
+		// Tebakan kode 2FA ikut dihitung untuk lockout akun
+		if until, locked := utils.LoginLockedUntil(ctx, db, email); locked {
+			loginRejected(c, db, userID, email, "admin", "locked", input.DeviceName)
+			return loginLocked(c, until)
+		}
+
+		roles, err := loginRoles(ctx, db, userID, "admin")
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		if err := verifySecondFactor(ctx, tx, userID, input.Code, input.RecoveryCode); err != nil {
+			if err == errInvalidMFACode {
+				tx.Rollback(ctx)
+				return loginFailed(c, db, userID, email, "admin", "invalid_2fa", input.DeviceName, err)
+			}
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		resp, err := completeLogin(c, db, userID, userUUID, email, roles, "admin", input.DeviceName)
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
+	}
+}
+
+// SetupMFALoginHandler - POST /api/admin/login/2fa/setup - enrollment saat 2FA diwajibkan role
+func SetupMFALoginHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			MFAToken string `json:"mfa_token"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+
+		userID, _, _, err := mfaLoginUser(ctx, db, input.MFAToken)
+		if err != nil {
+			return err
+		}
+
+		result, err := beginTOTPSetup(ctx, db, userID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(result)
+	}
+}
+
+// ConfirmMFALoginHandler - POST /api/admin/login/2fa/confirm - konfirmasi enrollment lalu login
+func ConfirmMFALoginHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			MFAToken   string `json:"mfa_token"`
+			Code       string `json:"code"`
+			DeviceName string `json:"device_name"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+
+		userID, userUUID, email, err := mfaLoginUser(ctx, db, input.MFAToken)
+		if err != nil {
+			return err
+		}
+
+		roles, err := loginRoles(ctx, db, userID, "admin")
+		if err != nil {
+			return err
+		}
+
+		codes, err := confirmTOTPSetup(ctx, db, userID, input.Code)
+		if err != nil {
+			return err
+		}
+
+		resp, err := completeLogin(c, db, userID, userUUID, email, roles, "admin", input.DeviceName)
+		if err != nil {
+			return err
+		}
+
+		// Recovery codes hanya ditampilkan sekali
+		resp["recovery_codes"] = codes
+		return c.JSON(resp)
+	}
+}
+
+// ============================================
+// 2FA Management Handlers (JWT)
+// ============================================
+
+// GetMFAStatusHandler - GET /api/admin/2fa - status 2FA user sendiri
+func GetMFAStatusHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		enabled, required, err := mfaStatus(ctx, db, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		var remaining int
+		_ = db.QueryRow(ctx, `
+			SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`,
+			userID).Scan(&remaining)
+
+		return c.JSON(fiber.Map{
+			"enabled":                  enabled,
+			"required":                 required,
+			"recovery_codes_remaining": remaining,
+		})
+	}
+}
+
+// SetupMFAHandler - POST /api/admin/2fa/setup - mulai enrollment TOTP
+func SetupMFAHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		result, err := beginTOTPSetup(context.Background(), db, userID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(result)
+	}
+}
+
+// ConfirmMFAHandler - POST /api/admin/2fa/confirm - aktifkan TOTP, return recovery codes
+func ConfirmMFAHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Code string `json:"code"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		codes, err := confirmTOTPSetup(context.Background(), db, userID, input.Code)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message":        "2FA enabled",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// RegenerateRecoveryCodesHandler - POST /api/admin/2fa/recovery-codes - ganti semua recovery code
+func RegenerateRecoveryCodesHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Code string `json:"code"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		if err := verifySecondFactor(ctx, tx, userID, input.Code, ""); err != nil {
+			return err
+		}
+
+		codes, err := replaceRecoveryCodes(ctx, tx, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"recovery_codes": codes})
+	}
+}
+
+// DisableMFAHandler - POST /api/admin/2fa/disable - matikan 2FA (ditolak jika diwajibkan role)
+func DisableMFAHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+
+		enabled, required, err := mfaStatus(ctx, db, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if !enabled {
+			return fiber.NewError(fiber.StatusBadRequest, "2FA is not enabled")
+		}
+		if required {
+			return fiber.NewError(fiber.StatusForbidden, "2FA is mandatory for your role")
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		if err := verifySecondFactor(ctx, tx, userID, input.Code, input.RecoveryCode); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "2FA disabled"})
+	}
+}
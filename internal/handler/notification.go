@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"log"
+
+	"shopedia-api/internal/queue"
+)
+
+// notifyUser - enqueue notifikasi ke worker, error hanya di-log karena
+// perubahan data utama sudah tersimpan
+func notifyUser(userID int, title, message, notifType string) {
+	task, err := queue.NewNotificationTask(userID, title, message, notifType)
+	if err != nil {
+		log.Printf("Create notification task error: %v", err)
+		return
+	}
+	if _, err := queue.Enqueue(task); err != nil {
+		log.Printf("Enqueue notification error: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/oidc"
+	utils "shopedia-api/internal/util"
+)
+
+// ============================================
+// OIDC Social Login (Google, Apple, dll)
+// ============================================
+
+const oidcStateTTL = 10 * time.Minute
+
+// ListOIDCProvidersHandler - GET /api/app/oidc/providers - provider social login yang aktif
+func ListOIDCProvidersHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"providers": oidc.Names()})
+	}
+}
+
+// OIDCAuthorizeHandler - GET /api/app/oidc/:provider/authorize - buat state/nonce/PKCE dan URL login provider
+func OIDCAuthorizeHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("provider")
+		provider, ok := oidc.Get(name)
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "Provider not found")
+		}
+
+		ctx := context.Background()
+
+		state, err := oidc.RandomString()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		nonce, err := oidc.RandomString()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		verifier, err := oidc.RandomString()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+		if err != nil {
+			log.Printf("OIDC authorize %s error: %v", name, err)
+			return fiber.NewError(fiber.StatusBadGateway, "Provider unavailable")
+		}
+
+		expiresAt := time.Now().Add(oidcStateTTL)
+		_, err = db.Exec(ctx, `
+			INSERT INTO oidc_states (state, provider, nonce, code_verifier, expires_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			state, name, nonce, verifier, expiresAt)
+		if err != nil {
+			log.Printf("OIDC save state error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{
+			"authorization_url": authURL,
+			"state":             state,
+			"expires_at":        expiresAt.Format(time.RFC3339),
+		})
+	}
+}
+
+// OIDCCallbackHandler - POST /api/app/oidc/:provider/callback - tukar code, verifikasi ID token, login / link akun
+func OIDCCallbackHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("provider")
+		provider, ok := oidc.Get(name)
+		if !ok {
+			return fiber.NewError(fiber.StatusNotFound, "Provider not found")
+		}
+
+		type Input struct {
+			Code       string `json:"code"`
+			State      string `json:"state"`
+			DeviceName string `json:"device_name"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+		if input.Code == "" || input.State == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Code and state are required")
+		}
+
+		ctx := context.Background()
+
+		// State sekali pakai: hapus saat dipakai supaya callback tidak bisa di-replay
+		var nonce, verifier string
+		err := db.QueryRow(ctx, `
+			DELETE FROM oidc_states
+			WHERE state = $1 AND provider = $2 AND expires_at > NOW()
+			RETURNING nonce, code_verifier`,
+			input.State, name).Scan(&nonce, &verifier)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired state")
+		}
+
+		rawIDToken, err := provider.Exchange(ctx, input.Code, verifier)
+		if err != nil {
+			log.Printf("OIDC exchange %s error: %v", name, err)
+			return fiber.NewError(fiber.StatusUnauthorized, "Authorization code rejected by provider")
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, rawIDToken, nonce)
+		if err != nil {
+			log.Printf("OIDC verify %s error: %v", name, err)
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid ID token")
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		userID, err := linkOIDCIdentity(ctx, tx, name, claims)
+		if err != nil {
+			return err
+		}
+
+		var userUUID, email string
+		var isActive, isBanned, isDeleted bool
+		err = tx.QueryRow(ctx, `
+			SELECT uuid, email, is_active, COALESCE(is_banned, FALSE), (deleted_at IS NOT NULL)
+			FROM users WHERE id = $1`,
+			userID).Scan(&userUUID, &email, &isActive, &isBanned, &isDeleted)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if isDeleted || isBanned || !isActive {
+			return fiber.NewError(fiber.StatusForbidden, "Account not active")
+		}
+
+		roles, err := loginRoles(ctx, tx, userID, "app")
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+		// Akun bisa saja baru diaktifkan saat linking
+		utils.InvalidateAuthContext(userID)
+
+		resp, err := completeLogin(c, db, userID, userUUID, email, roles, "app", input.DeviceName)
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
+	}
+}
+
+// linkOIDCIdentity - cari user dari (provider, sub); jika belum ter-link, link ke user
+// dengan email yang sama atau buat user baru. Link via email hanya jika email_verified.
+func linkOIDCIdentity(ctx context.Context, tx pgx.Tx, provider string, claims *oidc.Claims) (int, error) {
+	var userID int
+	err := tx.QueryRow(ctx, `
+		UPDATE user_identities SET email = COALESCE(NULLIF($3, ''), email), last_login_at = NOW()
+		WHERE provider = $1 AND subject = $2
+		RETURNING user_id`,
+		provider, claims.Subject, claims.Email).Scan(&userID)
+	if err == nil {
+		return userID, nil
+	}
+	if err != pgx.ErrNoRows {
+		log.Printf("OIDC lookup identity error: %v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+
+	if !claims.IsEmailVerified() {
+		return 0, fiber.NewError(fiber.StatusForbidden, "Email not verified by provider")
+	}
+
+	var isActive, isInvited, emailVerified bool
+	err = tx.QueryRow(ctx, `
+		SELECT id, is_active, COALESCE(is_invited, FALSE), email_verified_at IS NOT NULL
+		FROM users WHERE LOWER(email) = LOWER($1) FOR UPDATE`,
+		claims.Email).Scan(&userID, &isActive, &isInvited, &emailVerified)
+	switch {
+	case err == pgx.ErrNoRows:
+		// User baru: email sudah diverifikasi provider, langsung aktif tanpa OTP
+		fullName := strings.TrimSpace(claims.Name)
+		if fullName == "" {
+			fullName = strings.Split(claims.Email, "@")[0]
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (email, full_name, password_hash, is_active, email_verified_at)
+			VALUES ($1, $2, NULL, TRUE, NOW())
+			RETURNING id`,
+			strings.ToLower(claims.Email), fullName).Scan(&userID)
+		if err != nil {
+			log.Printf("OIDC create user error: %v", err)
+			return 0, fiber.ErrInternalServerError
+		}
+		if err := assignEndUserRole(ctx, tx, userID); err != nil {
+			return 0, err
+		}
+
+	case err != nil:
+		log.Printf("OIDC lookup user error: %v", err)
+		return 0, fiber.ErrInternalServerError
+
+	case !isActive && (emailVerified || isInvited):
+		// Dinonaktifkan admin atau undangan yang belum diterima: tidak boleh diaktifkan lewat provider
+		return 0, fiber.NewError(fiber.StatusForbidden, "Account not active")
+
+	case !isActive:
+		// Registrasi yang belum verifikasi OTP: pemilik email sudah terbukti lewat provider
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET is_active = TRUE, email_verified_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, userID)
+		if err != nil {
+			return 0, fiber.ErrInternalServerError
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM otp_codes WHERE user_id = $1`, userID); err != nil {
+			return 0, fiber.ErrInternalServerError
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())`,
+		userID, provider, claims.Subject, claims.Email)
+	if err != nil {
+		log.Printf("OIDC link identity error: %v", err)
+		return 0, fiber.ErrInternalServerError
+	}
+
+	return userID, nil
+}
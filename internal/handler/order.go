@@ -0,0 +1,934 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Order Response Types
+// ============================================
+
+type OrderProduct struct {
+	UUID  string  `json:"uuid"`
+	Title string  `json:"title"`
+	Slug  string  `json:"slug"`
+	Image *string `json:"image"`
+}
+
+type OrderItemResponse struct {
+	UUID       string             `json:"uuid"` // transaction uuid
+	Product    OrderProduct       `json:"product"`
+	Variant    *ProductVariantRef `json:"variant,omitempty"`
+	Qty        int                `json:"qty"`
+	UnitPrice  int64              `json:"unit_price"`
+	TotalPrice int64              `json:"total_price"`
+	Status     string             `json:"status"`
+}
+
+type OrderResponse struct {
+	UUID           string              `json:"uuid"`
+	SubtotalPrice  int64               `json:"subtotal_price"`
+	DiscountAmount int64               `json:"discount_amount"`
+	TotalPrice     int64               `json:"total_price"`
+	Status         string              `json:"status"`
+	Items          []OrderItemResponse `json:"items"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// getOrderItems - ambil baris transactions untuk beberapa order sekaligus
+func getOrderItems(ctx context.Context, db *pgxpool.Pool, orderIDs []int) (map[int][]OrderItemResponse, error) {
+	items := make(map[int][]OrderItemResponse)
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT t.order_id, t.uuid, p.uuid, p.title, COALESCE(p.slug, ''),
+			COALESCE(to_json(p.images), '[]'::json)::text,
+			v.uuid::TEXT, v.sku, v.options::TEXT,
+			t.qty, t.unit_price, t.total_price, t.status
+		FROM transactions t
+		JOIN products p ON t.product_id = p.id
+		LEFT JOIN product_variants v ON t.variant_id = v.id
+		WHERE t.order_id = ANY($1)
+		ORDER BY t.id`, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID int
+		var item OrderItemResponse
+		var imagesJSON string
+		var variantUUID, variantSKU, variantOptions *string
+		err := rows.Scan(&orderID, &item.UUID, &item.Product.UUID, &item.Product.Title, &item.Product.Slug,
+			&imagesJSON, &variantUUID, &variantSKU, &variantOptions, &item.Qty, &item.UnitPrice, &item.TotalPrice, &item.Status)
+		if err != nil {
+			continue
+		}
+		item.Variant = variantRef(variantUUID, variantSKU, variantOptions)
+
+		var images []string
+		if json.Unmarshal([]byte(imagesJSON), &images) == nil && len(images) > 0 {
+			item.Product.Image = &images[0]
+		}
+
+		items[orderID] = append(items[orderID], item)
+	}
+
+	return items, nil
+}
+
+// orderLine - produk (dan varian yang dipilih) untuk satu baris belanja, dengan harga & stock saat ini
+type orderLine struct {
+	productID  int
+	variantID  *int
+	categoryID *int
+	sellerID   int
+	unitPrice  int64
+	stock      int
+}
+
+// lookupOrderLine - validasi produk / varian untuk checkout dan validasi promo, sehingga keduanya
+// memakai harga yang sama (produk bervarian: harga & stock dari varian). forUpdate=true mengunci
+// baris produk & varian sampai DB transaction selesai (dipakai checkout).
+func lookupOrderLine(ctx context.Context, q querier, productUUID, variantUUID string, userID int, forUpdate bool) (orderLine, error) {
+	lock := ""
+	if forUpdate {
+		lock = ` FOR UPDATE OF p`
+	}
+
+	var line orderLine
+	var status string
+	var isActive, hasVariants, ownShop bool
+	err := q.QueryRow(ctx, `
+		SELECT p.id, p.category_id, p.owner_user_id, p.price, p.stock, p.status, p.is_active, p.has_variants,
+			(p.owner_user_id = $2 OR EXISTS (
+				SELECT 1 FROM shop_members sm
+				WHERE sm.shop_id = p.shop_id AND sm.user_id = $2 AND sm.status = 'active'
+			))
+		FROM products p WHERE p.uuid = $1 AND p.deleted_at IS NULL`+lock,
+		productUUID, userID).Scan(&line.productID, &line.categoryID, &line.sellerID, &line.unitPrice, &line.stock, &status, &isActive,
+		&hasVariants, &ownShop)
+	if err != nil {
+		return line, fiber.NewError(fiber.StatusNotFound, "Product not found: "+productUUID)
+	}
+
+	// Produk bervarian: harga & stock dari varian yang dipilih
+	if hasVariants {
+		if variantUUID == "" {
+			return line, fiber.NewError(fiber.StatusBadRequest, "Variant UUID is required for product: "+productUUID)
+		}
+		variantLock := ""
+		if forUpdate {
+			variantLock = ` FOR UPDATE`
+		}
+		var variantID int
+		var variantActive bool
+		err := q.QueryRow(ctx, `
+			SELECT id, price, stock, is_active FROM product_variants
+			WHERE uuid::TEXT = $1 AND product_id = $2 AND deleted_at IS NULL`+variantLock,
+			variantUUID, line.productID).Scan(&variantID, &line.unitPrice, &line.stock, &variantActive)
+		if err != nil {
+			return line, fiber.NewError(fiber.StatusNotFound, "Variant not found: "+variantUUID)
+		}
+		if !variantActive {
+			return line, fiber.NewError(fiber.StatusConflict, "Variant is not available: "+variantUUID)
+		}
+		line.variantID = &variantID
+	} else if variantUUID != "" {
+		return line, fiber.NewError(fiber.StatusBadRequest, "Product has no variants: "+productUUID)
+	}
+
+	if status != "active" || !isActive {
+		return line, fiber.NewError(fiber.StatusConflict, "Product is not available: "+productUUID)
+	}
+
+	// Owner maupun staff aktif toko tidak boleh membeli produk tokonya sendiri
+	if ownShop {
+		return line, fiber.NewError(fiber.StatusBadRequest, "Cannot buy your own product")
+	}
+
+	return line, nil
+}
+
+// PendingOrderTTL - batas waktu order pending sebelum dibatalkan otomatis oleh ExpirePendingOrders
+const PendingOrderTTL = 24 * time.Hour
+
+// cancelOrder - batalkan order pending: stock produk & varian dikembalikan, redemption promo dilepas
+// (harus dalam DB transaction, order sudah di-lock FOR UPDATE oleh pemanggil)
+func cancelOrder(ctx context.Context, tx pgx.Tx, orderID int, reason string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE transactions SET status = 'cancelled', updated_at = NOW()
+		WHERE order_id = $1 AND status = 'pending'`, orderID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE products p SET stock = p.stock + t.qty, updated_at = NOW()
+		FROM (
+			SELECT product_id, SUM(qty) AS qty FROM transactions
+			WHERE order_id = $1
+			GROUP BY product_id
+		) t
+		WHERE p.id = t.product_id`, orderID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE product_variants v SET stock = v.stock + t.qty, updated_at = NOW()
+		FROM (
+			SELECT variant_id, SUM(qty) AS qty FROM transactions
+			WHERE order_id = $1 AND variant_id IS NOT NULL
+			GROUP BY variant_id
+		) t
+		WHERE v.id = t.variant_id`, orderID)
+	if err != nil {
+		return err
+	}
+
+	// Redemption dihapus agar usage_limit & usage_per_user kembali tersedia
+	var promoID int
+	err = tx.QueryRow(ctx, `DELETE FROM promo_redemptions WHERE order_id = $1 RETURNING promo_id`,
+		orderID).Scan(&promoID)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		_, err = tx.Exec(ctx, `UPDATE promos SET used_count = GREATEST(used_count - 1, 0), updated_at = NOW() WHERE id = $1`,
+			promoID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders SET status = 'cancelled', cancelled_at = NOW(), cancel_reason = $1, updated_at = NOW()
+		WHERE id = $2`, reason, orderID)
+	return err
+}
+
+// ExpirePendingOrders - batalkan order yang masih pending lebih lama dari PendingOrderTTL.
+// Tiap order diproses dalam DB transaction sendiri, order yang sedang di-lock (mis. sedang
+// dikonfirmasi pembayarannya) dilewati dan dicoba lagi di putaran berikutnya.
+func ExpirePendingOrders(ctx context.Context, db *pgxpool.Pool) (int, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id FROM orders
+		WHERE status = 'pending' AND created_at < NOW() - make_interval(secs => $1)
+		ORDER BY created_at
+		LIMIT 100`, PendingOrderTTL.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	orderIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	expired := 0
+	for _, orderID := range orderIDs {
+		ok, buyerID, orderUUID, err := expireOrder(ctx, db, orderID)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+			notifyUser(buyerID, "Order dibatalkan",
+				fmt.Sprintf("Order %s dibatalkan otomatis karena melewati batas waktu", orderUUID), "warning")
+		}
+	}
+
+	return expired, nil
+}
+
+// expireOrder - batalkan satu order jika masih pending
+func expireOrder(ctx context.Context, db *pgxpool.Pool, orderID int) (bool, int, string, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var buyerID int
+	var orderUUID string
+	err = tx.QueryRow(ctx, `
+		SELECT buyer_user_id, uuid FROM orders WHERE id = $1 AND status = 'pending'
+		FOR UPDATE SKIP LOCKED`, orderID).Scan(&buyerID, &orderUUID)
+	if err == pgx.ErrNoRows {
+		return false, 0, "", nil
+	}
+	if err != nil {
+		return false, 0, "", err
+	}
+
+	if err := cancelOrder(ctx, tx, orderID, "expired"); err != nil {
+		return false, 0, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, "", err
+	}
+	return true, buyerID, orderUUID, nil
+}
+
+// ============================================
+// Buyer Order Handlers (App)
+// ============================================
+
+// CheckoutHandler - POST /orders - buat order dari daftar produk
+func CheckoutHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type ItemInput struct {
+			ProductUUID string `json:"product_uuid"`
+			VariantUUID string `json:"variant_uuid"` // wajib untuk produk bervarian
+			Qty         int    `json:"qty"`
+		}
+		type Input struct {
+			Items     []ItemInput `json:"items"`
+			PromoCode string      `json:"promo_code"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if len(input.Items) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "At least one item is required")
+		}
+
+		// Gabungkan produk + varian yang sama agar stock dicek sekali per baris
+		itemsByKey := make(map[string]ItemInput)
+		for _, item := range input.Items {
+			if item.ProductUUID == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Product UUID is required")
+			}
+			if item.Qty < 1 {
+				return fiber.NewError(fiber.StatusBadRequest, "Quantity must be at least 1")
+			}
+			key := item.ProductUUID + "/" + item.VariantUUID
+			if existing, ok := itemsByKey[key]; ok {
+				item.Qty += existing.Qty
+			}
+			itemsByKey[key] = item
+		}
+
+		// Lock produk dengan urutan tetap untuk menghindari deadlock antar checkout
+		itemKeys := make([]string, 0, len(itemsByKey))
+		for key := range itemsByKey {
+			itemKeys = append(itemKeys, key)
+		}
+		sort.Strings(itemKeys)
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		type lineItem struct {
+			orderLine
+			qty      int
+			discount int64
+		}
+		lines := []lineItem{}
+		var orderTotal int64
+
+		for _, key := range itemKeys {
+			item := itemsByKey[key]
+			productUUID, qty := item.ProductUUID, item.Qty
+
+			var line lineItem
+			line.orderLine, err = lookupOrderLine(ctx, tx, productUUID, item.VariantUUID, userID, true)
+			if err != nil {
+				return err
+			}
+			stock := line.stock
+
+			if stock < qty {
+				return fiber.NewError(fiber.StatusConflict, "Insufficient stock for product: "+productUUID)
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE products SET stock = stock - $1, updated_at = NOW() WHERE id = $2`,
+				qty, line.productID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+
+			if line.variantID != nil {
+				_, err = tx.Exec(ctx, `UPDATE product_variants SET stock = stock - $1, updated_at = NOW() WHERE id = $2`,
+					qty, *line.variantID)
+				if err != nil {
+					return fiber.ErrInternalServerError
+				}
+			}
+
+			line.qty = qty
+			lines = append(lines, line)
+			orderTotal += line.unitPrice * int64(qty)
+		}
+
+		// Promo dikunci (FOR UPDATE) sampai commit agar usage limit tidak terlampaui
+		var promo *promoResult
+		var discount int64
+		if input.PromoCode != "" {
+			items := make([]promoItem, 0, len(lines))
+			for _, line := range lines {
+				items = append(items, promoItem{
+					productID:  line.productID,
+					categoryID: line.categoryID,
+					subtotal:   line.unitPrice * int64(line.qty),
+				})
+			}
+			promo, err = evaluatePromo(ctx, tx, input.PromoCode, userID, items, true)
+			if err != nil {
+				return err
+			}
+			discount = promo.discount
+			for i := range lines {
+				lines[i].discount = promo.lineDiscounts[i]
+			}
+		}
+
+		var promoID *int
+		if promo != nil {
+			promoID = &promo.promoID
+		}
+
+		var orderID int
+		var orderUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO orders (buyer_user_id, subtotal_price, discount_amount, promo_id, total_price, status)
+			VALUES ($1, $2, $3, $4, $5, 'pending')
+			RETURNING id, uuid`,
+			userID, orderTotal, discount, promoID, orderTotal-discount).Scan(&orderID, &orderUUID)
+		if err != nil {
+			log.Printf("Checkout insert order error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		for _, line := range lines {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO transactions (order_id, buyer_user_id, seller_user_id, product_id, variant_id, qty, unit_price, total_price,
+					discount_amount, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'pending')`,
+				orderID, userID, line.sellerID, line.productID, line.variantID, line.qty, line.unitPrice, line.unitPrice*int64(line.qty),
+				line.discount)
+			if err != nil {
+				log.Printf("Checkout insert transaction error: %v", err)
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if promo != nil {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO promo_redemptions (promo_id, user_id, order_id, discount_amount)
+				VALUES ($1, $2, $3, $4)`,
+				promo.promoID, userID, orderID, discount)
+			if err != nil {
+				log.Printf("Checkout insert promo redemption error: %v", err)
+				return fiber.ErrInternalServerError
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE promos SET used_count = used_count + 1, updated_at = NOW() WHERE id = $1`,
+				promo.promoID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		// Produk / varian yang sudah dibeli dikeluarkan dari cart buyer
+		productIDs := make([]int, 0, len(lines))
+		variantIDs := make([]int, 0, len(lines))
+		for _, line := range lines {
+			variantID := 0
+			if line.variantID != nil {
+				variantID = *line.variantID
+			}
+			productIDs = append(productIDs, line.productID)
+			variantIDs = append(variantIDs, variantID)
+		}
+		_, err = tx.Exec(ctx, `
+			DELETE FROM cart_items ci USING carts ca
+			WHERE ci.cart_id = ca.id AND ca.user_id = $1
+			AND (ci.product_id, COALESCE(ci.variant_id, 0)) IN (SELECT * FROM unnest($2::int[], $3::int[]))`,
+			userID, productIDs, variantIDs)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("Checkout commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message":         "Order created successfully",
+			"uuid":            orderUUID,
+			"subtotal_price":  orderTotal,
+			"discount_amount": discount,
+			"total_price":     orderTotal - discount,
+		})
+	}
+}
+
+// ListMyOrdersHandler - GET /orders - list order milik buyer
+func ListMyOrdersHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "10"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 10
+		}
+		offset := (page - 1) * limit
+
+		status := c.Query("status", "")
+
+		baseQuery := `FROM orders o WHERE o.buyer_user_id = $1`
+		args := []interface{}{userID}
+		argCount := 1
+
+		if status != "" {
+			argCount++
+			baseQuery += ` AND o.status = $` + strconv.Itoa(argCount)
+			args = append(args, status)
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT o.id, o.uuid, o.subtotal_price, o.discount_amount, o.total_price, o.status, o.created_at, o.updated_at ` +
+			baseQuery + ` ORDER BY o.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		orders := []OrderResponse{}
+		orderIDs := []int{}
+		for rows.Next() {
+			var o OrderResponse
+			var orderID int
+			if err := rows.Scan(&orderID, &o.UUID, &o.SubtotalPrice, &o.DiscountAmount, &o.TotalPrice, &o.Status, &o.CreatedAt, &o.UpdatedAt); err != nil {
+				continue
+			}
+			orders = append(orders, o)
+			orderIDs = append(orderIDs, orderID)
+		}
+		rows.Close()
+
+		items, err := getOrderItems(ctx, db, orderIDs)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		for i, orderID := range orderIDs {
+			orders[i].Items = items[orderID]
+			if orders[i].Items == nil {
+				orders[i].Items = []OrderItemResponse{}
+			}
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       orders,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// GetMyOrderHandler - GET /orders/:uuid - detail order milik buyer
+func GetMyOrderHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		orderUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		var o OrderResponse
+		var orderID int
+		err := db.QueryRow(ctx, `
+			SELECT id, uuid, subtotal_price, discount_amount, total_price, status, created_at, updated_at
+			FROM orders WHERE uuid = $1 AND buyer_user_id = $2`,
+			orderUUID, userID).Scan(&orderID, &o.UUID, &o.SubtotalPrice, &o.DiscountAmount, &o.TotalPrice, &o.Status, &o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		items, err := getOrderItems(ctx, db, []int{orderID})
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		o.Items = items[orderID]
+		if o.Items == nil {
+			o.Items = []OrderItemResponse{}
+		}
+
+		return c.JSON(o)
+	}
+}
+
+// CompleteOrderHandler - POST /orders/:uuid/complete - buyer konfirmasi order diterima,
+// saldo seller dikreditkan per transaksi (hanya order yang sudah dibayar)
+func CompleteOrderHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		orderUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var orderID int
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, status FROM orders WHERE uuid = $1 AND buyer_user_id = $2
+			FOR UPDATE`,
+			orderUUID, userID).Scan(&orderID, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "paid" {
+			return fiber.NewError(fiber.StatusConflict, "Order cannot be completed in status "+status)
+		}
+
+		rows, err := tx.Query(ctx, `
+			UPDATE transactions SET status = 'completed', updated_at = NOW()
+			WHERE order_id = $1 AND status = 'paid'
+			RETURNING id, seller_user_id, total_price`, orderID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		type sellerCredit struct {
+			transactionID int
+			sellerID      int
+			amount        int64
+		}
+		credits := []sellerCredit{}
+		for rows.Next() {
+			var sc sellerCredit
+			if err := rows.Scan(&sc.transactionID, &sc.sellerID, &sc.amount); err != nil {
+				rows.Close()
+				return fiber.ErrInternalServerError
+			}
+			credits = append(credits, sc)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		for _, sc := range credits {
+			err := creditBalance(ctx, tx, sc.sellerID, sc.amount, &sc.transactionID,
+				"Penjualan order "+orderUUID)
+			if err != nil {
+				log.Printf("CompleteOrder credit balance error: %v", err)
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		// Jumlah terjual untuk sort best-selling katalog
+		_, err = tx.Exec(ctx, `
+			UPDATE products p SET sold_count = p.sold_count + t.qty
+			FROM (
+				SELECT product_id, SUM(qty) AS qty FROM transactions
+				WHERE order_id = $1 AND status = 'completed'
+				GROUP BY product_id
+			) t
+			WHERE p.id = t.product_id`, orderID)
+		if err != nil {
+			log.Printf("CompleteOrder sold count error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE orders SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, orderID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("CompleteOrder commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		// Notifikasi seller (non-blocking, saldo sudah tercatat)
+		for _, sc := range credits {
+			notifyUser(sc.sellerID, "Dana masuk",
+				fmt.Sprintf("Saldo Rp%d dari order %s telah masuk ke wallet Anda", sc.amount, orderUUID), "success")
+		}
+
+		return c.JSON(fiber.Map{"message": "Order completed successfully"})
+	}
+}
+
+// CancelOrderHandler - POST /orders/:uuid/cancel - buyer batalkan order yang masih pending,
+// stock dan kuota promo dikembalikan
+func CancelOrderHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		orderUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var orderID int
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, status FROM orders WHERE uuid = $1 AND buyer_user_id = $2
+			FOR UPDATE`,
+			orderUUID, userID).Scan(&orderID, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "pending" {
+			return fiber.NewError(fiber.StatusConflict, "Order cannot be cancelled in status "+status)
+		}
+
+		if err := cancelOrder(ctx, tx, orderID, "buyer"); err != nil {
+			log.Printf("CancelOrder error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("CancelOrder commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "Order cancelled successfully"})
+	}
+}
+
+// ============================================
+// Admin Order Handlers (Dashboard)
+// ============================================
+
+// ConfirmOrderPaymentHandler - POST /orders/:uuid/confirm-payment - finance konfirmasi pembayaran
+// order pending (mis. dari mutasi bank / callback payment gateway), order menjadi paid
+func ConfirmOrderPaymentHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("userID").(int)
+		orderUUID := c.Params("uuid")
+
+		type Input struct {
+			Reference string `json:"reference"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Reference == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Payment reference is required")
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var orderID, buyerID int
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, buyer_user_id, status FROM orders WHERE uuid = $1
+			FOR UPDATE`,
+			orderUUID).Scan(&orderID, &buyerID, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "pending" {
+			return fiber.NewError(fiber.StatusConflict, "Order payment cannot be confirmed in status "+status)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE transactions SET status = 'paid', updated_at = NOW()
+			WHERE order_id = $1 AND status = 'pending'`, orderID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE orders SET status = 'paid', paid_at = NOW(), paid_by = $1, payment_reference = $2, updated_at = NOW()
+			WHERE id = $3`, adminID, input.Reference, orderID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("ConfirmOrderPayment commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(buyerID, "Pembayaran diterima",
+			fmt.Sprintf("Pembayaran order %s telah dikonfirmasi", orderUUID), "success")
+
+		return c.JSON(fiber.Map{"message": "Order payment confirmed successfully"})
+	}
+}
+
+// ============================================
+// Seller Order Handlers (App)
+// ============================================
+
+type ShopOrderItemResponse struct {
+	OrderItemResponse
+	OrderUUID string    `json:"order_uuid"`
+	BuyerName string    `json:"buyer_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListShopOrdersHandler - GET /my/orders - baris order berisi produk toko (owner / staff order.view)
+func ListShopOrdersHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		status := c.Query("status", "")
+
+		baseQuery := `FROM transactions t
+			JOIN orders o ON t.order_id = o.id
+			JOIN products p ON t.product_id = p.id
+			LEFT JOIN product_variants v ON t.variant_id = v.id
+			LEFT JOIN users u ON t.buyer_user_id = u.id
+			WHERE p.shop_id = $1`
+		args := []interface{}{shopID}
+		argCount := 1
+
+		if status != "" {
+			argCount++
+			baseQuery += ` AND t.status = $` + strconv.Itoa(argCount)
+			args = append(args, status)
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT t.uuid, p.uuid, p.title, COALESCE(p.slug, ''),
+			COALESCE(to_json(p.images), '[]'::json)::text,
+			v.uuid::TEXT, v.sku, v.options::TEXT,
+			t.qty, t.unit_price, t.total_price, t.status,
+			o.uuid, COALESCE(u.full_name, ''), t.created_at ` +
+			baseQuery + ` ORDER BY t.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			log.Printf("ListShopOrders error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		items := []ShopOrderItemResponse{}
+		for rows.Next() {
+			var item ShopOrderItemResponse
+			var imagesJSON string
+			var variantUUID, variantSKU, variantOptions *string
+			err := rows.Scan(&item.UUID, &item.Product.UUID, &item.Product.Title, &item.Product.Slug,
+				&imagesJSON, &variantUUID, &variantSKU, &variantOptions, &item.Qty, &item.UnitPrice, &item.TotalPrice, &item.Status,
+				&item.OrderUUID, &item.BuyerName, &item.CreatedAt)
+			if err != nil {
+				continue
+			}
+			item.Variant = variantRef(variantUUID, variantSKU, variantOptions)
+
+			var images []string
+			if json.Unmarshal([]byte(imagesJSON), &images) == nil && len(images) > 0 {
+				item.Product.Image = &images[0]
+			}
+
+			items = append(items, item)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       items,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
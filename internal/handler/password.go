@@ -3,7 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
-	"net/smtp"
+	"log"
 	"os"
 	"time"
 
@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 
+	"shopedia-api/internal/queue"
 	utils "shopedia-api/internal/util"
 )
 
@@ -30,9 +31,10 @@ func ForgotPasswordHandler(db *pgxpool.Pool) fiber.Handler {
 		// Cek apakah email ada
 		var userID int
 		var isActive bool
+		var fullName string
 		err := db.QueryRow(ctx,
-			`SELECT id, is_active FROM users WHERE email = $1`,
-			input.Email).Scan(&userID, &isActive)
+			`SELECT id, is_active, COALESCE(full_name, '') FROM users WHERE email = $1`,
+			input.Email).Scan(&userID, &isActive, &fullName)
 		if err != nil {
 			// Jangan reveal apakah email ada atau tidak (security)
 			return c.JSON(fiber.Map{
@@ -60,10 +62,16 @@ func ForgotPasswordHandler(db *pgxpool.Pool) fiber.Handler {
 			})
 		}
 
-		// Buat token baru
+		// Buat token baru + outbox email dalam satu transaksi
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
 		expiresAt := time.Now().Add(15 * time.Minute)
 		var token string
-		err = db.QueryRow(ctx,
+		err = tx.QueryRow(ctx,
 			`INSERT INTO password_reset_tokens (user_id, expires_at)
 			 VALUES ($1, $2) RETURNING token`,
 			userID, expiresAt).Scan(&token)
@@ -72,7 +80,19 @@ func ForgotPasswordHandler(db *pgxpool.Pool) fiber.Handler {
 		}
 
 		// Kirim email
-		go sendPasswordResetEmail(input.Email, token)
+		resetURL := fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), token)
+		task, err := queue.NewSendPasswordResetTask(input.Email, fullName, resetURL)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if err := queue.AddToOutbox(ctx, tx, task, "critical"); err != nil {
+			log.Printf("Forgot password outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
 
 		return c.JSON(fiber.Map{
 			"message":    "If the email exists, a reset link will be sent",
@@ -116,9 +136,10 @@ func ResetPasswordHandler(db *pgxpool.Pool) fiber.Handler {
 		}
 
 		// Update password
-		_, err = db.Exec(ctx,
-			`UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`,
-			string(hash), userID)
+		var email string
+		err = db.QueryRow(ctx,
+			`UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2 RETURNING email`,
+			string(hash), userID).Scan(&email)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
@@ -131,8 +152,9 @@ func ResetPasswordHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.ErrInternalServerError
 		}
 
-		// Clear active session (force re-login)
+		// Clear active session (force re-login) dan buka lockout akun
 		_ = utils.ClearActiveSession(ctx, db, userID, "")
+		utils.ResetLoginFailures(ctx, db, userID, email)
 
 		return c.JSON(fiber.Map{
 			"message": "Password reset successfully, please login with new password",
@@ -162,7 +184,7 @@ func ChangePasswordHandler(db *pgxpool.Pool) fiber.Handler {
 		// Ambil password hash saat ini
 		var currentHash string
 		err := db.QueryRow(ctx,
-			`SELECT password_hash FROM users WHERE id = $1`,
+			`SELECT COALESCE(password_hash, '') FROM users WHERE id = $1`,
 			userID).Scan(&currentHash)
 		if err != nil {
 			return fiber.ErrInternalServerError
@@ -192,30 +214,3 @@ func ChangePasswordHandler(db *pgxpool.Pool) fiber.Handler {
 		})
 	}
 }
-
-// Utility function untuk kirim email reset password
-func sendPasswordResetEmail(email, token string) {
-	resetURL := fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), token)
-
-	auth := smtp.PlainAuth("", os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"), os.Getenv("SMTP_HOST"))
-	to := []string{email}
-	msg := []byte(fmt.Sprintf(
-		"Subject: Reset Your Password\r\n"+
-			"Content-Type: text/html; charset=UTF-8\r\n"+
-			"\r\n"+
-			"<h2>Reset Password</h2>"+
-			"<p>Click the link below to reset your password:</p>"+
-			"<p><a href=\"%s\">Reset Password</a></p>"+
-			"<p>Or copy this link: %s</p>"+
-			"<p>This link will expire in 15 minutes.</p>"+
-			"<p>If you didn't request this, please ignore this email.</p>",
-		resetURL, resetURL))
-
-	smtp.SendMail(
-		fmt.Sprintf("%s:%s", os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT")),
-		auth,
-		"noreply@myapp.com",
-		to,
-		msg,
-	)
-}
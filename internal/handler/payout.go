@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Payout Response Types
+// ============================================
+
+type PayoutUser struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type PayoutResponse struct {
+	UUID              string      `json:"uuid"`
+	Amount            int64       `json:"amount"`
+	BankName          string      `json:"bank_name"`
+	BankAccountNumber string      `json:"bank_account_number"`
+	BankAccountName   string      `json:"bank_account_name"`
+	Status            string      `json:"status"` // pending, approved, rejected
+	RejectReason      *string     `json:"reject_reason"`
+	User              *PayoutUser `json:"user,omitempty"` // hanya di dashboard
+	ReviewedAt        *time.Time  `json:"reviewed_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// listPayouts - query paginated payouts, sellerID 0 = semua seller (dashboard)
+func listPayouts(c *fiber.Ctx, db *pgxpool.Pool, sellerID int) error {
+	ctx := context.Background()
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	status := c.Query("status", "")
+
+	baseQuery := `FROM payouts po JOIN users u ON po.user_id = u.id WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	if sellerID != 0 {
+		argCount++
+		baseQuery += ` AND po.user_id = $` + strconv.Itoa(argCount)
+		args = append(args, sellerID)
+	}
+
+	if status != "" {
+		argCount++
+		baseQuery += ` AND po.status = $` + strconv.Itoa(argCount)
+		args = append(args, status)
+	}
+
+	var totalItems int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	argCount++
+	limitArg := argCount
+	argCount++
+	offsetArg := argCount
+	args = append(args, limit, offset)
+
+	dataQuery := `SELECT po.uuid, po.amount, po.bank_name, po.bank_account_number, po.bank_account_name,
+		po.status, po.reject_reason, po.reviewed_at, po.created_at, po.updated_at,
+		u.uuid, u.email, COALESCE(u.full_name, '') ` +
+		baseQuery + ` ORDER BY po.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+	rows, err := db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer rows.Close()
+
+	payouts := []PayoutResponse{}
+	for rows.Next() {
+		var p PayoutResponse
+		var u PayoutUser
+		err := rows.Scan(&p.UUID, &p.Amount, &p.BankName, &p.BankAccountNumber, &p.BankAccountName,
+			&p.Status, &p.RejectReason, &p.ReviewedAt, &p.CreatedAt, &p.UpdatedAt,
+			&u.UUID, &u.Email, &u.Name)
+		if err != nil {
+			continue
+		}
+		if sellerID == 0 {
+			p.User = &u
+		}
+		payouts = append(payouts, p)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	return c.JSON(PaginatedResponse{
+		Data:       payouts,
+		Page:       page,
+		Limit:      limit,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+	})
+}
+
+// ============================================
+// Seller Payout Handlers (App)
+// ============================================
+
+// RequestPayoutHandler - POST /my/payouts - ajukan penarikan saldo, dana ditahan sampai diproses finance
+func RequestPayoutHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Amount            int64  `json:"amount"`
+			BankName          string `json:"bank_name"`
+			BankAccountNumber string `json:"bank_account_number"`
+			BankAccountName   string `json:"bank_account_name"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Amount <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Amount must be greater than 0")
+		}
+
+		ctx := context.Background()
+
+		// Rekening kosong → pakai rekening dari pengajuan seller yang disetujui
+		if input.BankName == "" && input.BankAccountNumber == "" && input.BankAccountName == "" {
+			_ = db.QueryRow(ctx, `
+				SELECT bank_name, bank_account_number, bank_account_name FROM seller_applications
+				WHERE user_id = $1 AND status = 'approved'
+				ORDER BY reviewed_at DESC LIMIT 1`,
+				userID).Scan(&input.BankName, &input.BankAccountNumber, &input.BankAccountName)
+		}
+		if input.BankName == "" || input.BankAccountNumber == "" || input.BankAccountName == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Bank name, account number and account name are required")
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		// Lock baris balances agar request payout paralel tidak melebihi saldo
+		var balance int64
+		err = tx.QueryRow(ctx, `SELECT amount FROM balances WHERE user_id = $1 FOR UPDATE`,
+			userID).Scan(&balance)
+		if err != nil {
+			return fiber.NewError(fiber.StatusConflict, "Insufficient balance")
+		}
+
+		pending, err := getPendingHold(ctx, tx, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if input.Amount > balance-pending {
+			return fiber.NewError(fiber.StatusConflict, "Insufficient balance")
+		}
+
+		var balanceLogID int
+		err = tx.QueryRow(ctx, `
+			INSERT INTO balance_logs (user_id, amount, type, status, description)
+			VALUES ($1, $2, 'out', 'pending', 'Penarikan saldo')
+			RETURNING id`,
+			userID, input.Amount).Scan(&balanceLogID)
+		if err != nil {
+			log.Printf("RequestPayout insert balance log error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		var payoutUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO payouts (user_id, balance_log_id, amount, bank_name, bank_account_number, bank_account_name)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING uuid`,
+			userID, balanceLogID, input.Amount, input.BankName, input.BankAccountNumber, input.BankAccountName).Scan(&payoutUUID)
+		if err != nil {
+			log.Printf("RequestPayout insert payout error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("RequestPayout commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(userID, "Penarikan diajukan",
+			fmt.Sprintf("Permintaan penarikan Rp%d sedang diproses", input.Amount), "info")
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Payout requested successfully",
+			"uuid":    payoutUUID,
+		})
+	}
+}
+
+// ListMyPayoutsHandler - GET /my/payouts - riwayat penarikan seller
+func ListMyPayoutsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listPayouts(c, db, c.Locals("shopOwnerID").(int))
+	}
+}
+
+// ============================================
+// Admin Payout Handlers (Dashboard)
+// ============================================
+
+// ListPayoutsHandler - GET /payouts - list semua request payout
+func ListPayoutsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listPayouts(c, db, 0)
+	}
+}
+
+// GetPayoutHandler - GET /payouts/:uuid - detail request payout
+func GetPayoutHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		payoutUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		var p PayoutResponse
+		var u PayoutUser
+		err := db.QueryRow(ctx, `
+			SELECT po.uuid, po.amount, po.bank_name, po.bank_account_number, po.bank_account_name,
+				po.status, po.reject_reason, po.reviewed_at, po.created_at, po.updated_at,
+				u.uuid, u.email, COALESCE(u.full_name, '')
+			FROM payouts po JOIN users u ON po.user_id = u.id
+			WHERE po.uuid = $1`, payoutUUID).Scan(&p.UUID, &p.Amount, &p.BankName, &p.BankAccountNumber, &p.BankAccountName,
+			&p.Status, &p.RejectReason, &p.ReviewedAt, &p.CreatedAt, &p.UpdatedAt,
+			&u.UUID, &u.Email, &u.Name)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+		p.User = &u
+
+		return c.JSON(p)
+	}
+}
+
+// ApprovePayoutHandler - POST /payouts/:uuid/approve - dana dianggap sudah ditransfer, saldo dikurangi
+func ApprovePayoutHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewerID := c.Locals("userID").(int)
+		payoutUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var payoutID, sellerID int
+		var balanceLogID *int
+		var amount int64
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, user_id, balance_log_id, amount, status FROM payouts WHERE uuid = $1
+			FOR UPDATE`,
+			payoutUUID).Scan(&payoutID, &sellerID, &balanceLogID, &amount, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "pending" {
+			return fiber.NewError(fiber.StatusConflict, "Payout already "+status)
+		}
+
+		var balance int64
+		err = tx.QueryRow(ctx, `SELECT amount FROM balances WHERE user_id = $1 FOR UPDATE`,
+			sellerID).Scan(&balance)
+		if err != nil || balance < amount {
+			return fiber.NewError(fiber.StatusConflict, "Insufficient balance")
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE balances SET amount = amount - $1, updated_at = NOW() WHERE user_id = $2`,
+			amount, sellerID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE balance_logs SET status = 'completed' WHERE id = $1`, balanceLogID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE payouts SET status = 'approved', reviewed_by = $1, reviewed_at = NOW(), updated_at = NOW()
+			WHERE id = $2`, reviewerID, payoutID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("ApprovePayout commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(sellerID, "Penarikan disetujui",
+			fmt.Sprintf("Penarikan Rp%d telah disetujui dan ditransfer ke rekening Anda", amount), "success")
+
+		return c.JSON(fiber.Map{"message": "Payout approved successfully"})
+	}
+}
+
+// RejectPayoutHandler - POST /payouts/:uuid/reject - tolak payout, hold dana dilepas
+func RejectPayoutHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewerID := c.Locals("userID").(int)
+		payoutUUID := c.Params("uuid")
+
+		type Input struct {
+			Reason string `json:"reason"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Reason == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Reason is required")
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var payoutID, sellerID int
+		var balanceLogID *int
+		var amount int64
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, user_id, balance_log_id, amount, status FROM payouts WHERE uuid = $1
+			FOR UPDATE`,
+			payoutUUID).Scan(&payoutID, &sellerID, &balanceLogID, &amount, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "pending" {
+			return fiber.NewError(fiber.StatusConflict, "Payout already "+status)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE balance_logs SET status = 'cancelled' WHERE id = $1`, balanceLogID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE payouts SET status = 'rejected', reject_reason = $1, reviewed_by = $2, reviewed_at = NOW(), updated_at = NOW()
+			WHERE id = $3`, input.Reason, reviewerID, payoutID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("RejectPayout commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(sellerID, "Penarikan ditolak",
+			fmt.Sprintf("Penarikan Rp%d ditolak: %s", amount, input.Reason), "warning")
+
+		return c.JSON(fiber.Map{"message": "Payout rejected successfully"})
+	}
+}
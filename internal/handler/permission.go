@@ -7,6 +7,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	utils "shopedia-api/internal/util"
 )
 
 // ============================================
@@ -280,6 +282,9 @@ func UpdatePermissionHandler(db *pgxpool.Pool) fiber.Handler {
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
+
+			// Nama permission ikut di auth context user yang memilikinya
+			utils.InvalidateAllAuthContexts()
 		}
 
 		if input.Description != nil {
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,20 +28,41 @@ type ProductOwner struct {
 	Name string `json:"name"`
 }
 
+type ProductShop struct {
+	UUID     string  `json:"uuid"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	LogoURL  *string `json:"logo_url"`
+	Location *string `json:"location"`
+}
+
+type ProductRating struct {
+	Average *float64 `json:"average"`
+	Count   int      `json:"count"`
+}
+
 type ProductResponse struct {
-	UUID        string           `json:"uuid"`
-	Title       string           `json:"title"`
-	Slug        string           `json:"slug"`
-	Description *string          `json:"description"`
-	Images      []string         `json:"images"`
-	Price       int64            `json:"price"`
-	Stock       int              `json:"stock"`
-	Category    *ProductCategory `json:"category,omitempty"`
-	Owner       ProductOwner     `json:"owner"`
-	Status      string           `json:"status"`
-	IsActive    bool             `json:"is_active"`
-	CreatedAt   time.Time        `json:"created_at"`
-	UpdatedAt   time.Time        `json:"updated_at"`
+	UUID        string             `json:"uuid"`
+	Title       string             `json:"title"`
+	Slug        string             `json:"slug"`
+	Description *string            `json:"description"`
+	Images      []string           `json:"images"`
+	Price       int64              `json:"price"`
+	Stock       int                `json:"stock"`
+	HasVariants bool               `json:"has_variants"`
+	PriceRange  *ProductPriceRange `json:"price_range,omitempty"`
+	SoldCount   int                `json:"sold_count"`
+	Rating      ProductRating      `json:"rating"`
+	Options     []ProductOption    `json:"options,omitempty"`
+	Variants    []ProductVariant   `json:"variants,omitempty"`
+	Category    *ProductCategory   `json:"category,omitempty"`
+	Owner       ProductOwner       `json:"owner"`
+	Shop        *ProductShop       `json:"shop,omitempty"`
+	Highlight   *ProductHighlight  `json:"highlight,omitempty"`
+	Status      string             `json:"status"`
+	IsActive    bool               `json:"is_active"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
 }
 
 type ProductDetailResponse struct {
@@ -49,109 +72,271 @@ type ProductDetailResponse struct {
 	BlockedBy   *string    `json:"blocked_by,omitempty"`
 }
 
+// ============================================
+// Helper Functions
+// ============================================
+
+const productColumns = `p.uuid, p.title, p.slug, p.description,
+	COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock, p.has_variants, p.price_max,
+	p.sold_count, p.rating_avg::FLOAT8, p.rating_count,
+	pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
+	s.uuid::TEXT, s.name, s.slug, s.logo_url, s.location,
+	p.status, p.is_active, p.created_at, p.updated_at`
+
+const productJoins = `FROM products p
+	LEFT JOIN product_categories pc ON p.category_id = pc.id
+	LEFT JOIN users u ON p.owner_user_id = u.id
+	LEFT JOIN shops s ON p.shop_id = s.id`
+
+// scanProduct - scan baris hasil query productColumns ke p, kolom tambahan sesudahnya ke extra
+func scanProduct(row pgx.Row, p *ProductResponse, extra ...interface{}) error {
+	var imagesJSON string
+	var catUUID, catName, catIcon *string
+	var ownerUUID, ownerName string
+	var shopUUID, shopName, shopSlug *string
+	var shop ProductShop
+	var priceMax *int64
+
+	dest := []interface{}{&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock, &p.HasVariants, &priceMax,
+		&p.SoldCount, &p.Rating.Average, &p.Rating.Count,
+		&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
+		&shopUUID, &shopName, &shopSlug, &shop.LogoURL, &shop.Location,
+		&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
+		p.Images = []string{}
+	}
+
+	if catUUID != nil {
+		p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
+	}
+	p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
+
+	if p.HasVariants && priceMax != nil {
+		p.PriceRange = &ProductPriceRange{Min: p.Price, Max: *priceMax}
+	}
+
+	if shopUUID != nil {
+		shop.UUID, shop.Name, shop.Slug = *shopUUID, *shopName, *shopSlug
+		p.Shop = &shop
+	}
+
+	return nil
+}
+
+// scanProductHit - scanProduct untuk list yang bisa berisi hasil pencarian
+// (kolom highlight productSearch sesudah productColumns)
+func scanProductHit(row pgx.Row, p *ProductResponse, searching bool) error {
+	if !searching {
+		return scanProduct(row, p)
+	}
+	var hl ProductHighlight
+	if err := scanProduct(row, p, &hl.Title, &hl.Snippet); err != nil {
+		return err
+	}
+	p.Highlight = &hl
+	return nil
+}
+
 // ============================================
 // Public Product Handlers (for End Users)
 // ============================================
 
-// ListPublicProductsHandler - GET /products - list active products (public)
-func ListPublicProductsHandler(db *pgxpool.Pool) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		ctx := context.Background()
+// listPublicProducts - query paginated produk aktif dengan filter, sort dan facet, shopID 0 = semua toko
+func listPublicProducts(c *fiber.Ctx, db *pgxpool.Pool, shopID int) error {
+	ctx := context.Background()
 
-		page, _ := strconv.Atoi(c.Query("page", "1"))
-		limit, _ := strconv.Atoi(c.Query("limit", "20"))
-		if page < 1 {
-			page = 1
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	search := strings.TrimSpace(c.Query("search", ""))
+	categoryUUID := c.Query("category", "")
+	shopRef := c.Query("shop", "")
+	location := strings.TrimSpace(c.Query("location", ""))
+	inStock := c.Query("in_stock", "") == "true" || c.Query("in_stock", "") == "1"
+
+	var priceMin, priceMax int64 = -1, -1
+	if v := c.Query("price_min", ""); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid price_min")
 		}
-		if limit < 1 || limit > 100 {
-			limit = 20
+		priceMin = n
+	}
+	if v := c.Query("price_max", ""); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid price_max")
 		}
-		offset := (page - 1) * limit
-
-		search := c.Query("search", "")
-		categoryUUID := c.Query("category", "")
+		priceMax = n
+	}
+	if priceMin >= 0 && priceMax >= 0 && priceMin > priceMax {
+		return fiber.NewError(fiber.StatusBadRequest, "price_min cannot be greater than price_max")
+	}
 
-		baseQuery := `FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
-			WHERE p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE`
-		args := []interface{}{}
-		argCount := 0
+	var rating float64
+	if v := c.Query("rating", ""); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 1 || n > 5 {
+			return fiber.NewError(fiber.StatusBadRequest, "Rating must be between 1 and 5")
+		}
+		rating = n
+	}
 
+	sort := c.Query("sort", "")
+	if _, ok := productSorts[sort]; sort != "" && !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid sort")
+	}
+	if sort == "" || (sort == "relevance" && search == "") {
+		sort = "newest"
 		if search != "" {
-			argCount++
-			baseQuery += ` AND (p.title ILIKE $` + strconv.Itoa(argCount) + ` OR p.description ILIKE $` + strconv.Itoa(argCount) + `)`
-			args = append(args, "%"+search+"%")
+			sort = "relevance"
 		}
+	}
 
-		if categoryUUID != "" {
-			argCount++
-			baseQuery += ` AND pc.uuid = $` + strconv.Itoa(argCount)
-			args = append(args, categoryUUID)
-		}
+	baseQuery := productJoins + `
+		WHERE p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE
+		AND (s.id IS NULL OR s.status = 'active')`
+	args := []interface{}{}
+	argCount := 0
 
-		var totalItems int
-		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
-		if err != nil {
-			log.Printf("ListPublicProducts count error: %v", err)
-			return fiber.ErrInternalServerError
-		}
+	if shopID != 0 {
+		argCount++
+		baseQuery += ` AND p.shop_id = $` + strconv.Itoa(argCount)
+		args = append(args, shopID)
+	}
 
+	var ps productSearch
+	if search != "" {
 		argCount++
-		limitArg := argCount
+		ps = newProductSearch(argCount)
+		baseQuery += ps.where
+		args = append(args, search)
+	}
+
+	if shopRef != "" {
 		argCount++
-		offsetArg := argCount
-		args = append(args, limit, offset)
+		baseQuery += ` AND (s.uuid::TEXT = $` + strconv.Itoa(argCount) + ` OR s.slug = $` + strconv.Itoa(argCount) + `)`
+		args = append(args, shopRef)
+	}
 
-		dataQuery := `SELECT p.uuid, p.title, p.slug, p.description,
-			COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-			pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-			p.status, p.is_active, p.created_at, p.updated_at ` +
-			baseQuery + ` ORDER BY p.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+	if location != "" {
+		argCount++
+		baseQuery += ` AND s.location ILIKE $` + strconv.Itoa(argCount)
+		args = append(args, "%"+location+"%")
+	}
 
-		rows, err := db.Query(ctx, dataQuery, args...)
-		if err != nil {
-			log.Printf("ListPublicProducts query error: %v", err)
-			return fiber.ErrInternalServerError
+	if inStock {
+		baseQuery += ` AND p.stock > 0`
+	}
+
+	if rating > 0 {
+		argCount++
+		baseQuery += ` AND p.rating_avg >= $` + strconv.Itoa(argCount)
+		args = append(args, rating)
+	}
+
+	// Filter kategori & harga dipisah agar facet bisa menghitung tanpa filternya sendiri
+	categoryMatch, priceMatch := `TRUE`, `TRUE`
+	if categoryUUID != "" {
+		argCount++
+		categoryMatch = `pc.uuid::TEXT = $` + strconv.Itoa(argCount)
+		args = append(args, categoryUUID)
+	}
+	// Produk bervarian cocok jika price_range-nya beririsan dengan rentang filter
+	if priceMin >= 0 {
+		argCount++
+		priceMatch = `COALESCE(p.price_max, p.price) >= $` + strconv.Itoa(argCount)
+		args = append(args, priceMin)
+	}
+	if priceMax >= 0 {
+		argCount++
+		if priceMatch == `TRUE` {
+			priceMatch = ``
+		} else {
+			priceMatch += ` AND `
 		}
-		defer rows.Close()
+		priceMatch += `p.price <= $` + strconv.Itoa(argCount)
+		args = append(args, priceMax)
+	}
 
-		products := []ProductResponse{}
-		for rows.Next() {
-			var p ProductResponse
-			var imagesJSON string
-			var catUUID, catName, catIcon *string
-			var ownerUUID, ownerName string
+	filteredQuery := baseQuery + ` AND (` + categoryMatch + `) AND (` + priceMatch + `)`
 
-			err := rows.Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-				&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-				&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
-			if err != nil {
-				log.Printf("ListPublicProducts scan error: %v", err)
-				continue
-			}
+	var totalItems int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) `+filteredQuery, args...).Scan(&totalItems)
+	if err != nil {
+		log.Printf("ListPublicProducts count error: %v", err)
+		return fiber.ErrInternalServerError
+	}
 
-			if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-				p.Images = []string{}
-			}
+	facets, err := loadProductFacets(ctx, db, baseQuery, categoryMatch, priceMatch, args)
+	if err != nil {
+		log.Printf("ListPublicProducts facets error: %v", err)
+		return fiber.ErrInternalServerError
+	}
 
-			if catUUID != nil {
-				p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
-			}
-			p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
+	argCount++
+	limitArg := argCount
+	argCount++
+	offsetArg := argCount
+	args = append(args, limit, offset)
 
-			products = append(products, p)
+	columns, orderBy := productColumns, productSorts[sort]
+	if search != "" {
+		columns += `, ` + ps.highlight
+	}
+	if sort == "relevance" {
+		orderBy = ps.rank + ` DESC, p.created_at DESC`
+	}
+
+	dataQuery := `SELECT ` + columns + ` ` +
+		filteredQuery + ` ORDER BY ` + orderBy + ` LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+	rows, err := db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		log.Printf("ListPublicProducts query error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+	defer rows.Close()
+
+	products := []ProductResponse{}
+	for rows.Next() {
+		var p ProductResponse
+		if err := scanProductHit(rows, &p, search != ""); err != nil {
+			log.Printf("ListPublicProducts scan error: %v", err)
+			continue
 		}
+		products = append(products, p)
+	}
 
-		totalPages := (totalItems + limit - 1) / limit
+	totalPages := (totalItems + limit - 1) / limit
 
-		return c.JSON(PaginatedResponse{
+	return c.JSON(ProductListResponse{
+		PaginatedResponse: PaginatedResponse{
 			Data:       products,
 			Page:       page,
 			Limit:      limit,
 			TotalItems: totalItems,
 			TotalPages: totalPages,
-		})
+		},
+		Facets: facets,
+	})
+}
+
+// ListPublicProductsHandler - GET /products - list active products (public)
+func ListPublicProductsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listPublicProducts(c, db, 0)
 	}
 }
 
@@ -162,34 +347,19 @@ func GetPublicProductHandler(db *pgxpool.Pool) fiber.Handler {
 		ctx := context.Background()
 
 		var p ProductResponse
-		var imagesJSON string
-		var catUUID, catName, catIcon *string
-		var ownerUUID, ownerName string
-
-		err := db.QueryRow(ctx, `
-			SELECT p.uuid, p.title, p.slug, p.description,
-				COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-				pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-				p.status, p.is_active, p.created_at, p.updated_at
-			FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
-			WHERE p.uuid = $1 AND p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE`,
-			productUUID).Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-			&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-			&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
-		if err != nil {
+		row := db.QueryRow(ctx, `
+			SELECT `+productColumns+` `+productJoins+`
+			WHERE p.uuid = $1 AND p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE
+			AND (s.id IS NULL OR s.status = 'active')`,
+			productUUID)
+		if err := scanProduct(row, &p); err != nil {
 			return fiber.ErrNotFound
 		}
 
-		if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-			p.Images = []string{}
-		}
-
-		if catUUID != nil {
-			p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
+		if err := loadProductVariants(ctx, db, &p, true); err != nil {
+			log.Printf("GetPublicProduct variants error: %v", err)
+			return fiber.ErrInternalServerError
 		}
-		p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
 
 		return c.JSON(p)
 	}
@@ -199,10 +369,10 @@ func GetPublicProductHandler(db *pgxpool.Pool) fiber.Handler {
 // Seller Product Handlers (for Apps)
 // ============================================
 
-// ListSellerProductsHandler - GET /my/products - list seller's own products
+// ListSellerProductsHandler - GET /my/products - list products of the seller's shop
 func ListSellerProductsHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		shopID := c.Locals("shopID").(int)
 		ctx := context.Background()
 
 		page, _ := strconv.Atoi(c.Query("page", "1"))
@@ -217,11 +387,8 @@ func ListSellerProductsHandler(db *pgxpool.Pool) fiber.Handler {
 
 		status := c.Query("status", "")
 
-		baseQuery := `FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
-			WHERE p.deleted_at IS NULL AND p.owner_user_id = $1`
-		args := []interface{}{userID}
+		baseQuery := productJoins + ` WHERE p.deleted_at IS NULL AND p.shop_id = $1`
+		args := []interface{}{shopID}
 		argCount := 1
 
 		if status != "" {
@@ -242,10 +409,7 @@ func ListSellerProductsHandler(db *pgxpool.Pool) fiber.Handler {
 		offsetArg := argCount
 		args = append(args, limit, offset)
 
-		dataQuery := `SELECT p.uuid, p.title, p.slug, p.description,
-			COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-			pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-			p.status, p.is_active, p.created_at, p.updated_at ` +
+		dataQuery := `SELECT ` + productColumns + ` ` +
 			baseQuery + ` ORDER BY p.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
 
 		rows, err := db.Query(ctx, dataQuery, args...)
@@ -257,26 +421,9 @@ func ListSellerProductsHandler(db *pgxpool.Pool) fiber.Handler {
 		products := []ProductResponse{}
 		for rows.Next() {
 			var p ProductResponse
-			var imagesJSON string
-			var catUUID, catName, catIcon *string
-			var ownerUUID, ownerName string
-
-			err := rows.Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-				&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-				&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
-			if err != nil {
+			if err := scanProduct(rows, &p); err != nil {
 				continue
 			}
-
-			if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-				p.Images = []string{}
-			}
-
-			if catUUID != nil {
-				p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
-			}
-			p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
-
 			products = append(products, p)
 		}
 
@@ -295,42 +442,24 @@ func ListSellerProductsHandler(db *pgxpool.Pool) fiber.Handler {
 // GetSellerProductHandler - GET /my/products/:uuid - get seller's product detail
 func GetSellerProductHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		shopID := c.Locals("shopID").(int)
 		productUUID := c.Params("uuid")
 		ctx := context.Background()
 
 		var p ProductDetailResponse
-		var imagesJSON string
-		var catUUID, catName, catIcon *string
-		var ownerUUID, ownerName string
-
-		err := db.QueryRow(ctx, `
-			SELECT p.uuid, p.title, p.slug, p.description,
-				COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-				pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-				p.status, p.is_active, p.created_at, p.updated_at,
-				p.block_reason, p.blocked_at, COALESCE(bu.full_name, '')
-			FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
+		row := db.QueryRow(ctx, `
+			SELECT `+productColumns+`, p.block_reason, p.blocked_at, COALESCE(bu.full_name, '')
+			`+productJoins+`
 			LEFT JOIN users bu ON p.blocked_by = bu.id
-			WHERE p.uuid = $1 AND p.owner_user_id = $2 AND p.deleted_at IS NULL`,
-			productUUID, userID).Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-			&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-			&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
-			&p.BlockReason, &p.BlockedAt, &p.BlockedBy)
-		if err != nil {
+			WHERE p.uuid = $1 AND p.shop_id = $2 AND p.deleted_at IS NULL`,
+			productUUID, shopID)
+		if err := scanProduct(row, &p.ProductResponse, &p.BlockReason, &p.BlockedAt, &p.BlockedBy); err != nil {
 			return fiber.ErrNotFound
 		}
 
-		if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-			p.Images = []string{}
-		}
-
-		if catUUID != nil {
-			p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
+		if err := loadProductVariants(ctx, db, &p.ProductResponse, false); err != nil {
+			return fiber.ErrInternalServerError
 		}
-		p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
 
 		return c.JSON(p)
 	}
@@ -339,7 +468,8 @@ func GetSellerProductHandler(db *pgxpool.Pool) fiber.Handler {
 // CreateProductHandler - POST /my/products - create new product
 func CreateProductHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		shopID := c.Locals("shopID").(int)
+		ownerID := c.Locals("shopOwnerID").(int)
 
 		type Input struct {
 			Title        string   `json:"title"`
@@ -350,12 +480,19 @@ func CreateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			CategoryUUID *string  `json:"category_uuid"`
 			Slug         *string  `json:"slug"`
 			IsActive     *bool    `json:"is_active"`
+			// Matrix varian (opsional); price & stock produk dihitung dari varian
+			Options  []productOptionInput  `json:"options"`
+			Variants []productVariantInput `json:"variants"`
 		}
 		var input Input
 		if err := c.BodyParser(&input); err != nil {
 			return fiber.ErrBadRequest
 		}
 
+		if err := validateVariantMatrix(input.Options, input.Variants); err != nil {
+			return err
+		}
+
 		if input.Title == "" {
 			return fiber.NewError(fiber.StatusBadRequest, "Title is required")
 		}
@@ -395,17 +532,41 @@ func CreateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			input.Images = []string{}
 		}
 
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		// Produk milik owner toko (saldo penjualan masuk ke owner), juga jika dibuat staff
+		var productID int
 		var productUUID string
-		err := db.QueryRow(ctx, `
-			INSERT INTO products (owner_user_id, category_id, title, slug, description, images, price, stock, is_active, status)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'active')
-			RETURNING uuid`,
-			userID, categoryID, input.Title, slug, input.Description, input.Images, input.Price, input.Stock, isActive).Scan(&productUUID)
+		err = tx.QueryRow(ctx, `
+			INSERT INTO products (owner_user_id, shop_id, category_id, title, slug, description, images, price, stock, is_active, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'active')
+			RETURNING id, uuid`,
+			ownerID, shopID, categoryID, input.Title, slug, input.Description, input.Images, input.Price, input.Stock, isActive).Scan(&productID, &productUUID)
 		if err != nil {
 			log.Printf("CreateProduct error: %v", err)
 			return fiber.ErrInternalServerError
 		}
 
+		if len(input.Variants) > 0 {
+			if err := saveProductVariants(ctx, tx, productID, input.Options, input.Variants); err != nil {
+				return err
+			}
+		}
+
+		if err := enqueueProductIndex(ctx, tx, productID, productUUID, "create"); err != nil {
+			log.Printf("CreateProduct outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("CreateProduct commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
 		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 			"message": "Product created successfully",
 			"uuid":    productUUID,
@@ -417,7 +578,7 @@ func CreateProductHandler(db *pgxpool.Pool) fiber.Handler {
 // UpdateProductHandler - PUT /my/products/:uuid - update product
 func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		shopID := c.Locals("shopID").(int)
 		productUUID := c.Params("uuid")
 
 		type Input struct {
@@ -429,6 +590,9 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			CategoryUUID *string  `json:"category_uuid"`
 			Slug         *string  `json:"slug"`
 			IsActive     *bool    `json:"is_active"`
+			// Matrix varian: jika dikirim, menggantikan seluruh option & varian ([] = hapus varian)
+			Options  []productOptionInput  `json:"options"`
+			Variants []productVariantInput `json:"variants"`
 		}
 		var input Input
 		if err := c.BodyParser(&input); err != nil {
@@ -439,8 +603,9 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 
 		var productID int
 		var currentStatus string
-		err := db.QueryRow(ctx, `SELECT id, status FROM products WHERE uuid = $1 AND owner_user_id = $2 AND deleted_at IS NULL`,
-			productUUID, userID).Scan(&productID, &currentStatus)
+		var hasVariants bool
+		err := db.QueryRow(ctx, `SELECT id, status, has_variants FROM products WHERE uuid = $1 AND shop_id = $2 AND deleted_at IS NULL`,
+			productUUID, shopID).Scan(&productID, &currentStatus, &hasVariants)
 		if err != nil {
 			return fiber.ErrNotFound
 		}
@@ -449,8 +614,28 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.NewError(fiber.StatusForbidden, "Cannot edit blocked product")
 		}
 
+		if input.Options != nil && input.Variants == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Variants are required when options change")
+		}
+		if input.Variants != nil {
+			if err := validateVariantMatrix(input.Options, input.Variants); err != nil {
+				return err
+			}
+			hasVariants = len(input.Variants) > 0
+		}
+		if hasVariants && (input.Price != nil || input.Stock != nil) {
+			return fiber.NewError(fiber.StatusBadRequest, "Price and stock are managed per variant")
+		}
+
+		// Semua perubahan + task index search dalam satu transaksi
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
 		if input.Title != nil {
-			_, err = db.Exec(ctx, `UPDATE products SET title = $1, updated_at = NOW() WHERE id = $2`, *input.Title, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET title = $1, updated_at = NOW() WHERE id = $2`, *input.Title, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
@@ -458,21 +643,21 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 
 		if input.Slug != nil {
 			slug := generateSlug(*input.Slug)
-			_, err = db.Exec(ctx, `UPDATE products SET slug = $1, updated_at = NOW() WHERE id = $2`, slug, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET slug = $1, updated_at = NOW() WHERE id = $2`, slug, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
 		}
 
 		if input.Description != nil {
-			_, err = db.Exec(ctx, `UPDATE products SET description = $1, updated_at = NOW() WHERE id = $2`, *input.Description, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET description = $1, updated_at = NOW() WHERE id = $2`, *input.Description, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
 		}
 
 		if input.Images != nil {
-			_, err = db.Exec(ctx, `UPDATE products SET images = $1, updated_at = NOW() WHERE id = $2`, input.Images, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET images = $1, updated_at = NOW() WHERE id = $2`, input.Images, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
@@ -482,7 +667,7 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			if *input.Price < 0 {
 				return fiber.NewError(fiber.StatusBadRequest, "Price must be positive")
 			}
-			_, err = db.Exec(ctx, `UPDATE products SET price = $1, updated_at = NOW() WHERE id = $2`, *input.Price, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET price = $1, updated_at = NOW() WHERE id = $2`, *input.Price, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
@@ -492,7 +677,7 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			if *input.Stock < 0 {
 				return fiber.NewError(fiber.StatusBadRequest, "Stock must be positive")
 			}
-			_, err = db.Exec(ctx, `UPDATE products SET stock = $1, updated_at = NOW() WHERE id = $2`, *input.Stock, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET stock = $1, updated_at = NOW() WHERE id = $2`, *input.Stock, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
@@ -502,26 +687,43 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 			var categoryID *int
 			if *input.CategoryUUID != "" {
 				var catID int
-				err := db.QueryRow(ctx, `SELECT id FROM product_categories WHERE uuid = $1 AND deleted_at IS NULL AND is_active = TRUE`,
+				err := tx.QueryRow(ctx, `SELECT id FROM product_categories WHERE uuid = $1 AND deleted_at IS NULL AND is_active = TRUE`,
 					*input.CategoryUUID).Scan(&catID)
 				if err != nil {
 					return fiber.NewError(fiber.StatusBadRequest, "Invalid category")
 				}
 				categoryID = &catID
 			}
-			_, err = db.Exec(ctx, `UPDATE products SET category_id = $1, updated_at = NOW() WHERE id = $2`, categoryID, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET category_id = $1, updated_at = NOW() WHERE id = $2`, categoryID, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
 		}
 
 		if input.IsActive != nil {
-			_, err = db.Exec(ctx, `UPDATE products SET is_active = $1, updated_at = NOW() WHERE id = $2`, *input.IsActive, productID)
+			_, err = tx.Exec(ctx, `UPDATE products SET is_active = $1, updated_at = NOW() WHERE id = $2`, *input.IsActive, productID)
 			if err != nil {
 				return fiber.ErrInternalServerError
 			}
 		}
 
+		if input.Variants != nil {
+			if err := saveProductVariants(ctx, tx, productID, input.Options, input.Variants); err != nil {
+				return err
+			}
+		}
+
+		if input.Title != nil || input.Description != nil {
+			if err := enqueueProductIndex(ctx, tx, productID, productUUID, "update"); err != nil {
+				log.Printf("UpdateProduct outbox error: %v", err)
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
 		return c.JSON(fiber.Map{"message": "Product updated successfully"})
 	}
 }
@@ -529,22 +731,37 @@ func UpdateProductHandler(db *pgxpool.Pool) fiber.Handler {
 // DeleteProductHandler - DELETE /my/products/:uuid - soft delete product
 func DeleteProductHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		shopID := c.Locals("shopID").(int)
 		productUUID := c.Params("uuid")
 		ctx := context.Background()
 
 		var productID int
-		err := db.QueryRow(ctx, `SELECT id FROM products WHERE uuid = $1 AND owner_user_id = $2 AND deleted_at IS NULL`,
-			productUUID, userID).Scan(&productID)
+		err := db.QueryRow(ctx, `SELECT id FROM products WHERE uuid = $1 AND shop_id = $2 AND deleted_at IS NULL`,
+			productUUID, shopID).Scan(&productID)
 		if err != nil {
 			return fiber.ErrNotFound
 		}
 
-		_, err = db.Exec(ctx, `UPDATE products SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, productID)
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, `UPDATE products SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, productID)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
 
+		if err := enqueueProductIndex(ctx, tx, productID, productUUID, "delete"); err != nil {
+			log.Printf("DeleteProduct outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
 		return c.JSON(fiber.Map{"message": "Product deleted successfully"})
 	}
 }
@@ -568,22 +785,22 @@ func ListAdminProductsHandler(db *pgxpool.Pool) fiber.Handler {
 		}
 		offset := (page - 1) * limit
 
-		search := c.Query("search", "")
+		search := strings.TrimSpace(c.Query("search", ""))
 		status := c.Query("status", "")
 		categoryUUID := c.Query("category", "")
 		ownerUUID := c.Query("owner", "")
+		shopUUID := c.Query("shop", "")
 
-		baseQuery := `FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
-			WHERE p.deleted_at IS NULL`
+		baseQuery := productJoins + ` WHERE p.deleted_at IS NULL`
 		args := []interface{}{}
 		argCount := 0
 
+		var ps productSearch
 		if search != "" {
 			argCount++
-			baseQuery += ` AND (p.title ILIKE $` + strconv.Itoa(argCount) + ` OR p.description ILIKE $` + strconv.Itoa(argCount) + `)`
-			args = append(args, "%"+search+"%")
+			ps = newProductSearch(argCount)
+			baseQuery += ps.where
+			args = append(args, search)
 		}
 
 		if status != "" {
@@ -604,6 +821,12 @@ func ListAdminProductsHandler(db *pgxpool.Pool) fiber.Handler {
 			args = append(args, ownerUUID)
 		}
 
+		if shopUUID != "" {
+			argCount++
+			baseQuery += ` AND s.uuid::TEXT = $` + strconv.Itoa(argCount)
+			args = append(args, shopUUID)
+		}
+
 		var totalItems int
 		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
 		if err != nil {
@@ -616,14 +839,18 @@ func ListAdminProductsHandler(db *pgxpool.Pool) fiber.Handler {
 		offsetArg := argCount
 		args = append(args, limit, offset)
 
-		dataQuery := `SELECT p.uuid, p.title, p.slug, p.description,
-			COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-			pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-			p.status, p.is_active, p.created_at, p.updated_at ` +
-			baseQuery + ` ORDER BY p.created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+		columns, orderBy := productColumns, `p.created_at DESC`
+		if search != "" {
+			columns += `, ` + ps.highlight
+			orderBy = ps.rank + ` DESC, p.created_at DESC`
+		}
+
+		dataQuery := `SELECT ` + columns + ` ` +
+			baseQuery + ` ORDER BY ` + orderBy + ` LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
 
 		rows, err := db.Query(ctx, dataQuery, args...)
 		if err != nil {
+			log.Printf("ListAdminProducts query error: %v", err)
 			return fiber.ErrInternalServerError
 		}
 		defer rows.Close()
@@ -631,26 +858,9 @@ func ListAdminProductsHandler(db *pgxpool.Pool) fiber.Handler {
 		products := []ProductResponse{}
 		for rows.Next() {
 			var p ProductResponse
-			var imagesJSON string
-			var catUUID, catName, catIcon *string
-			var ownerUUID, ownerName string
-
-			err := rows.Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-				&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-				&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
-			if err != nil {
+			if err := scanProductHit(rows, &p, search != ""); err != nil {
 				continue
 			}
-
-			if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-				p.Images = []string{}
-			}
-
-			if catUUID != nil {
-				p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
-			}
-			p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
-
 			products = append(products, p)
 		}
 
@@ -673,37 +883,19 @@ func GetAdminProductHandler(db *pgxpool.Pool) fiber.Handler {
 		ctx := context.Background()
 
 		var p ProductDetailResponse
-		var imagesJSON string
-		var catUUID, catName, catIcon *string
-		var ownerUUID, ownerName string
-
-		err := db.QueryRow(ctx, `
-			SELECT p.uuid, p.title, p.slug, p.description,
-				COALESCE(to_json(p.images), '[]'::json)::text, p.price, p.stock,
-				pc.uuid, pc.name, pc.icon, u.uuid, COALESCE(u.full_name, ''),
-				p.status, p.is_active, p.created_at, p.updated_at,
-				p.block_reason, p.blocked_at, COALESCE(bu.full_name, '')
-			FROM products p
-			LEFT JOIN product_categories pc ON p.category_id = pc.id
-			LEFT JOIN users u ON p.owner_user_id = u.id
+		row := db.QueryRow(ctx, `
+			SELECT `+productColumns+`, p.block_reason, p.blocked_at, COALESCE(bu.full_name, '')
+			`+productJoins+`
 			LEFT JOIN users bu ON p.blocked_by = bu.id
 			WHERE p.uuid = $1 AND p.deleted_at IS NULL`,
-			productUUID).Scan(&p.UUID, &p.Title, &p.Slug, &p.Description, &imagesJSON, &p.Price, &p.Stock,
-			&catUUID, &catName, &catIcon, &ownerUUID, &ownerName,
-			&p.Status, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
-			&p.BlockReason, &p.BlockedAt, &p.BlockedBy)
-		if err != nil {
+			productUUID)
+		if err := scanProduct(row, &p.ProductResponse, &p.BlockReason, &p.BlockedAt, &p.BlockedBy); err != nil {
 			return fiber.ErrNotFound
 		}
 
-		if err := json.Unmarshal([]byte(imagesJSON), &p.Images); err != nil {
-			p.Images = []string{}
-		}
-
-		if catUUID != nil {
-			p.Category = &ProductCategory{UUID: catUUID, Name: catName, Icon: catIcon}
+		if err := loadProductVariants(ctx, db, &p.ProductResponse, false); err != nil {
+			return fiber.ErrInternalServerError
 		}
-		p.Owner = ProductOwner{UUID: ownerUUID, Name: ownerName}
 
 		return c.JSON(p)
 	}
@@ -712,7 +904,7 @@ func GetAdminProductHandler(db *pgxpool.Pool) fiber.Handler {
 // BlockProductHandler - POST /products/:uuid/block - block a product
 func BlockProductHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("user_id").(int)
+		userID := c.Locals("userID").(int)
 		productUUID := c.Params("uuid")
 
 		type Input struct {
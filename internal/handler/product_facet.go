@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Catalog Sort & Facet Types
+// ============================================
+
+// productSorts - ORDER BY untuk query sort list produk public
+// (relevance memakai rank productSearch, hanya jika ada search)
+var productSorts = map[string]string{
+	"newest":       `p.created_at DESC`,
+	"price_asc":    `p.price ASC, p.created_at DESC`,
+	"price_desc":   `p.price DESC, p.created_at DESC`,
+	"best_selling": `p.sold_count DESC, p.created_at DESC`,
+	"relevance":    ``,
+}
+
+// productPriceBuckets - rentang harga facet (Rupiah, batas inklusif seperti filter price_min / price_max),
+// Max 0 = tanpa batas atas
+var productPriceBuckets = []struct{ Min, Max int64 }{
+	{0, 50000},
+	{50000, 100000},
+	{100000, 250000},
+	{250000, 500000},
+	{500000, 1000000},
+	{1000000, 0},
+}
+
+type CategoryFacet struct {
+	UUID  string `json:"uuid"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type PriceBucketFacet struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int    `json:"count"`
+}
+
+type ProductFacets struct {
+	Categories   []CategoryFacet    `json:"categories"`
+	PriceBuckets []PriceBucketFacet `json:"price_buckets"`
+}
+
+type ProductListResponse struct {
+	PaginatedResponse
+	Facets ProductFacets `json:"facets"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// loadProductFacets - jumlah produk per kategori dan per bucket harga. baseQuery berisi semua filter
+// kecuali kategori & harga; facet kategori memakai filter harga (priceMatch) dan sebaliknya,
+// sehingga pilihan lain di sidebar tetap terlihat jumlahnya
+func loadProductFacets(ctx context.Context, db *pgxpool.Pool, baseQuery, categoryMatch, priceMatch string, args []interface{}) (ProductFacets, error) {
+	facets := ProductFacets{Categories: []CategoryFacet{}, PriceBuckets: []PriceBucketFacet{}}
+
+	cte := `WITH f AS (
+		SELECT pc.uuid::TEXT AS category_uuid, pc.name AS category_name,
+			p.price, COALESCE(p.price_max, p.price) AS price_hi,
+			COALESCE(` + categoryMatch + `, FALSE) AS category_ok, (` + priceMatch + `) AS price_ok
+		` + baseQuery + `
+	) `
+
+	rows, err := db.Query(ctx, cte+`
+		SELECT category_uuid, category_name, COUNT(*) FROM f
+		WHERE category_uuid IS NOT NULL AND price_ok
+		GROUP BY category_uuid, category_name
+		ORDER BY COUNT(*) DESC, category_name`, args...)
+	if err != nil {
+		return facets, err
+	}
+	for rows.Next() {
+		var cf CategoryFacet
+		if err := rows.Scan(&cf.UUID, &cf.Name, &cf.Count); err != nil {
+			rows.Close()
+			return facets, err
+		}
+		facets.Categories = append(facets.Categories, cf)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return facets, err
+	}
+
+	// Produk bervarian masuk ke setiap bucket yang beririsan dengan price_range-nya
+	columns := ``
+	counts := make([]int, len(productPriceBuckets))
+	dest := make([]interface{}, len(productPriceBuckets))
+	for i, b := range productPriceBuckets {
+		if i > 0 {
+			columns += `, `
+		}
+		cond := `price_hi >= ` + strconv.FormatInt(b.Min, 10)
+		if b.Max > 0 {
+			cond += ` AND price <= ` + strconv.FormatInt(b.Max, 10)
+		}
+		columns += `COUNT(*) FILTER (WHERE ` + cond + `)`
+		dest[i] = &counts[i]
+	}
+	err = db.QueryRow(ctx, cte+`SELECT `+columns+` FROM f WHERE category_ok`, args...).Scan(dest...)
+	if err != nil {
+		return facets, err
+	}
+	for i, b := range productPriceBuckets {
+		bf := PriceBucketFacet{Min: b.Min, Count: counts[i]}
+		if b.Max > 0 {
+			max := b.Max
+			bf.Max = &max
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, bf)
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"shopedia-api/internal/queue"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Text search configuration dari migration 031 (stemmer indonesian)
+const productSearchConfig = "'shopedia_id'"
+
+// ProductHighlight - potongan hasil pencarian (sudah HTML-escaped), kata yang cocok dibungkus <mark>...</mark>
+type ProductHighlight struct {
+	Title   string  `json:"title"`
+	Snippet *string `json:"snippet"`
+}
+
+// productSearch - potongan SQL pencarian produk untuk parameter kata kunci $arg:
+// cocok full-text (search_vector) atau mirip title (pg_trgm, toleran typo)
+type productSearch struct {
+	where     string
+	rank      string
+	highlight string
+}
+
+func newProductSearch(arg int) productSearch {
+	n := `$` + strconv.Itoa(arg)
+	tsq := `websearch_to_tsquery(` + productSearchConfig + `, ` + n + `)`
+	return productSearch{
+		where: ` AND (p.search_vector @@ ` + tsq + ` OR ` + n + ` <% p.title)`,
+		rank:  `(ts_rank(p.search_vector, ` + tsq + `) + word_similarity(` + n + `, p.title))`,
+		highlight: `ts_headline(` + productSearchConfig + `, ` + sqlHTMLEscape(`p.title`) + `, ` + tsq + `,
+				'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+			NULLIF(ts_headline(` + productSearchConfig + `, ` + sqlHTMLEscape(`COALESCE(p.description, '')`) + `, ` + tsq + `,
+				'MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter=" … ", StartSel=<mark>, StopSel=</mark>'), '')`,
+	}
+}
+
+// sqlHTMLEscape - escape HTML di SQL sebelum ts_headline menambahkan tag <mark>,
+// agar title / description dari seller tidak bisa menyisipkan markup
+func sqlHTMLEscape(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr + `,
+				'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// enqueueProductIndex - task update search_vector lewat outbox, ikut transaksi penulisan produk
+// sehingga index tidak tertinggal walau Redis sedang tidak tersedia
+func enqueueProductIndex(ctx context.Context, tx pgx.Tx, productID int, productUUID, action string) error {
+	task, err := queue.NewProductIndexTask(productID, productUUID, action)
+	if err != nil {
+		return err
+	}
+	return queue.AddToOutbox(ctx, tx, task, "low")
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxProductOptions  = 3
+	maxProductVariants = 100
+)
+
+// ============================================
+// Product Variant Types
+// ============================================
+
+type ProductPriceRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+type ProductOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type ProductVariant struct {
+	UUID        string            `json:"uuid"`
+	SKU         string            `json:"sku"`
+	Options     map[string]string `json:"options"`
+	Price       int64             `json:"price"`
+	Stock       int               `json:"stock"`
+	Images      []string          `json:"images"`
+	IsActive    bool              `json:"is_active"`
+	IsAvailable bool              `json:"is_available"`
+}
+
+// ProductVariantRef - varian yang dipilih di cart / order
+type ProductVariantRef struct {
+	UUID    string            `json:"uuid"`
+	SKU     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+}
+
+type productOptionInput struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type productVariantInput struct {
+	UUID     string            `json:"uuid"` // opsional, untuk mengubah varian yang sudah ada
+	SKU      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	Price    int64             `json:"price"`
+	Stock    int               `json:"stock"`
+	Images   []string          `json:"images"`
+	IsActive *bool             `json:"is_active"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// validateVariantMatrix - cek option type dan setiap varian: satu value per option,
+// kombinasi dan SKU unik dalam satu produk
+func validateVariantMatrix(options []productOptionInput, variants []productVariantInput) error {
+	if len(variants) == 0 {
+		if len(options) > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "At least one variant is required when options are set")
+		}
+		return nil
+	}
+	if len(options) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Options are required for variants")
+	}
+	if len(options) > maxProductOptions {
+		return fiber.NewError(fiber.StatusBadRequest, "Maximum 3 option types per product")
+	}
+	if len(variants) > maxProductVariants {
+		return fiber.NewError(fiber.StatusBadRequest, "Maximum 100 variants per product")
+	}
+
+	allowed := make(map[string]map[string]bool)
+	for i := range options {
+		o := &options[i]
+		o.Name = strings.TrimSpace(o.Name)
+		if o.Name == "" || len(o.Name) > 50 {
+			return fiber.NewError(fiber.StatusBadRequest, "Option name is required (max 50 characters)")
+		}
+		if allowed[o.Name] != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Duplicate option: "+o.Name)
+		}
+		if len(o.Values) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Option "+o.Name+" has no values")
+		}
+		allowed[o.Name] = make(map[string]bool)
+		for j, v := range o.Values {
+			v = strings.TrimSpace(v)
+			if v == "" || allowed[o.Name][v] {
+				return fiber.NewError(fiber.StatusBadRequest, "Option "+o.Name+" has an empty or duplicate value")
+			}
+			o.Values[j] = v
+			allowed[o.Name][v] = true
+		}
+	}
+
+	skus := make(map[string]bool)
+	combos := make(map[string]bool)
+	for i := range variants {
+		v := &variants[i]
+		v.SKU = strings.TrimSpace(v.SKU)
+		if v.SKU == "" || len(v.SKU) > 64 {
+			return fiber.NewError(fiber.StatusBadRequest, "Variant SKU is required (max 64 characters)")
+		}
+		if skus[v.SKU] {
+			return fiber.NewError(fiber.StatusBadRequest, "Duplicate SKU: "+v.SKU)
+		}
+		skus[v.SKU] = true
+
+		if v.Price < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Price must be positive")
+		}
+		if v.Stock < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Stock must be positive")
+		}
+
+		if len(v.Options) != len(options) {
+			return fiber.NewError(fiber.StatusBadRequest, "Variant "+v.SKU+" must have one value for every option")
+		}
+		for name, value := range v.Options {
+			if !allowed[name][value] {
+				return fiber.NewError(fiber.StatusBadRequest, "Variant "+v.SKU+" has invalid option "+name+": "+value)
+			}
+		}
+
+		// json.Marshal mengurutkan key map, jadi bisa dipakai sebagai kunci kombinasi
+		combo, _ := json.Marshal(v.Options)
+		if combos[string(combo)] {
+			return fiber.NewError(fiber.StatusBadRequest, "Duplicate option combination for variant "+v.SKU)
+		}
+		combos[string(combo)] = true
+
+		if v.Images == nil {
+			v.Images = []string{}
+		}
+	}
+
+	return nil
+}
+
+// saveProductVariants - simpan matrix varian produk (input sudah divalidasi): option type diganti,
+// varian dicocokkan lewat uuid atau kombinasi option, sisanya di-soft delete.
+// Error yang dikembalikan selalu *fiber.Error.
+func saveProductVariants(ctx context.Context, tx pgx.Tx, productID int, options []productOptionInput, variants []productVariantInput) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM product_options WHERE product_id = $1`, productID); err != nil {
+		log.Printf("Save product options error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+	for i, o := range options {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO product_options (product_id, name, option_values, position) VALUES ($1, $2, $3, $4)`,
+			productID, o.Name, o.Values, i)
+		if err != nil {
+			log.Printf("Save product options error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	// Varian lama yang tidak ada di input di-soft delete dulu agar SKU-nya bisa dipakai ulang
+	uuids, combos := []string{}, []string{}
+	for _, v := range variants {
+		if v.UUID != "" {
+			uuids = append(uuids, v.UUID)
+		} else {
+			combo, _ := json.Marshal(v.Options)
+			combos = append(combos, string(combo))
+		}
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE product_variants SET deleted_at = NOW(), updated_at = NOW()
+		WHERE product_id = $1 AND deleted_at IS NULL
+		AND NOT (uuid::TEXT = ANY($2) OR options = ANY($3::jsonb[]))`,
+		productID, uuids, combos)
+	if err != nil {
+		log.Printf("Save product variants error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+
+	for i, v := range variants {
+		optionsJSON, _ := json.Marshal(v.Options)
+		isActive := true
+		if v.IsActive != nil {
+			isActive = *v.IsActive
+		}
+
+		var variantID int
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM product_variants
+			WHERE product_id = $1 AND deleted_at IS NULL
+			AND (uuid::TEXT = $2 OR ($2 = '' AND options = $3::jsonb))`,
+			productID, v.UUID, string(optionsJSON)).Scan(&variantID)
+		switch {
+		case err == nil:
+			_, err = tx.Exec(ctx, `
+				UPDATE product_variants SET sku = $1, options = $2::jsonb, price = $3, stock = $4, images = $5,
+					is_active = $6, position = $7, updated_at = NOW()
+				WHERE id = $8`,
+				v.SKU, string(optionsJSON), v.Price, v.Stock, v.Images, isActive, i, variantID)
+		case errors.Is(err, pgx.ErrNoRows) && v.UUID != "":
+			return fiber.NewError(fiber.StatusBadRequest, "Variant not found: "+v.UUID)
+		case errors.Is(err, pgx.ErrNoRows):
+			err = tx.QueryRow(ctx, `
+				INSERT INTO product_variants (product_id, sku, options, price, stock, images, is_active, position)
+				VALUES ($1, $2, $3::jsonb, $4, $5, $6, $7, $8)
+				RETURNING id`,
+				productID, v.SKU, string(optionsJSON), v.Price, v.Stock, v.Images, isActive, i).Scan(&variantID)
+		}
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fiber.NewError(fiber.StatusConflict, "SKU or option combination already used by another variant")
+			}
+			log.Printf("Save product variant error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+	}
+
+	if err := syncVariantAggregates(ctx, tx, productID); err != nil {
+		log.Printf("Sync variant aggregates error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+	return nil
+}
+
+// syncVariantAggregates - products.price (termurah), price_max dan stock (total) dari varian aktif,
+// supaya list, filter dan checkout produk tetap bisa membaca kolom products
+func syncVariantAggregates(ctx context.Context, tx pgx.Tx, productID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE products p SET
+			has_variants = v.total > 0,
+			price = CASE WHEN v.total > 0 THEN COALESCE(v.min_price, p.price) ELSE p.price END,
+			price_max = CASE WHEN v.total > 0 THEN COALESCE(v.max_price, p.price) END,
+			stock = CASE WHEN v.total > 0 THEN v.stock ELSE p.stock END,
+			updated_at = NOW()
+		FROM (
+			SELECT COUNT(*) AS total,
+				MIN(price) FILTER (WHERE is_active) AS min_price,
+				MAX(price) FILTER (WHERE is_active) AS max_price,
+				COALESCE(SUM(stock) FILTER (WHERE is_active), 0) AS stock
+			FROM product_variants WHERE product_id = $1 AND deleted_at IS NULL
+		) v
+		WHERE p.id = $1`, productID)
+	return err
+}
+
+// loadProductVariants - isi p.Options dan p.Variants untuk response detail produk,
+// public hanya menampilkan varian aktif
+func loadProductVariants(ctx context.Context, db *pgxpool.Pool, p *ProductResponse, public bool) error {
+	if !p.HasVariants {
+		return nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT o.name, o.option_values FROM product_options o
+		JOIN products p ON o.product_id = p.id
+		WHERE p.uuid = $1
+		ORDER BY o.position`, p.UUID)
+	if err != nil {
+		return err
+	}
+	p.Options = []ProductOption{}
+	for rows.Next() {
+		var o ProductOption
+		if err := rows.Scan(&o.Name, &o.Values); err != nil {
+			rows.Close()
+			return err
+		}
+		p.Options = append(p.Options, o)
+	}
+	rows.Close()
+
+	rows, err = db.Query(ctx, `
+		SELECT v.uuid::TEXT, v.sku, v.options::TEXT, v.price, v.stock, v.images, v.is_active
+		FROM product_variants v
+		JOIN products p ON v.product_id = p.id
+		WHERE p.uuid = $1 AND v.deleted_at IS NULL AND ($2 = FALSE OR v.is_active = TRUE)
+		ORDER BY v.position, v.id`, p.UUID, public)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	p.Variants = []ProductVariant{}
+	for rows.Next() {
+		var v ProductVariant
+		var optionsJSON string
+		if err := rows.Scan(&v.UUID, &v.SKU, &optionsJSON, &v.Price, &v.Stock, &v.Images, &v.IsActive); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(optionsJSON), &v.Options); err != nil {
+			v.Options = map[string]string{}
+		}
+		v.IsAvailable = v.IsActive && v.Stock > 0
+		p.Variants = append(p.Variants, v)
+	}
+
+	return rows.Err()
+}
+
+// variantRef - varian dari kolom hasil LEFT JOIN product_variants (nil jika tanpa varian)
+func variantRef(uuid, sku, optionsJSON *string) *ProductVariantRef {
+	if uuid == nil {
+		return nil
+	}
+	ref := &ProductVariantRef{UUID: *uuid}
+	if sku != nil {
+		ref.SKU = *sku
+	}
+	if optionsJSON == nil || json.Unmarshal([]byte(*optionsJSON), &ref.Options) != nil {
+		ref.Options = map[string]string{}
+	}
+	return ref
+}
@@ -0,0 +1,715 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Promo Response Types
+// ============================================
+
+type PromoResponse struct {
+	UUID          string     `json:"uuid"`
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Description   *string    `json:"description"`
+	DiscountType  string     `json:"discount_type"` // percentage, fixed
+	DiscountValue int64      `json:"discount_value"`
+	MaxDiscount   *int64     `json:"max_discount"`
+	MinSpend      int64      `json:"min_spend"`
+	UsageLimit    *int       `json:"usage_limit"`
+	UsagePerUser  *int       `json:"usage_per_user"`
+	UsedCount     int        `json:"used_count"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	IsActive      bool       `json:"is_active"`
+	CategoryUUIDs []string   `json:"category_uuids"`
+	ProductUUIDs  []string   `json:"product_uuids"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// promoItem - satu baris belanja yang dievaluasi terhadap promo
+type promoItem struct {
+	productID  int
+	categoryID *int
+	subtotal   int64
+}
+
+// promoResult - hasil evaluasi promo yang valid
+type promoResult struct {
+	promoID          int
+	code             string
+	eligibleSubtotal int64
+	discount         int64
+	lineDiscounts    []int64 // diskon per item (urutan sama dengan items), 0 untuk item di luar scope
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// evaluatePromo - validasi kode promo untuk user & item, hitung diskon.
+// forUpdate=true mengunci baris promo sampai DB transaction selesai, sehingga
+// pengecekan usage_limit dan redemption tidak bisa balapan (dipakai checkout).
+func evaluatePromo(ctx context.Context, q querier, code string, userID int, items []promoItem, forUpdate bool) (*promoResult, error) {
+	query := `
+		SELECT id, code, discount_type, discount_value, max_discount, min_spend,
+			usage_limit, usage_per_user, used_count, is_active,
+			(starts_at IS NOT NULL AND starts_at > NOW()), (ends_at IS NOT NULL AND ends_at < NOW())
+		FROM promos WHERE code = $1 AND deleted_at IS NULL`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var result promoResult
+	var discountType string
+	var discountValue, minSpend int64
+	var maxDiscount *int64
+	var usageLimit, usagePerUser *int
+	var usedCount int
+	var isActive, notStarted, expired bool
+	err := q.QueryRow(ctx, query, normalizePromoCode(code)).Scan(&result.promoID, &result.code, &discountType,
+		&discountValue, &maxDiscount, &minSpend, &usageLimit, &usagePerUser, &usedCount, &isActive, &notStarted, &expired)
+	if err == pgx.ErrNoRows {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Promo code not found")
+	}
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+
+	switch {
+	case !isActive:
+		return nil, fiber.NewError(fiber.StatusConflict, "Promo is not active")
+	case notStarted:
+		return nil, fiber.NewError(fiber.StatusConflict, "Promo has not started yet")
+	case expired:
+		return nil, fiber.NewError(fiber.StatusConflict, "Promo has expired")
+	case usageLimit != nil && usedCount >= *usageLimit:
+		return nil, fiber.NewError(fiber.StatusConflict, "Promo usage limit has been reached")
+	}
+
+	if usagePerUser != nil {
+		var userUsage int
+		err := q.QueryRow(ctx, `SELECT COUNT(*) FROM promo_redemptions WHERE promo_id = $1 AND user_id = $2`,
+			result.promoID, userID).Scan(&userUsage)
+		if err != nil {
+			return nil, fiber.ErrInternalServerError
+		}
+		if userUsage >= *usagePerUser {
+			return nil, fiber.NewError(fiber.StatusConflict, "You have reached the usage limit for this promo")
+		}
+	}
+
+	// Scope: tanpa kategori/produk = semua item eligible
+	var categoryIDs, productIDs []int
+	err = q.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT array_agg(category_id) FROM promo_categories WHERE promo_id = $1), '{}'),
+			COALESCE((SELECT array_agg(product_id) FROM promo_products WHERE promo_id = $1), '{}')`,
+		result.promoID).Scan(&categoryIDs, &productIDs)
+	if err != nil {
+		return nil, fiber.ErrInternalServerError
+	}
+
+	scoped := len(categoryIDs) > 0 || len(productIDs) > 0
+	inScope := make(map[int]bool)
+	for _, id := range productIDs {
+		inScope[id] = true
+	}
+	inCategory := make(map[int]bool)
+	for _, id := range categoryIDs {
+		inCategory[id] = true
+	}
+
+	eligible := make([]bool, len(items))
+	for i, item := range items {
+		if !scoped || inScope[item.productID] || (item.categoryID != nil && inCategory[*item.categoryID]) {
+			eligible[i] = true
+			result.eligibleSubtotal += item.subtotal
+		}
+	}
+
+	if result.eligibleSubtotal == 0 {
+		return nil, fiber.NewError(fiber.StatusConflict, "Promo does not apply to these items")
+	}
+	if result.eligibleSubtotal < minSpend {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Minimum spend for this promo is Rp%d", minSpend))
+	}
+
+	if discountType == "percentage" {
+		result.discount = result.eligibleSubtotal * discountValue / 100
+		if maxDiscount != nil && result.discount > *maxDiscount {
+			result.discount = *maxDiscount
+		}
+	} else {
+		result.discount = discountValue
+	}
+	if result.discount > result.eligibleSubtotal {
+		result.discount = result.eligibleSubtotal
+	}
+
+	// Alokasi proporsional ke item eligible, sisa pembulatan ke item eligible terakhir
+	result.lineDiscounts = make([]int64, len(items))
+	remaining, last := result.discount, -1
+	for i, item := range items {
+		if eligible[i] {
+			result.lineDiscounts[i] = result.discount * item.subtotal / result.eligibleSubtotal
+			remaining -= result.lineDiscounts[i]
+			last = i
+		}
+	}
+	result.lineDiscounts[last] += remaining
+
+	return &result, nil
+}
+
+// normalizePromoCode - kode promo case-insensitive, disimpan uppercase
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// resolvePromoScope - ubah uuid kategori/produk menjadi id
+func resolvePromoScope(ctx context.Context, db *pgxpool.Pool, categoryUUIDs, productUUIDs []string) ([]int, []int, error) {
+	categoryIDs := []int{}
+	for _, catUUID := range categoryUUIDs {
+		var id int
+		err := db.QueryRow(ctx, `SELECT id FROM product_categories WHERE uuid = $1 AND deleted_at IS NULL`, catUUID).Scan(&id)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Category not found: "+catUUID)
+		}
+		categoryIDs = append(categoryIDs, id)
+	}
+
+	productIDs := []int{}
+	for _, productUUID := range productUUIDs {
+		var id int
+		err := db.QueryRow(ctx, `SELECT id FROM products WHERE uuid = $1 AND deleted_at IS NULL`, productUUID).Scan(&id)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Product not found: "+productUUID)
+		}
+		productIDs = append(productIDs, id)
+	}
+
+	return categoryIDs, productIDs, nil
+}
+
+// savePromoScope - replace scope kategori/produk sebuah promo
+func savePromoScope(ctx context.Context, tx pgx.Tx, promoID int, categoryIDs, productIDs []int) error {
+	if categoryIDs != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM promo_categories WHERE promo_id = $1`, promoID); err != nil {
+			return err
+		}
+		for _, id := range categoryIDs {
+			_, err := tx.Exec(ctx, `INSERT INTO promo_categories (promo_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				promoID, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if productIDs != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM promo_products WHERE promo_id = $1`, promoID); err != nil {
+			return err
+		}
+		for _, id := range productIDs {
+			_, err := tx.Exec(ctx, `INSERT INTO promo_products (promo_id, product_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				promoID, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePromoFields - validasi nilai diskon & periode promo
+func validatePromoFields(p *PromoResponse) error {
+	if p.DiscountType != "percentage" && p.DiscountType != "fixed" {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid discount_type. Must be 'percentage' or 'fixed'")
+	}
+	if p.DiscountValue <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "discount_value must be greater than 0")
+	}
+	if p.DiscountType == "percentage" && p.DiscountValue > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Percentage discount cannot exceed 100")
+	}
+	if p.MaxDiscount != nil && *p.MaxDiscount <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "max_discount must be greater than 0")
+	}
+	if p.MinSpend < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "min_spend cannot be negative")
+	}
+	if (p.UsageLimit != nil && *p.UsageLimit <= 0) || (p.UsagePerUser != nil && *p.UsagePerUser <= 0) {
+		return fiber.NewError(fiber.StatusBadRequest, "Usage limits must be greater than 0")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fiber.NewError(fiber.StatusBadRequest, "ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// getPromoByUUID - ambil promo beserta scope
+func getPromoByUUID(ctx context.Context, db *pgxpool.Pool, promoUUID string) (int, *PromoResponse, error) {
+	var promoID int
+	var p PromoResponse
+	err := db.QueryRow(ctx, `
+		SELECT id, uuid, code, name, description, discount_type, discount_value, max_discount, min_spend,
+			usage_limit, usage_per_user, used_count, starts_at, ends_at, is_active, created_at, updated_at
+		FROM promos WHERE uuid = $1 AND deleted_at IS NULL`,
+		promoUUID).Scan(&promoID, &p.UUID, &p.Code, &p.Name, &p.Description, &p.DiscountType, &p.DiscountValue,
+		&p.MaxDiscount, &p.MinSpend, &p.UsageLimit, &p.UsagePerUser, &p.UsedCount, &p.StartsAt, &p.EndsAt,
+		&p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = db.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT array_agg(pc.uuid::TEXT) FROM promo_categories pr JOIN product_categories pc ON pr.category_id = pc.id WHERE pr.promo_id = $1), '{}'),
+			COALESCE((SELECT array_agg(p.uuid::TEXT) FROM promo_products pr JOIN products p ON pr.product_id = p.id WHERE pr.promo_id = $1), '{}')`,
+		promoID).Scan(&p.CategoryUUIDs, &p.ProductUUIDs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return promoID, &p, nil
+}
+
+// ============================================
+// Admin Promo Handlers (Dashboard)
+// ============================================
+
+// ListPromosHandler - GET /promos - list promo
+func ListPromosHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		search := c.Query("search", "")
+		activeFilter := c.Query("is_active", "")
+
+		baseQuery := `FROM promos WHERE deleted_at IS NULL`
+		args := []interface{}{}
+		argCount := 0
+
+		if search != "" {
+			argCount++
+			baseQuery += ` AND (code ILIKE $` + strconv.Itoa(argCount) + ` OR name ILIKE $` + strconv.Itoa(argCount) + `)`
+			args = append(args, "%"+search+"%")
+		}
+
+		if activeFilter != "" {
+			argCount++
+			baseQuery += ` AND is_active = $` + strconv.Itoa(argCount)
+			args = append(args, activeFilter == "true")
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT uuid, code, name, description, discount_type, discount_value, max_discount, min_spend,
+			usage_limit, usage_per_user, used_count, starts_at, ends_at, is_active, created_at, updated_at ` +
+			baseQuery + ` ORDER BY created_at DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		promos := []PromoResponse{}
+		for rows.Next() {
+			var p PromoResponse
+			err := rows.Scan(&p.UUID, &p.Code, &p.Name, &p.Description, &p.DiscountType, &p.DiscountValue,
+				&p.MaxDiscount, &p.MinSpend, &p.UsageLimit, &p.UsagePerUser, &p.UsedCount, &p.StartsAt, &p.EndsAt,
+				&p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+			if err != nil {
+				continue
+			}
+			p.CategoryUUIDs = []string{}
+			p.ProductUUIDs = []string{}
+			promos = append(promos, p)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       promos,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// GetPromoHandler - GET /promos/:uuid - detail promo + scope
+func GetPromoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, promo, err := getPromoByUUID(context.Background(), db, c.Params("uuid"))
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(promo)
+	}
+}
+
+// CreatePromoHandler - POST /promos - buat voucher baru
+func CreatePromoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Code          string     `json:"code"`
+			Name          string     `json:"name"`
+			Description   *string    `json:"description"`
+			DiscountType  string     `json:"discount_type"`
+			DiscountValue int64      `json:"discount_value"`
+			MaxDiscount   *int64     `json:"max_discount"`
+			MinSpend      int64      `json:"min_spend"`
+			UsageLimit    *int       `json:"usage_limit"`
+			UsagePerUser  *int       `json:"usage_per_user"`
+			StartsAt      *time.Time `json:"starts_at"`
+			EndsAt        *time.Time `json:"ends_at"`
+			IsActive      *bool      `json:"is_active"`
+			CategoryUUIDs []string   `json:"category_uuids"`
+			ProductUUIDs  []string   `json:"product_uuids"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		code := normalizePromoCode(input.Code)
+		if code == "" || input.Name == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Code and name are required")
+		}
+
+		promo := PromoResponse{
+			DiscountType:  input.DiscountType,
+			DiscountValue: input.DiscountValue,
+			MaxDiscount:   input.MaxDiscount,
+			MinSpend:      input.MinSpend,
+			UsageLimit:    input.UsageLimit,
+			UsagePerUser:  input.UsagePerUser,
+			StartsAt:      input.StartsAt,
+			EndsAt:        input.EndsAt,
+		}
+		if err := validatePromoFields(&promo); err != nil {
+			return err
+		}
+
+		isActive := true
+		if input.IsActive != nil {
+			isActive = *input.IsActive
+		}
+
+		ctx := context.Background()
+
+		var exists bool
+		db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM promos WHERE code = $1 AND deleted_at IS NULL)`, code).Scan(&exists)
+		if exists {
+			return fiber.NewError(fiber.StatusConflict, "Promo code already exists")
+		}
+
+		categoryIDs, productIDs, err := resolvePromoScope(ctx, db, input.CategoryUUIDs, input.ProductUUIDs)
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var promoID int
+		var promoUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO promos (code, name, description, discount_type, discount_value, max_discount, min_spend,
+				usage_limit, usage_per_user, starts_at, ends_at, is_active, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING id, uuid`,
+			code, input.Name, input.Description, input.DiscountType, input.DiscountValue, input.MaxDiscount, input.MinSpend,
+			input.UsageLimit, input.UsagePerUser, input.StartsAt, input.EndsAt, isActive, userID).Scan(&promoID, &promoUUID)
+		if err != nil {
+			log.Printf("CreatePromo error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := savePromoScope(ctx, tx, promoID, categoryIDs, productIDs); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Promo created successfully",
+			"uuid":    promoUUID,
+			"code":    code,
+		})
+	}
+}
+
+// UpdatePromoHandler - PUT /promos/:uuid - update promo (field yang dikirim saja)
+func UpdatePromoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		promoUUID := c.Params("uuid")
+
+		type Input struct {
+			Code          *string    `json:"code"`
+			Name          *string    `json:"name"`
+			Description   *string    `json:"description"`
+			DiscountType  *string    `json:"discount_type"`
+			DiscountValue *int64     `json:"discount_value"`
+			MaxDiscount   *int64     `json:"max_discount"`
+			MinSpend      *int64     `json:"min_spend"`
+			UsageLimit    *int       `json:"usage_limit"`
+			UsagePerUser  *int       `json:"usage_per_user"`
+			StartsAt      *time.Time `json:"starts_at"`
+			EndsAt        *time.Time `json:"ends_at"`
+			IsActive      *bool      `json:"is_active"`
+			CategoryUUIDs []string   `json:"category_uuids"` // dikirim = replace scope
+			ProductUUIDs  []string   `json:"product_uuids"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		// Field opsional yang dikirim null secara eksplisit dikosongkan (tanpa batas)
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(c.Body(), &raw); err != nil {
+			return fiber.ErrBadRequest
+		}
+		isNull := func(field string) bool {
+			v, ok := raw[field]
+			return ok && string(v) == "null"
+		}
+
+		ctx := context.Background()
+
+		promoID, promo, err := getPromoByUUID(ctx, db, promoUUID)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		// Merge lalu validasi sebagai satu kesatuan (misal percentage <= 100, ends_at > starts_at)
+		if input.Code != nil {
+			code := normalizePromoCode(*input.Code)
+			if code == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Code cannot be empty")
+			}
+			var exists bool
+			db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM promos WHERE code = $1 AND id != $2 AND deleted_at IS NULL)`,
+				code, promoID).Scan(&exists)
+			if exists {
+				return fiber.NewError(fiber.StatusConflict, "Promo code already exists")
+			}
+			promo.Code = code
+		}
+		if input.Name != nil {
+			promo.Name = *input.Name
+		}
+		if input.Description != nil {
+			promo.Description = input.Description
+		}
+		if input.DiscountType != nil {
+			promo.DiscountType = *input.DiscountType
+		}
+		if input.DiscountValue != nil {
+			promo.DiscountValue = *input.DiscountValue
+		}
+		if input.MaxDiscount != nil || isNull("max_discount") {
+			promo.MaxDiscount = input.MaxDiscount
+		}
+		if input.MinSpend != nil {
+			promo.MinSpend = *input.MinSpend
+		}
+		if input.UsageLimit != nil || isNull("usage_limit") {
+			promo.UsageLimit = input.UsageLimit
+		}
+		if input.UsagePerUser != nil || isNull("usage_per_user") {
+			promo.UsagePerUser = input.UsagePerUser
+		}
+		if input.StartsAt != nil || isNull("starts_at") {
+			promo.StartsAt = input.StartsAt
+		}
+		if input.EndsAt != nil || isNull("ends_at") {
+			promo.EndsAt = input.EndsAt
+		}
+		if input.IsActive != nil {
+			promo.IsActive = *input.IsActive
+		}
+
+		if err := validatePromoFields(promo); err != nil {
+			return err
+		}
+
+		var categoryIDs, productIDs []int
+		if input.CategoryUUIDs != nil || input.ProductUUIDs != nil {
+			categoryIDs, productIDs, err = resolvePromoScope(ctx, db, input.CategoryUUIDs, input.ProductUUIDs)
+			if err != nil {
+				return err
+			}
+			if input.CategoryUUIDs == nil {
+				categoryIDs = nil
+			}
+			if input.ProductUUIDs == nil {
+				productIDs = nil
+			}
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, `
+			UPDATE promos SET code = $1, name = $2, description = $3, discount_type = $4, discount_value = $5,
+				max_discount = $6, min_spend = $7, usage_limit = $8, usage_per_user = $9, starts_at = $10,
+				ends_at = $11, is_active = $12, updated_at = NOW()
+			WHERE id = $13`,
+			promo.Code, promo.Name, promo.Description, promo.DiscountType, promo.DiscountValue,
+			promo.MaxDiscount, promo.MinSpend, promo.UsageLimit, promo.UsagePerUser, promo.StartsAt,
+			promo.EndsAt, promo.IsActive, promoID)
+		if err != nil {
+			log.Printf("UpdatePromo error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := savePromoScope(ctx, tx, promoID, categoryIDs, productIDs); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "Promo updated successfully"})
+	}
+}
+
+// DeletePromoHandler - DELETE /promos/:uuid - soft delete promo
+func DeletePromoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		promoUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		result, err := db.Exec(ctx, `
+			UPDATE promos SET deleted_at = NOW(), updated_at = NOW()
+			WHERE uuid = $1 AND deleted_at IS NULL`, promoUUID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if result.RowsAffected() == 0 {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(fiber.Map{"message": "Promo deleted successfully"})
+	}
+}
+
+// ============================================
+// App Promo Handlers
+// ============================================
+
+// ValidatePromoHandler - POST /promos/validate - cek kode promo terhadap item yang akan di-checkout
+func ValidatePromoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type ItemInput struct {
+			ProductUUID string `json:"product_uuid"`
+			VariantUUID string `json:"variant_uuid"` // wajib untuk produk bervarian
+			Qty         int    `json:"qty"`
+		}
+		type Input struct {
+			Code  string      `json:"code"`
+			Items []ItemInput `json:"items"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Code == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Promo code is required")
+		}
+		if len(input.Items) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "At least one item is required")
+		}
+
+		ctx := context.Background()
+
+		items := []promoItem{}
+		var subtotal int64
+		for _, in := range input.Items {
+			if in.Qty < 1 {
+				return fiber.NewError(fiber.StatusBadRequest, "Quantity must be at least 1")
+			}
+
+			// Harga dihitung sama seperti checkout (produk bervarian: harga varian)
+			line, err := lookupOrderLine(ctx, db, in.ProductUUID, in.VariantUUID, userID, false)
+			if err != nil {
+				return err
+			}
+			item := promoItem{
+				productID:  line.productID,
+				categoryID: line.categoryID,
+				subtotal:   line.unitPrice * int64(in.Qty),
+			}
+			subtotal += item.subtotal
+			items = append(items, item)
+		}
+
+		result, err := evaluatePromo(ctx, db, input.Code, userID, items, false)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"code":              result.code,
+			"subtotal":          subtotal,
+			"eligible_subtotal": result.eligibleSubtotal,
+			"discount":          result.discount,
+			"total":             subtotal - result.discount,
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	utils "shopedia-api/internal/util"
+)
+
+type tokenPair struct {
+	AccessToken      string
+	AccessJTI        string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+func (p *tokenPair) response() fiber.Map {
+	return fiber.Map{
+		"access_token":       p.AccessToken,
+		"expires_at":         p.AccessExpiresAt.Format(time.RFC3339),
+		"refresh_token":      p.RefreshToken,
+		"refresh_expires_at": p.RefreshExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// issueTokenPair - buat access token + refresh token baru dalam family yang diberikan
+func issueTokenPair(ctx context.Context, tx pgx.Tx, userID int, userUUID string, roles *userRoles, mode, familyID string) (*tokenPair, error) {
+	accessExpires := time.Now().Add(utils.AccessTokenTTL)
+	accessToken, jti, err := utils.GenerateAccessToken(userID, userUUID, roles.Roles, roles.Scopes, utils.LoginScope(mode), accessExpires)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshExpires := time.Now().Add(utils.RefreshTokenTTL(mode))
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, mode, access_jti, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userID, familyID, refreshHash, mode, jti, accessExpires, refreshExpires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:      accessToken,
+		AccessJTI:        jti,
+		AccessExpiresAt:  accessExpires,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpires,
+	}, nil
+}
+
+// RefreshTokenHandler - POST /api/app/refresh & /api/admin/refresh
+// Tukar refresh token dengan access token + refresh token baru (rotasi).
+// Refresh token yang sudah pernah dirotasi lalu dipakai lagi dianggap bocor,
+// seluruh family-nya di-revoke dan user harus login ulang.
+func RefreshTokenHandler(db *pgxpool.Pool, mode string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil || input.RefreshToken == "" {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var tokenID, userID int
+		var familyID, tokenMode string
+		var expiresAt time.Time
+		var rotatedAt, revokedAt *time.Time
+		err = tx.QueryRow(ctx, `
+			SELECT id, user_id, family_id::TEXT, mode, expires_at, rotated_at, revoked_at
+			FROM refresh_tokens
+			WHERE token_hash = $1
+			FOR UPDATE`,
+			utils.HashRefreshToken(input.RefreshToken)).Scan(&tokenID, &userID, &familyID, &tokenMode, &expiresAt, &rotatedAt, &revokedAt)
+		if err != nil || tokenMode != mode {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid refresh token")
+		}
+
+		if revokedAt != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Refresh token has been revoked")
+		}
+
+		if rotatedAt != nil {
+			// Reuse terdeteksi - lepas lock dulu, lalu revoke seluruh family
+			tx.Rollback(ctx)
+			log.Printf("Refresh token reuse detected: user %d, family %s", userID, familyID)
+			if err := utils.RevokeRefreshFamily(ctx, db, familyID); err != nil {
+				log.Printf("Revoke refresh family error: %v", err)
+			}
+			return fiber.NewError(fiber.StatusUnauthorized, "Refresh token reuse detected, please login again")
+		}
+
+		if time.Now().After(expiresAt) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Refresh token expired, please login again")
+		}
+
+		// Cek status user (sama seperti JWTProtected)
+		var userUUID, email string
+		var isActive, isBanned, isDeleted bool
+		err = tx.QueryRow(ctx, `
+			SELECT uuid, email, is_active, COALESCE(is_banned, FALSE), (deleted_at IS NOT NULL)
+			FROM users WHERE id = $1`,
+			userID).Scan(&userUUID, &email, &isActive, &isBanned, &isDeleted)
+		if err != nil || isDeleted || isBanned || !isActive {
+			return fiber.NewError(fiber.StatusUnauthorized, "Account is not active")
+		}
+
+		roles, err := loginRoles(ctx, tx, userID, mode)
+		if err != nil {
+			return err
+		}
+
+		// Role yang baru diwajibkan 2FA tidak bisa memperpanjang session tanpa enrollment
+		if mode == "admin" {
+			enabled, required, err := mfaStatus(ctx, tx, userID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			if required && !enabled {
+				return fiber.NewError(fiber.StatusUnauthorized, "2FA setup required, please login again")
+			}
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE refresh_tokens SET rotated_at = NOW() WHERE id = $1`, tokenID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		pair, err := issueTokenPair(ctx, tx, userID, userUUID, roles, mode, familyID)
+		if err != nil {
+			log.Printf("Refresh issue token error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		// Access token lama dari session ini di-revoke, device tetap sama
+		err = utils.RotateSession(ctx, db, userID, familyID, pair.AccessJTI, pair.AccessExpiresAt, email, roles.Roles, mode, c.IP())
+		if err != nil {
+			log.Printf("Refresh rotate session error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(pair.response())
+	}
+}
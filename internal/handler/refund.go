@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Admin Refund Handlers (Dashboard)
+// ============================================
+
+// RefundTransactionHandler - POST /transactions/:uuid/refund - full/partial refund transaksi.
+// Stock dikembalikan, saldo refund buyer (buyer_credits) dikreditkan dan saldo seller didebit dalam satu DB transaction.
+func RefundTransactionHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("userID").(int)
+		transactionUUID := c.Params("uuid")
+
+		type Input struct {
+			Qty    int    `json:"qty"` // kosong = refund semua qty yang tersisa
+			Reason string `json:"reason"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Reason == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Reason is required")
+		}
+		if input.Qty < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Quantity must be at least 1")
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var transactionID, buyerID, sellerID, productID, qty, refundedQty int
+		var totalPrice, unitPrice, refundedAmount, lineDiscount int64
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, buyer_user_id, seller_user_id, product_id, qty, refunded_qty,
+				total_price, unit_price, refunded_amount, discount_amount, status
+			FROM transactions WHERE uuid = $1
+			FOR UPDATE`,
+			transactionUUID).Scan(&transactionID, &buyerID, &sellerID, &productID, &qty, &refundedQty,
+			&totalPrice, &unitPrice, &refundedAmount, &lineDiscount, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		// Hanya transaksi yang saldo seller-nya sudah dikreditkan yang bisa di-refund
+		if status != "completed" && status != "partially_refunded" {
+			return fiber.NewError(fiber.StatusConflict, "Transaction cannot be refunded in status "+status)
+		}
+
+		remainingQty := qty - refundedQty
+		refundQty := input.Qty
+		if refundQty == 0 {
+			refundQty = remainingQty
+		}
+		if refundQty > remainingQty {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Refund quantity exceeds remaining quantity (%d)", remainingQty))
+		}
+
+		// Refund terakhir mengambil sisa total_price agar total refund selalu sama dengan total_price
+		refundAmount := unitPrice * int64(refundQty)
+		if refundQty == remainingQty {
+			refundAmount = totalPrice - refundedAmount
+		}
+
+		newStatus := "partially_refunded"
+		if refundQty == remainingQty {
+			newStatus = "refunded"
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE transactions SET status = $1, refunded_qty = refunded_qty + $2,
+				refunded_amount = refunded_amount + $3, updated_at = NOW()
+			WHERE id = $4`,
+			newStatus, refundQty, refundAmount, transactionID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE products SET stock = stock + $1, sold_count = GREATEST(sold_count - $1, 0), updated_at = NOW()
+			WHERE id = $2`,
+			refundQty, productID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE product_variants SET stock = stock + $1, updated_at = NOW()
+			WHERE id = (SELECT variant_id FROM transactions WHERE id = $2)`,
+			refundQty, transactionID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		description := "Refund transaksi " + transactionUUID
+		if err := debitBalance(ctx, tx, sellerID, refundAmount, &transactionID, description); err != nil {
+			log.Printf("Refund debit seller error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		// Diskon voucher ditanggung platform: seller didebit penuh, buyer menerima
+		// refund dikurangi diskon baris ini secara proporsional qty (dihitung kumulatif
+		// agar total diskon yang dipotong di semua refund sama dengan discount_amount)
+		discountShare := lineDiscount*int64(refundedQty+refundQty)/int64(qty) - lineDiscount*int64(refundedQty)/int64(qty)
+		buyerAmount := refundAmount - discountShare
+
+		if err := creditBuyer(ctx, tx, buyerID, buyerAmount, &transactionID, description); err != nil {
+			log.Printf("Refund credit buyer error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		var refundUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO refunds (transaction_id, qty, amount, reason, refunded_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING uuid`,
+			transactionID, refundQty, refundAmount, input.Reason, adminID).Scan(&refundUUID)
+		if err != nil {
+			log.Printf("Refund insert error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("Refund commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(buyerID, "Refund diproses",
+			fmt.Sprintf("Refund Rp%d telah masuk ke saldo refund Anda", buyerAmount), "success")
+		notifyUser(sellerID, "Refund transaksi",
+			fmt.Sprintf("Saldo Anda dikurangi Rp%d untuk refund transaksi %s: %s", refundAmount, transactionUUID, input.Reason), "warning")
+
+		return c.JSON(fiber.Map{
+			"message":       "Transaction refunded successfully",
+			"uuid":          refundUUID,
+			"status":        newStatus,
+			"refund_qty":    refundQty,
+			"refund_amount": refundAmount,
+			"buyer_amount":  buyerAmount,
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Review Response Types
+// ============================================
+
+type ReviewResponse struct {
+	UUID         string    `json:"uuid"`
+	Rating       int       `json:"rating"`
+	Comment      *string   `json:"comment"`
+	ReviewerName string    `json:"reviewer_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ============================================
+// Review Handlers (App)
+// ============================================
+
+// CreateReviewHandler - POST /reviews - ulasan buyer untuk transaksi yang sudah completed.
+// Agregat products.rating_avg / rating_count diperbarui dalam DB transaction yang sama.
+func CreateReviewHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			TransactionUUID string `json:"transaction_uuid"`
+			Rating          int    `json:"rating"`
+			Comment         string `json:"comment"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.TransactionUUID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Transaction UUID is required")
+		}
+		if input.Rating < 1 || input.Rating > 5 {
+			return fiber.NewError(fiber.StatusBadRequest, "Rating must be between 1 and 5")
+		}
+		var comment *string
+		if s := strings.TrimSpace(input.Comment); s != "" {
+			comment = &s
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var transactionID, productID int
+		var status string
+		err = tx.QueryRow(ctx, `
+			SELECT id, product_id, status FROM transactions
+			WHERE uuid::TEXT = $1 AND buyer_user_id = $2`,
+			input.TransactionUUID, userID).Scan(&transactionID, &productID, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		// Hanya barang yang sudah diterima (order completed) yang bisa diulas
+		if status != "completed" && status != "partially_refunded" {
+			return fiber.NewError(fiber.StatusConflict, "Transaction cannot be reviewed in status "+status)
+		}
+
+		// Lock produk agar agregat rating tidak tertimpa ulasan lain yang masuk bersamaan
+		if _, err := tx.Exec(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, productID); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		var reviewUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO product_reviews (product_id, transaction_id, user_id, rating, comment)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING uuid`,
+			productID, transactionID, userID, input.Rating, comment).Scan(&reviewUUID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fiber.NewError(fiber.StatusConflict, "Transaction has already been reviewed")
+			}
+			log.Printf("Review insert error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE products p SET rating_avg = r.avg, rating_count = r.count
+			FROM (
+				SELECT ROUND(AVG(rating), 1) AS avg, COUNT(*) AS count
+				FROM product_reviews WHERE product_id = $1
+			) r
+			WHERE p.id = $1`,
+			productID)
+		if err != nil {
+			log.Printf("Review rating update error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Review submitted successfully",
+			"uuid":    reviewUUID,
+		})
+	}
+}
+
+// ============================================
+// Public Review Handlers
+// ============================================
+
+// ListProductReviewsHandler - GET /products/:uuid/reviews - ulasan produk, terbaru dulu
+func ListProductReviewsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		productUUID := c.Params("uuid")
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		var productID int
+		err := db.QueryRow(ctx, `
+			SELECT id FROM products
+			WHERE uuid = $1 AND deleted_at IS NULL AND status = 'active' AND is_active = TRUE`,
+			productUUID).Scan(&productID)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		var totalItems int
+		err = db.QueryRow(ctx, `SELECT COUNT(*) FROM product_reviews WHERE product_id = $1`, productID).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		rows, err := db.Query(ctx, `
+			SELECT r.uuid, r.rating, r.comment, COALESCE(u.full_name, ''), r.created_at
+			FROM product_reviews r
+			JOIN users u ON r.user_id = u.id
+			WHERE r.product_id = $1
+			ORDER BY r.created_at DESC, r.id DESC LIMIT $2 OFFSET $3`,
+			productID, limit, offset)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		reviews := []ReviewResponse{}
+		for rows.Next() {
+			var r ReviewResponse
+			if err := rows.Scan(&r.UUID, &r.Rating, &r.Comment, &r.ReviewerName, &r.CreatedAt); err != nil {
+				continue
+			}
+			reviews = append(reviews, r)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       reviews,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
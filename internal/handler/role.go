@@ -7,6 +7,9 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/queue"
+	utils "shopedia-api/internal/util"
 )
 
 // ============================================
@@ -18,6 +21,8 @@ type RoleResponse struct {
 	Name        string    `json:"name"`
 	Description *string   `json:"description"`
 	Scope       string    `json:"scope"`
+	MaxSessions int       `json:"max_sessions"`
+	MFARequired bool      `json:"mfa_required"`
 	IsSystem    bool      `json:"is_system"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -28,6 +33,8 @@ type RoleDetailResponse struct {
 	Name        string               `json:"name"`
 	Description *string              `json:"description"`
 	Scope       string               `json:"scope"`
+	MaxSessions int                  `json:"max_sessions"`
+	MFARequired bool                 `json:"mfa_required"`
 	IsSystem    bool                 `json:"is_system"`
 	Permissions []PermissionResponse `json:"permissions"`
 	CreatedAt   time.Time            `json:"created_at"`
@@ -88,7 +95,7 @@ func ListRolesHandler(db *pgxpool.Pool) fiber.Handler {
 		limitArg := argCount
 		argCount++
 		offsetArg := argCount
-		dataQuery := `SELECT uuid, name, description, scope, is_system, created_at, updated_at ` +
+		dataQuery := `SELECT uuid, name, description, scope, max_sessions, mfa_required, is_system, created_at, updated_at ` +
 			baseQuery + ` ORDER BY created_at ASC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
 		args = append(args, limit, offset)
 
@@ -102,7 +109,7 @@ func ListRolesHandler(db *pgxpool.Pool) fiber.Handler {
 		for rows.Next() {
 			var role RoleResponse
 			err := rows.Scan(&role.UUID, &role.Name, &role.Description,
-				&role.Scope, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+				&role.Scope, &role.MaxSessions, &role.MFARequired, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 			if err != nil {
 				continue
 			}
@@ -130,11 +137,11 @@ func GetRoleHandler(db *pgxpool.Pool) fiber.Handler {
 		var role RoleDetailResponse
 		var roleID int
 		err := db.QueryRow(ctx, `
-			SELECT id, uuid, name, description, scope, is_system, created_at, updated_at
+			SELECT id, uuid, name, description, scope, max_sessions, mfa_required, is_system, created_at, updated_at
 			FROM roles WHERE uuid = $1 AND deleted_at IS NULL`,
 			roleUUID).Scan(
 			&roleID, &role.UUID, &role.Name, &role.Description,
-			&role.Scope, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+			&role.Scope, &role.MaxSessions, &role.MFARequired, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 		if err != nil {
 			return fiber.ErrNotFound
 		}
@@ -171,6 +178,8 @@ func CreateRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			Name        string  `json:"name"`
 			Description *string `json:"description"`
 			Scope       string  `json:"scope"` // 'app' or 'dashboard'
+			MaxSessions *int    `json:"max_sessions"`
+			MFARequired bool    `json:"mfa_required"` // wajib 2FA untuk login dashboard
 		}
 		var input Input
 		if err := c.BodyParser(&input); err != nil {
@@ -185,6 +194,18 @@ func CreateRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Scope must be 'app' or 'dashboard'")
 		}
 
+		// Default batas session: 5 device untuk app, dashboard selalu single-session
+		maxSessions := 5
+		if input.Scope == "dashboard" {
+			maxSessions = 1
+		}
+		if input.MaxSessions != nil {
+			if *input.MaxSessions < 1 {
+				return fiber.NewError(fiber.StatusBadRequest, "max_sessions must be at least 1")
+			}
+			maxSessions = *input.MaxSessions
+		}
+
 		ctx := context.Background()
 
 		// Check if role name already exists
@@ -201,10 +222,10 @@ func CreateRoleHandler(db *pgxpool.Pool) fiber.Handler {
 		// Create role
 		var roleUUID string
 		err = db.QueryRow(ctx, `
-			INSERT INTO roles (name, description, scope, is_system)
-			VALUES ($1, $2, $3, FALSE)
+			INSERT INTO roles (name, description, scope, max_sessions, mfa_required, is_system)
+			VALUES ($1, $2, $3, $4, FALSE)
 			RETURNING uuid`,
-			input.Name, input.Description, input.Scope).Scan(&roleUUID)
+			input.Name, input.Description, input.Scope, maxSessions).Scan(&roleUUID)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
@@ -225,6 +246,8 @@ func UpdateRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			Name        *string `json:"name"`
 			Description *string `json:"description"`
 			Scope       *string `json:"scope"`
+			MaxSessions *int    `json:"max_sessions"`
+			MFARequired *bool   `json:"mfa_required"`
 		}
 		var input Input
 		if err := c.BodyParser(&input); err != nil {
@@ -284,6 +307,30 @@ func UpdateRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			}
 		}
 
+		if input.MaxSessions != nil {
+			if *input.MaxSessions < 1 {
+				return fiber.NewError(fiber.StatusBadRequest, "max_sessions must be at least 1")
+			}
+			_, err = db.Exec(ctx, `UPDATE roles SET max_sessions = $1, updated_at = NOW() WHERE id = $2`,
+				*input.MaxSessions, roleID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if input.MFARequired != nil {
+			_, err = db.Exec(ctx, `UPDATE roles SET mfa_required = $1, updated_at = NOW() WHERE id = $2`,
+				*input.MFARequired, roleID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		// Nama / scope role ikut di auth context semua user yang memilikinya
+		if input.Name != nil || input.Scope != nil {
+			utils.InvalidateAllAuthContexts()
+		}
+
 		return c.JSON(fiber.Map{
 			"message": "Role updated successfully",
 		})
@@ -383,6 +430,10 @@ func AssignPermissionsToRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			}
 		}
 
+		if assignedCount > 0 {
+			utils.InvalidateAllAuthContexts()
+		}
+
 		return c.JSON(fiber.Map{
 			"message":        "Permissions assigned successfully",
 			"assigned_count": assignedCount,
@@ -424,6 +475,8 @@ func RemovePermissionFromRoleHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.NewError(fiber.StatusNotFound, "Permission not assigned to this role")
 		}
 
+		utils.InvalidateAllAuthContexts()
+
 		return c.JSON(fiber.Map{
 			"message": "Permission removed from role successfully",
 		})
@@ -434,6 +487,15 @@ func RemovePermissionFromRoleHandler(db *pgxpool.Pool) fiber.Handler {
 // User-Role Assignment Handlers
 // ============================================
 
+// assignUserRole - tambah role ke user (idempotent); panggil utils.InvalidateAuthContext setelahnya
+func assignUserRole(ctx context.Context, q queue.Execer, userID, roleID, assignedBy int) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO user_roles (user_id, role_id, assigned_by)
+		VALUES ($1, $2, $3) ON CONFLICT (user_id, role_id) DO NOTHING`,
+		userID, roleID, assignedBy)
+	return err
+}
+
 // AssignRoleToUserHandler - POST /users/:uuid/roles - assign role to user
 func AssignRoleToUserHandler(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -467,14 +529,12 @@ func AssignRoleToUserHandler(db *pgxpool.Pool) fiber.Handler {
 		}
 
 		// Assign role
-		_, err = db.Exec(ctx, `
-			INSERT INTO user_roles (user_id, role_id, assigned_by)
-			VALUES ($1, $2, $3) ON CONFLICT (user_id, role_id) DO NOTHING`,
-			userID, roleID, assignedBy)
-		if err != nil {
+		if err := assignUserRole(ctx, db, userID, roleID, assignedBy); err != nil {
 			return fiber.ErrInternalServerError
 		}
 
+		utils.InvalidateAuthContext(userID)
+
 		return c.JSON(fiber.Map{
 			"message": "Role assigned to user successfully",
 		})
@@ -515,6 +575,8 @@ func RemoveRoleFromUserHandler(db *pgxpool.Pool) fiber.Handler {
 			return fiber.NewError(fiber.StatusNotFound, "Role not assigned to this user")
 		}
 
+		utils.InvalidateAuthContext(userID)
+
 		return c.JSON(fiber.Map{
 			"message": "Role removed from user successfully",
 		})
@@ -537,7 +599,7 @@ func GetUserRolesHandler(db *pgxpool.Pool) fiber.Handler {
 
 		// Get roles
 		rows, err := db.Query(ctx, `
-			SELECT r.uuid, r.name, r.description, r.scope, r.is_system, r.created_at, r.updated_at
+			SELECT r.uuid, r.name, r.description, r.scope, r.max_sessions, r.mfa_required, r.is_system, r.created_at, r.updated_at
 			FROM user_roles ur
 			JOIN roles r ON ur.role_id = r.id
 			WHERE ur.user_id = $1 AND r.deleted_at IS NULL
@@ -551,7 +613,7 @@ func GetUserRolesHandler(db *pgxpool.Pool) fiber.Handler {
 		for rows.Next() {
 			var role RoleResponse
 			if rows.Scan(&role.UUID, &role.Name, &role.Description,
-				&role.Scope, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt) == nil {
+				&role.Scope, &role.MaxSessions, &role.MFARequired, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt) == nil {
 				roles = append(roles, role)
 			}
 		}
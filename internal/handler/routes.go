@@ -7,9 +7,18 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Catatan: permission/role guard untuk /admin dipasang per-route, bukan lewat
+// admin.Group("").Use(...). Group("") memakai prefix yang sama, sehingga Use()
+// di sana ikut berlaku untuk semua route /admin yang didaftarkan setelahnya.
 func SetupRoutes(app *fiber.App, db *pgxpool.Pool) {
+	// Public key untuk verifikasi JWT oleh service lain
+	app.Get("/.well-known/jwks.json", JWKSHandler())
+
 	api := app.Group("/api")
 
+	// Seller integration routes (JWT atau API key) - harus sebelum route /app lain
+	SetupSellerIntegrationRoutes(api, db)
+
 	// Auth routes
 	SetupAppAuthRoutes(api, db)
 	SetupAdminAuthRoutes(api, db)
@@ -25,6 +34,30 @@ func SetupRoutes(app *fiber.App, db *pgxpool.Pool) {
 	SetupPublicRoutes(api, db)
 	SetupSellerRoutes(api, db)
 	SetupAdminProductRoutes(api, db)
+
+	// Cart & Order routes
+	SetupAppCartRoutes(api, db)
+	SetupAppOrderRoutes(api, db)
+	SetupAppReviewRoutes(api, db)
+
+	// Finance routes
+	SetupAdminFinanceRoutes(api, db)
+	SetupAppCreditRoutes(api, db)
+
+	// Promo routes
+	SetupAdminPromoRoutes(api, db)
+	SetupAppPromoRoutes(api, db)
+
+	// Banner routes
+	SetupAdminBannerRoutes(api, db)
+
+	// Support ticket routes
+	SetupAppTicketRoutes(api, db)
+	SetupAdminTicketRoutes(api, db)
+
+	// Seller onboarding routes
+	SetupAppSellerApplicationRoutes(api, db)
+	SetupAdminSellerApplicationRoutes(api, db)
 }
 
 // ============================================
@@ -37,15 +70,26 @@ func SetupAppAuthRoutes(api fiber.Router, db *pgxpool.Pool) {
 	api.Post("/app/verify-otp", middleware.StrictRateLimit(), VerifyOTPHandler(db))
 	api.Post("/app/request-new-otp", middleware.OTPRateLimit(), RequestNewOTPHandler(db))
 	api.Post("/app/login", middleware.StrictRateLimit(), LoginHandler(db, "app"))
+	api.Post("/app/refresh", RefreshTokenHandler(db, "app"))
 	api.Post("/app/forgot-password", middleware.StrictRateLimit(), ForgotPasswordHandler(db))
 	api.Post("/app/reset-password", middleware.StrictRateLimit(), ResetPasswordHandler(db))
 
+	// Undangan staff toko (token dari email)
+	api.Post("/app/shop-invites/accept", middleware.StrictRateLimit(), AcceptShopInviteHandler(db))
+
+	// Social login (OIDC)
+	api.Get("/app/oidc/providers", ListOIDCProvidersHandler())
+	api.Get("/app/oidc/:provider/authorize", middleware.StrictRateLimit(), OIDCAuthorizeHandler(db))
+	api.Post("/app/oidc/:provider/callback", middleware.StrictRateLimit(), OIDCCallbackHandler(db))
+
 	// Protected auth endpoints
 	appAuth := api.Group("/app")
 	appAuth.Use(middleware.JWTProtected(db))
 	appAuth.Post("/logout", LogoutHandler(db))
 	appAuth.Post("/logout-all", LogoutAllHandler(db))
 	appAuth.Post("/change-password", ChangePasswordHandler(db))
+	appAuth.Get("/sessions", ListMySessionsHandler(db))
+	appAuth.Delete("/sessions/:id", RevokeMySessionHandler(db))
 }
 
 // ============================================
@@ -72,22 +116,33 @@ func SetupAdminAuthRoutes(api fiber.Router, db *pgxpool.Pool) {
 	admin.Post("/register", AdminRegisterHandler(db))     // First time super_admin
 	admin.Post("/accept-invite", AcceptInviteHandler(db)) // Admin accept invite
 	admin.Post("/login", LoginHandler(db, "admin"))
+	admin.Post("/refresh", RefreshTokenHandler(db, "admin"))
+	admin.Post("/login/2fa", middleware.StrictRateLimit(), VerifyMFALoginHandler(db))
+	admin.Post("/login/2fa/setup", middleware.StrictRateLimit(), SetupMFALoginHandler(db))
+	admin.Post("/login/2fa/confirm", middleware.StrictRateLimit(), ConfirmMFALoginHandler(db))
 	admin.Post("/forgot-password", ForgotPasswordHandler(db))
 	admin.Post("/reset-password", ResetPasswordHandler(db))
 
-	// Protected auth endpoints (semua admin/super_admin)
+	// Protected auth endpoints (semua role dashboard: admin, finance, ops, ...)
 	adminAuth := admin.Group("")
 	adminAuth.Use(middleware.JWTProtected(db))
-	adminAuth.Use(middleware.RoleRequired(db, []string{"admin", "super_admin"}))
-	adminAuth.Post("/logout", LogoutHandler(db))
-	adminAuth.Post("/logout-all", LogoutAllHandler(db))
-	adminAuth.Post("/change-password", ChangePasswordHandler(db))
+	adminAuthGuard := middleware.ScopeRequired(db, "dashboard")
+	adminAuth.Post("/logout", adminAuthGuard, LogoutHandler(db))
+	adminAuth.Post("/logout-all", adminAuthGuard, LogoutAllHandler(db))
+	adminAuth.Post("/change-password", adminAuthGuard, ChangePasswordHandler(db))
+
+	// 2FA (TOTP) self-service
+	adminAuth.Get("/2fa", adminAuthGuard, GetMFAStatusHandler(db))
+	adminAuth.Post("/2fa/setup", adminAuthGuard, SetupMFAHandler(db))
+	adminAuth.Post("/2fa/confirm", adminAuthGuard, ConfirmMFAHandler(db))
+	adminAuth.Post("/2fa/recovery-codes", adminAuthGuard, RegenerateRecoveryCodesHandler(db))
+	adminAuth.Post("/2fa/disable", adminAuthGuard, DisableMFAHandler(db))
 
 	// Super admin only auth endpoints
 	superAdminAuth := admin.Group("")
 	superAdminAuth.Use(middleware.JWTProtected(db))
-	superAdminAuth.Use(middleware.RoleRequired(db, []string{"super_admin"}))
-	superAdminAuth.Post("/invite-user", InviteUserHandler(db))
+	superAdminAuthGuard := middleware.RoleRequired(db, []string{"super_admin"})
+	superAdminAuth.Post("/invite-user", superAdminAuthGuard, InviteUserHandler(db))
 }
 
 // ============================================
@@ -104,44 +159,37 @@ func SetupAdminUserRoutes(api fiber.Router, db *pgxpool.Pool) {
 	admin.Put("/me", UpdateProfileHandler(db))
 
 	// User management - requires user.* permissions
-	userMgmt := admin.Group("")
-	userMgmt.Use(middleware.PermissionRequired(db, []string{"user.view"}))
+	userMgmt := middleware.PermissionRequired(db, []string{"user.view"})
 
 	// Internal user management (exclude end_user)
-	userMgmt.Get("/users", ListUsersHandler(db))
-	userMgmt.Get("/users/:uuid", GetUserHandler(db))
-	userMgmt.Get("/users/:uuid/roles", GetUserRolesHandler(db))
+	admin.Get("/users", userMgmt, ListUsersHandler(db))
+	admin.Get("/users/:uuid", userMgmt, GetUserHandler(db))
+	admin.Get("/users/:uuid/roles", userMgmt, GetUserRolesHandler(db))
 
 	// User write operations
-	userWrite := admin.Group("")
-	userWrite.Use(middleware.PermissionRequired(db, []string{"user.update"}))
-	userWrite.Put("/users/:uuid", UpdateUserHandler(db))
+	userWrite := middleware.PermissionRequired(db, []string{"user.update"})
+	admin.Put("/users/:uuid", userWrite, UpdateUserHandler(db))
 
-	userDelete := admin.Group("")
-	userDelete.Use(middleware.PermissionRequired(db, []string{"user.delete"}))
-	userDelete.Delete("/users/:uuid", DeleteUserHandler(db))
+	userDelete := middleware.PermissionRequired(db, []string{"user.delete"})
+	admin.Delete("/users/:uuid", userDelete, DeleteUserHandler(db))
 
-	userActivate := admin.Group("")
-	userActivate.Use(middleware.PermissionRequired(db, []string{"user.activate"}))
-	userActivate.Post("/users/:uuid/activate", ActivateUserHandler(db))
-	userActivate.Post("/users/:uuid/deactivate", DeactivateUserHandler(db))
+	userActivate := middleware.PermissionRequired(db, []string{"user.activate"})
+	admin.Post("/users/:uuid/activate", userActivate, ActivateUserHandler(db))
+	admin.Post("/users/:uuid/deactivate", userActivate, DeactivateUserHandler(db))
 
 	// End user management (only end_user)
-	endUserMgmt := admin.Group("")
-	endUserMgmt.Use(middleware.PermissionRequired(db, []string{"user.view"}))
-	endUserMgmt.Get("/end-users", ListEndUsersHandler(db))
-	endUserMgmt.Get("/end-users/:uuid", GetEndUserHandler(db))
+	endUserMgmt := middleware.PermissionRequired(db, []string{"user.view"})
+	admin.Get("/end-users", endUserMgmt, ListEndUsersHandler(db))
+	admin.Get("/end-users/:uuid", endUserMgmt, GetEndUserHandler(db))
 
-	endUserBan := admin.Group("")
-	endUserBan.Use(middleware.PermissionRequired(db, []string{"user.ban"}))
-	endUserBan.Post("/end-users/:uuid/ban", BanEndUserHandler(db))
-	endUserBan.Post("/end-users/:uuid/unban", UnbanEndUserHandler(db))
+	endUserBan := middleware.PermissionRequired(db, []string{"user.ban"})
+	admin.Post("/end-users/:uuid/ban", endUserBan, BanEndUserHandler(db))
+	admin.Post("/end-users/:uuid/unban", endUserBan, UnbanEndUserHandler(db))
 
 	// Role assignment to user (super_admin only)
-	roleAssign := admin.Group("")
-	roleAssign.Use(middleware.PermissionRequired(db, []string{"role.assign"}))
-	roleAssign.Post("/users/:uuid/roles", AssignRoleToUserHandler(db))
-	roleAssign.Delete("/users/:uuid/roles/:role_uuid", RemoveRoleFromUserHandler(db))
+	roleAssign := middleware.PermissionRequired(db, []string{"role.assign"})
+	admin.Post("/users/:uuid/roles", roleAssign, AssignRoleToUserHandler(db))
+	admin.Delete("/users/:uuid/roles/:role_uuid", roleAssign, RemoveRoleFromUserHandler(db))
 }
 
 // ============================================
@@ -154,35 +202,31 @@ func SetupAdminRoleRoutes(api fiber.Router, db *pgxpool.Pool) {
 	admin.Use(middleware.ScopeRequired(db, "dashboard"))
 
 	// Role management - view
-	roleView := admin.Group("")
-	roleView.Use(middleware.PermissionRequired(db, []string{"role.view"}))
-	roleView.Get("/roles", ListRolesHandler(db))
-	roleView.Get("/roles/:uuid", GetRoleHandler(db))
+	roleView := middleware.PermissionRequired(db, []string{"role.view"})
+	admin.Get("/roles", roleView, ListRolesHandler(db))
+	admin.Get("/roles/:uuid", roleView, GetRoleHandler(db))
 
 	// Role management - write (super_admin only)
-	roleWrite := admin.Group("")
-	roleWrite.Use(middleware.RoleRequired(db, []string{"super_admin"}))
-	roleWrite.Post("/roles", CreateRoleHandler(db))
-	roleWrite.Put("/roles/:uuid", UpdateRoleHandler(db))
-	roleWrite.Delete("/roles/:uuid", DeleteRoleHandler(db))
+	roleWrite := middleware.RoleRequired(db, []string{"super_admin"})
+	admin.Post("/roles", roleWrite, CreateRoleHandler(db))
+	admin.Put("/roles/:uuid", roleWrite, UpdateRoleHandler(db))
+	admin.Delete("/roles/:uuid", roleWrite, DeleteRoleHandler(db))
 
 	// Role-Permission assignment (super_admin only)
-	roleWrite.Post("/roles/:uuid/permissions", AssignPermissionsToRoleHandler(db))
-	roleWrite.Delete("/roles/:uuid/permissions/:perm_uuid", RemovePermissionFromRoleHandler(db))
+	admin.Post("/roles/:uuid/permissions", roleWrite, AssignPermissionsToRoleHandler(db))
+	admin.Delete("/roles/:uuid/permissions/:perm_uuid", roleWrite, RemovePermissionFromRoleHandler(db))
 
 	// Permission management - view
-	permView := admin.Group("")
-	permView.Use(middleware.PermissionRequired(db, []string{"permission.view"}))
-	permView.Get("/permissions", ListPermissionsHandler(db))
-	permView.Get("/permissions/modules", ListPermissionModulesHandler(db))
-	permView.Get("/permissions/:uuid", GetPermissionHandler(db))
+	permView := middleware.PermissionRequired(db, []string{"permission.view"})
+	admin.Get("/permissions", permView, ListPermissionsHandler(db))
+	admin.Get("/permissions/modules", permView, ListPermissionModulesHandler(db))
+	admin.Get("/permissions/:uuid", permView, GetPermissionHandler(db))
 
 	// Permission management - write (super_admin only)
-	permWrite := admin.Group("")
-	permWrite.Use(middleware.RoleRequired(db, []string{"super_admin"}))
-	permWrite.Post("/permissions", CreatePermissionHandler(db))
-	permWrite.Put("/permissions/:uuid", UpdatePermissionHandler(db))
-	permWrite.Delete("/permissions/:uuid", DeletePermissionHandler(db))
+	permWrite := middleware.RoleRequired(db, []string{"super_admin"})
+	admin.Post("/permissions", permWrite, CreatePermissionHandler(db))
+	admin.Put("/permissions/:uuid", permWrite, UpdatePermissionHandler(db))
+	admin.Delete("/permissions/:uuid", permWrite, DeletePermissionHandler(db))
 }
 
 // ============================================
@@ -196,6 +240,14 @@ func SetupPublicRoutes(api fiber.Router, db *pgxpool.Pool) {
 	// Public products - no auth required
 	api.Get("/products", ListPublicProductsHandler(db))
 	api.Get("/products/:uuid", GetPublicProductHandler(db))
+	api.Get("/products/:uuid/reviews", ListProductReviewsHandler(db))
+
+	// Public shops (storefront) - no auth required
+	api.Get("/shops/:slug", GetPublicShopHandler(db))
+	api.Get("/shops/:slug/products", ListShopProductsHandler(db))
+
+	// Public banners - no auth required
+	api.Get("/banners", ListPublicBannersHandler(db))
 }
 
 // ============================================
@@ -207,12 +259,56 @@ func SetupSellerRoutes(api fiber.Router, db *pgxpool.Pool) {
 	seller.Use(middleware.JWTProtected(db))
 	seller.Use(middleware.RoleRequired(db, []string{"seller", "end_user"}))
 
+	// Akses toko lewat shop_members (owner atau staff dengan permission toko)
+	shopMember := shopAccess(db, "")
+	shopOwner := shopAccess(db, shopOwnerOnly)
+
+	// Seller shop profile
+	seller.Get("/shops", ListMyShopsHandler(db))
+	seller.Get("/shop", shopMember, GetMyShopHandler(db))
+	seller.Put("/shop", shopOwner, UpdateMyShopHandler(db))
+
+	// Shop staff (owner only)
+	seller.Get("/shop/members", shopOwner, ListShopMembersHandler(db))
+	seller.Post("/shop/members", shopOwner, InviteShopMemberHandler(db))
+	seller.Put("/shop/members/:uuid", shopOwner, UpdateShopMemberHandler(db))
+	seller.Delete("/shop/members/:uuid", shopOwner, RemoveShopMemberHandler(db))
+
+	// Seller orders (baris order berisi produk toko)
+	seller.Get("/orders", shopAccess(db, ShopPermOrderView), ListShopOrdersHandler(db))
+
+	// Seller payouts (request hanya owner, pemilik saldo)
+	seller.Get("/payouts", shopAccess(db, ShopPermWalletView), ListMyPayoutsHandler(db))
+	seller.Post("/payouts", shopOwner, RequestPayoutHandler(db))
+
+	// API key untuk integrasi (kelola hanya lewat login, bukan dengan API key)
+	seller.Get("/api-keys", ListMyAPIKeysHandler(db))
+	seller.Post("/api-keys", CreateMyAPIKeyHandler(db))
+	seller.Delete("/api-keys/:uuid", RevokeMyAPIKeyHandler(db))
+}
+
+// ============================================
+// Seller Integration Routes (JWT / API Key)
+// ============================================
+
+// Route di sini menerima "Authorization: Bearer <jwt>" maupun "Authorization: ApiKey <key>"
+// dengan scope per route. Didaftarkan sebelum group /app yang memakai JWTProtected,
+// karena Use() di group itu ikut berlaku untuk semua route /app sesudahnya.
+func SetupSellerIntegrationRoutes(api fiber.Router, db *pgxpool.Pool) {
+	sellerRole := middleware.RoleRequired(db, []string{"seller", "end_user"})
+	productAccess := shopAccess(db, ShopPermProductManage)
+	walletAccess := shopAccess(db, ShopPermWalletView)
+
 	// Seller product management
-	seller.Get("/products", ListSellerProductsHandler(db))
-	seller.Get("/products/:uuid", GetSellerProductHandler(db))
-	seller.Post("/products", CreateProductHandler(db))
-	seller.Put("/products/:uuid", UpdateProductHandler(db))
-	seller.Delete("/products/:uuid", DeleteProductHandler(db))
+	api.Get("/app/my/products", middleware.JWTOrAPIKey(db, "product.read"), sellerRole, productAccess, ListSellerProductsHandler(db))
+	api.Get("/app/my/products/:uuid", middleware.JWTOrAPIKey(db, "product.read"), sellerRole, productAccess, GetSellerProductHandler(db))
+	api.Post("/app/my/products", middleware.JWTOrAPIKey(db, "product.write"), sellerRole, productAccess, CreateProductHandler(db))
+	api.Put("/app/my/products/:uuid", middleware.JWTOrAPIKey(db, "product.write"), sellerRole, productAccess, UpdateProductHandler(db))
+	api.Delete("/app/my/products/:uuid", middleware.JWTOrAPIKey(db, "product.write"), sellerRole, productAccess, DeleteProductHandler(db))
+
+	// Seller wallet
+	api.Get("/app/my/wallet", middleware.JWTOrAPIKey(db, "wallet.read"), sellerRole, walletAccess, GetMyWalletHandler(db))
+	api.Get("/app/my/wallet/ledger", middleware.JWTOrAPIKey(db, "wallet.read"), sellerRole, walletAccess, ListMyLedgerHandler(db))
 }
 
 // ============================================
@@ -225,35 +321,273 @@ func SetupAdminProductRoutes(api fiber.Router, db *pgxpool.Pool) {
 	admin.Use(middleware.ScopeRequired(db, "dashboard"))
 
 	// Category management - view
-	catView := admin.Group("")
-	catView.Use(middleware.PermissionRequired(db, []string{"category.view"}))
-	catView.Get("/categories", ListCategoriesHandler(db))
-	catView.Get("/categories/:uuid", GetCategoryHandler(db))
+	catView := middleware.PermissionRequired(db, []string{"category.view"})
+	admin.Get("/categories", catView, ListCategoriesHandler(db))
+	admin.Get("/categories/:uuid", catView, GetCategoryHandler(db))
 
 	// Category management - create
-	catCreate := admin.Group("")
-	catCreate.Use(middleware.PermissionRequired(db, []string{"category.create"}))
-	catCreate.Post("/categories", CreateCategoryHandler(db))
+	catCreate := middleware.PermissionRequired(db, []string{"category.create"})
+	admin.Post("/categories", catCreate, CreateCategoryHandler(db))
 
 	// Category management - update
-	catUpdate := admin.Group("")
-	catUpdate.Use(middleware.PermissionRequired(db, []string{"category.update"}))
-	catUpdate.Put("/categories/:uuid", UpdateCategoryHandler(db))
+	catUpdate := middleware.PermissionRequired(db, []string{"category.update"})
+	admin.Put("/categories/:uuid", catUpdate, UpdateCategoryHandler(db))
 
 	// Category management - delete
-	catDelete := admin.Group("")
-	catDelete.Use(middleware.PermissionRequired(db, []string{"category.delete"}))
-	catDelete.Delete("/categories/:uuid", DeleteCategoryHandler(db))
+	catDelete := middleware.PermissionRequired(db, []string{"category.delete"})
+	admin.Delete("/categories/:uuid", catDelete, DeleteCategoryHandler(db))
 
 	// Product management - view
-	prodView := admin.Group("")
-	prodView.Use(middleware.PermissionRequired(db, []string{"product.view"}))
-	prodView.Get("/products", ListAdminProductsHandler(db))
-	prodView.Get("/products/:uuid", GetAdminProductHandler(db))
+	prodView := middleware.PermissionRequired(db, []string{"product.view"})
+	admin.Get("/products", prodView, ListAdminProductsHandler(db))
+	admin.Get("/products/:uuid", prodView, GetAdminProductHandler(db))
 
 	// Product management - moderate (block/unblock)
-	prodModerate := admin.Group("")
-	prodModerate.Use(middleware.PermissionRequired(db, []string{"product.moderate"}))
-	prodModerate.Post("/products/:uuid/block", BlockProductHandler(db))
-	prodModerate.Post("/products/:uuid/unblock", UnblockProductHandler(db))
+	prodModerate := middleware.PermissionRequired(db, []string{"product.moderate"})
+	admin.Post("/products/:uuid/block", prodModerate, BlockProductHandler(db))
+	admin.Post("/products/:uuid/unblock", prodModerate, UnblockProductHandler(db))
+
+	// Shop moderation (suspend/unsuspend)
+	admin.Post("/shops/:uuid/suspend", prodModerate, SuspendShopHandler(db))
+	admin.Post("/shops/:uuid/unsuspend", prodModerate, UnsuspendShopHandler(db))
+}
+
+// ============================================
+// Admin Finance Routes
+// ============================================
+
+func SetupAdminFinanceRoutes(api fiber.Router, db *pgxpool.Pool) {
+	admin := api.Group("/admin")
+	admin.Use(middleware.JWTProtected(db))
+	admin.Use(middleware.ScopeRequired(db, "dashboard"))
+
+	// Finance - view
+	financeView := middleware.PermissionRequired(db, []string{"finance.view"})
+	admin.Get("/wallets/reconcile", financeView, ReconcileBalancesHandler(db))
+	admin.Get("/finance/summary", financeView, FinanceSummaryHandler(db))
+
+	// Finance - export
+	financeExport := middleware.PermissionRequired(db, []string{"finance.export"})
+	admin.Get("/finance/export", financeExport, FinanceExportHandler(db))
+	admin.Get("/finance/exports/:uuid", financeExport, GetFinanceExportHandler(db))
+	admin.Get("/finance/exports/:uuid/download", financeExport, DownloadFinanceExportHandler(db))
+
+	// Payouts - view
+	payoutView := middleware.PermissionRequired(db, []string{"finance.view", "finance.payout"})
+	admin.Get("/payouts", payoutView, ListPayoutsHandler(db))
+	admin.Get("/payouts/:uuid", payoutView, GetPayoutHandler(db))
+
+	// Payouts - approve/reject
+	payoutProcess := middleware.PermissionRequired(db, []string{"finance.payout"})
+	admin.Post("/payouts/:uuid/approve", payoutProcess, ApprovePayoutHandler(db))
+	admin.Post("/payouts/:uuid/reject", payoutProcess, RejectPayoutHandler(db))
+
+	// Order payments (konfirmasi pembayaran, order pending -> paid)
+	payment := middleware.PermissionRequired(db, []string{"finance.payment"})
+	admin.Post("/orders/:uuid/confirm-payment", payment, ConfirmOrderPaymentHandler(db))
+
+	// Refunds
+	refund := middleware.PermissionRequired(db, []string{"finance.refund"})
+	admin.Post("/transactions/:uuid/refund", refund, RefundTransactionHandler(db))
+}
+
+// ============================================
+// App Buyer Credit Routes
+// ============================================
+
+func SetupAppCreditRoutes(api fiber.Router, db *pgxpool.Pool) {
+	credits := api.Group("/app/credits")
+	credits.Use(middleware.JWTProtected(db))
+	credits.Use(middleware.ScopeRequired(db, "app"))
+
+	credits.Get("", GetMyCreditsHandler(db))
+	credits.Get("/ledger", ListMyCreditLedgerHandler(db))
+}
+
+// ============================================
+// App Order Routes
+// ============================================
+
+func SetupAppOrderRoutes(api fiber.Router, db *pgxpool.Pool) {
+	orders := api.Group("/app/orders")
+	orders.Use(middleware.JWTProtected(db))
+	orders.Use(middleware.ScopeRequired(db, "app"))
+
+	orders.Post("", CheckoutHandler(db))
+	orders.Get("", ListMyOrdersHandler(db))
+	orders.Get("/:uuid", GetMyOrderHandler(db))
+	orders.Post("/:uuid/complete", CompleteOrderHandler(db))
+	orders.Post("/:uuid/cancel", CancelOrderHandler(db))
+}
+
+// ============================================
+// App Review Routes
+// ============================================
+
+func SetupAppReviewRoutes(api fiber.Router, db *pgxpool.Pool) {
+	reviews := api.Group("/app/reviews")
+	reviews.Use(middleware.JWTProtected(db))
+	reviews.Use(middleware.ScopeRequired(db, "app"))
+
+	reviews.Post("", CreateReviewHandler(db))
+}
+
+// ============================================
+// App Cart Routes
+// ============================================
+
+func SetupAppCartRoutes(api fiber.Router, db *pgxpool.Pool) {
+	cart := api.Group("/app/cart")
+	cart.Use(middleware.JWTProtected(db))
+	cart.Use(middleware.ScopeRequired(db, "app"))
+
+	cart.Get("", GetCartHandler(db))
+	cart.Delete("", ClearCartHandler(db))
+	cart.Post("/items", AddCartItemHandler(db))
+	cart.Put("/items/:uuid", UpdateCartItemHandler(db))
+	cart.Delete("/items/:uuid", RemoveCartItemHandler(db))
+}
+
+// ============================================
+// Admin Promo Routes
+// ============================================
+
+func SetupAdminPromoRoutes(api fiber.Router, db *pgxpool.Pool) {
+	admin := api.Group("/admin")
+	admin.Use(middleware.JWTProtected(db))
+	admin.Use(middleware.ScopeRequired(db, "dashboard"))
+
+	// Promo management - view
+	promoView := middleware.PermissionRequired(db, []string{"promo.view"})
+	admin.Get("/promos", promoView, ListPromosHandler(db))
+	admin.Get("/promos/:uuid", promoView, GetPromoHandler(db))
+
+	// Promo management - create
+	promoCreate := middleware.PermissionRequired(db, []string{"promo.create"})
+	admin.Post("/promos", promoCreate, CreatePromoHandler(db))
+
+	// Promo management - update
+	promoUpdate := middleware.PermissionRequired(db, []string{"promo.update"})
+	admin.Put("/promos/:uuid", promoUpdate, UpdatePromoHandler(db))
+
+	// Promo management - delete
+	promoDelete := middleware.PermissionRequired(db, []string{"promo.delete"})
+	admin.Delete("/promos/:uuid", promoDelete, DeletePromoHandler(db))
+}
+
+// ============================================
+// App Promo Routes
+// ============================================
+
+func SetupAppPromoRoutes(api fiber.Router, db *pgxpool.Pool) {
+	promos := api.Group("/app/promos")
+	promos.Use(middleware.JWTProtected(db))
+	promos.Use(middleware.ScopeRequired(db, "app"))
+
+	promos.Post("/validate", ValidatePromoHandler(db))
+}
+
+// ============================================
+// Admin Banner Routes
+// ============================================
+
+func SetupAdminBannerRoutes(api fiber.Router, db *pgxpool.Pool) {
+	admin := api.Group("/admin")
+	admin.Use(middleware.JWTProtected(db))
+	admin.Use(middleware.ScopeRequired(db, "dashboard"))
+
+	// Banner management - view
+	bannerView := middleware.PermissionRequired(db, []string{"banner.view"})
+	admin.Get("/banners", bannerView, ListBannersHandler(db))
+	admin.Get("/banners/:uuid", bannerView, GetBannerHandler(db))
+
+	// Banner management - create
+	bannerCreate := middleware.PermissionRequired(db, []string{"banner.create"})
+	admin.Post("/banners", bannerCreate, CreateBannerHandler(db))
+
+	// Banner management - update
+	bannerUpdate := middleware.PermissionRequired(db, []string{"banner.update"})
+	admin.Put("/banners/:uuid", bannerUpdate, UpdateBannerHandler(db))
+
+	// Banner management - delete
+	bannerDelete := middleware.PermissionRequired(db, []string{"banner.delete"})
+	admin.Delete("/banners/:uuid", bannerDelete, DeleteBannerHandler(db))
+}
+
+// ============================================
+// App Ticket Routes
+// ============================================
+
+func SetupAppTicketRoutes(api fiber.Router, db *pgxpool.Pool) {
+	tickets := api.Group("/app/tickets")
+	tickets.Use(middleware.JWTProtected(db))
+	tickets.Use(middleware.ScopeRequired(db, "app"))
+
+	tickets.Post("", CreateTicketHandler(db))
+	tickets.Get("", ListMyTicketsHandler(db))
+	tickets.Get("/:uuid", GetMyTicketHandler(db))
+	tickets.Post("/:uuid/messages", ReplyMyTicketHandler(db))
+}
+
+// ============================================
+// Admin Ticket Routes
+// ============================================
+
+func SetupAdminTicketRoutes(api fiber.Router, db *pgxpool.Pool) {
+	admin := api.Group("/admin")
+	admin.Use(middleware.JWTProtected(db))
+	admin.Use(middleware.ScopeRequired(db, "dashboard"))
+
+	// Support - view queue
+	supportView := middleware.PermissionRequired(db, []string{"support.view"})
+	admin.Get("/tickets", supportView, ListTicketsHandler(db))
+	admin.Get("/tickets/:uuid", supportView, GetTicketHandler(db))
+
+	// Support - respond (reply & assignment)
+	supportRespond := middleware.PermissionRequired(db, []string{"support.respond"})
+	admin.Post("/tickets/:uuid/messages", supportRespond, RespondTicketHandler(db))
+	admin.Post("/tickets/:uuid/assign", supportRespond, AssignTicketHandler(db))
+
+	// Support - escalate
+	supportEscalate := middleware.PermissionRequired(db, []string{"support.escalate"})
+	admin.Post("/tickets/:uuid/escalate", supportEscalate, EscalateTicketHandler(db))
+
+	// Support - close
+	supportClose := middleware.PermissionRequired(db, []string{"support.close"})
+	admin.Post("/tickets/:uuid/close", supportClose, CloseTicketHandler(db))
+}
+
+// ============================================
+// App Seller Application Routes
+// ============================================
+
+func SetupAppSellerApplicationRoutes(api fiber.Router, db *pgxpool.Pool) {
+	application := api.Group("/app/seller-application")
+	application.Use(middleware.JWTProtected(db))
+	application.Use(middleware.ScopeRequired(db, "app"))
+
+	application.Post("", SubmitSellerApplicationHandler(db))
+	application.Get("", GetMySellerApplicationHandler(db))
+	application.Put("", ResubmitSellerApplicationHandler(db))
+}
+
+// ============================================
+// Admin Seller Application Routes
+// ============================================
+
+func SetupAdminSellerApplicationRoutes(api fiber.Router, db *pgxpool.Pool) {
+	admin := api.Group("/admin")
+	admin.Use(middleware.JWTProtected(db))
+	admin.Use(middleware.ScopeRequired(db, "dashboard"))
+
+	// Seller applications - view queue
+	sellerView := middleware.PermissionRequired(db, []string{"seller.view"})
+	admin.Get("/seller-applications", sellerView, ListSellerApplicationsHandler(db))
+	admin.Get("/seller-applications/:uuid", sellerView, GetSellerApplicationHandler(db))
+
+	// Seller applications - review (KYC)
+	sellerReview := middleware.PermissionRequired(db, []string{"seller.review"})
+	admin.Post("/seller-applications/:uuid/approve", sellerReview, ApproveSellerApplicationHandler(db))
+	admin.Post("/seller-applications/:uuid/reject", sellerReview, RejectSellerApplicationHandler(db))
+	admin.Post("/seller-applications/:uuid/request-info", sellerReview, RequestSellerApplicationInfoHandler(db))
 }
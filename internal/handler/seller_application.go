@@ -0,0 +1,493 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/queue"
+	utils "shopedia-api/internal/util"
+)
+
+// ============================================
+// Seller Application Response Types
+// ============================================
+
+type SellerApplicationUser struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type SellerApplicationResponse struct {
+	UUID              string                 `json:"uuid"`
+	ShopName          string                 `json:"shop_name"`
+	ShopDescription   *string                `json:"shop_description"`
+	IDType            string                 `json:"id_type"`
+	IDNumber          string                 `json:"id_number"`
+	IDDocumentURL     string                 `json:"id_document_url"`
+	SelfieURL         string                 `json:"selfie_url"`
+	BankName          string                 `json:"bank_name"`
+	BankAccountNumber string                 `json:"bank_account_number"`
+	BankAccountName   string                 `json:"bank_account_name"`
+	Status            string                 `json:"status"` // pending, needs_info, approved, rejected
+	ReviewNote        *string                `json:"review_note"`
+	User              *SellerApplicationUser `json:"user,omitempty"` // hanya di dashboard
+	ReviewedAt        *time.Time             `json:"reviewed_at"`
+	SubmittedAt       time.Time              `json:"submitted_at"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+var sellerIDTypes = map[string]bool{"ktp": true, "passport": true, "kitas": true}
+
+const sellerApplicationColumns = `sa.uuid, sa.shop_name, sa.shop_description, sa.id_type, sa.id_number,
+	sa.id_document_url, sa.selfie_url, sa.bank_name, sa.bank_account_number, sa.bank_account_name,
+	sa.status, sa.review_note, sa.reviewed_at, sa.submitted_at, sa.created_at, sa.updated_at,
+	u.uuid, u.email, COALESCE(u.full_name, '')`
+
+// sellerApplicationInput - data pengajuan (submit & resubmit)
+type sellerApplicationInput struct {
+	ShopName          string  `json:"shop_name"`
+	ShopDescription   *string `json:"shop_description"`
+	IDType            string  `json:"id_type"`
+	IDNumber          string  `json:"id_number"`
+	IDDocumentURL     string  `json:"id_document_url"`
+	SelfieURL         string  `json:"selfie_url"`
+	BankName          string  `json:"bank_name"`
+	BankAccountNumber string  `json:"bank_account_number"`
+	BankAccountName   string  `json:"bank_account_name"`
+}
+
+func (in *sellerApplicationInput) validate() error {
+	in.ShopName = strings.TrimSpace(in.ShopName)
+	in.IDType = strings.ToLower(strings.TrimSpace(in.IDType))
+	in.IDNumber = strings.TrimSpace(in.IDNumber)
+
+	if in.ShopName == "" || len(in.ShopName) > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Shop name is required (max 100 characters)")
+	}
+	if !sellerIDTypes[in.IDType] {
+		return fiber.NewError(fiber.StatusBadRequest, "id_type must be one of: ktp, passport, kitas")
+	}
+	if in.IDNumber == "" || len(in.IDNumber) > 50 {
+		return fiber.NewError(fiber.StatusBadRequest, "ID number is required (max 50 characters)")
+	}
+	if in.IDDocumentURL == "" || in.SelfieURL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "ID document and selfie uploads are required")
+	}
+	if in.BankName == "" || in.BankAccountNumber == "" || in.BankAccountName == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Bank name, account number and account name are required")
+	}
+	return nil
+}
+
+// scanSellerApplication - scan baris hasil query sellerApplicationColumns, staff=false tanpa data user
+func scanSellerApplication(row pgx.Row, staff bool) (*SellerApplicationResponse, error) {
+	var a SellerApplicationResponse
+	var u SellerApplicationUser
+	err := row.Scan(&a.UUID, &a.ShopName, &a.ShopDescription, &a.IDType, &a.IDNumber,
+		&a.IDDocumentURL, &a.SelfieURL, &a.BankName, &a.BankAccountNumber, &a.BankAccountName,
+		&a.Status, &a.ReviewNote, &a.ReviewedAt, &a.SubmittedAt, &a.CreatedAt, &a.UpdatedAt,
+		&u.UUID, &u.Email, &u.Name)
+	if err != nil {
+		return nil, err
+	}
+	if staff {
+		a.User = &u
+	}
+	return &a, nil
+}
+
+// lockSellerApplication - lock pengajuan untuk direview, status harus salah satu dari allowed
+func lockSellerApplication(ctx context.Context, tx pgx.Tx, appUUID string, allowed ...string) (int, int, string, error) {
+	var appID, userID int
+	var shopName, status string
+	err := tx.QueryRow(ctx, `
+		SELECT id, user_id, shop_name, status FROM seller_applications WHERE uuid::TEXT = $1
+		FOR UPDATE`,
+		appUUID).Scan(&appID, &userID, &shopName, &status)
+	if err != nil {
+		return 0, 0, "", fiber.ErrNotFound
+	}
+
+	for _, s := range allowed {
+		if status == s {
+			return appID, userID, shopName, nil
+		}
+	}
+	return 0, 0, "", fiber.NewError(fiber.StatusConflict, "Application already "+status)
+}
+
+// notifyApplicant - notifikasi hasil review lewat outbox (ikut tx review)
+func notifyApplicant(ctx context.Context, tx pgx.Tx, userID int, title, message, notifType string) error {
+	task, err := queue.NewNotificationTask(userID, title, message, notifType)
+	if err != nil {
+		return err
+	}
+	return queue.AddToOutbox(ctx, tx, task, "default")
+}
+
+// ============================================
+// App Seller Application Handlers
+// ============================================
+
+// SubmitSellerApplicationHandler - POST /api/app/seller-application - ajukan diri jadi seller
+func SubmitSellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		var input sellerApplicationInput
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+		if err := input.validate(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		var isSeller, hasOpen bool
+		err := db.QueryRow(ctx, `
+			SELECT
+				EXISTS(SELECT 1 FROM user_roles ur JOIN roles r ON ur.role_id = r.id
+				       WHERE ur.user_id = $1 AND r.name = 'seller'),
+				EXISTS(SELECT 1 FROM seller_applications
+				       WHERE user_id = $1 AND status IN ('pending', 'needs_info'))`,
+			userID).Scan(&isSeller, &hasOpen)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if isSeller {
+			return fiber.NewError(fiber.StatusConflict, "You are already a seller")
+		}
+		if hasOpen {
+			return fiber.NewError(fiber.StatusConflict, "You already have an application in review")
+		}
+
+		var appUUID string
+		err = db.QueryRow(ctx, `
+			INSERT INTO seller_applications (user_id, shop_name, shop_description, id_type, id_number,
+				id_document_url, selfie_url, bank_name, bank_account_number, bank_account_name)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING uuid`,
+			userID, input.ShopName, input.ShopDescription, input.IDType, input.IDNumber,
+			input.IDDocumentURL, input.SelfieURL, input.BankName, input.BankAccountNumber, input.BankAccountName).Scan(&appUUID)
+		if err != nil {
+			// Index unik pengajuan terbuka (submit paralel)
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.ConstraintName == "idx_seller_applications_open" {
+				return fiber.NewError(fiber.StatusConflict, "You already have an application in review")
+			}
+			log.Printf("SubmitSellerApplication error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Seller application submitted",
+			"uuid":    appUUID,
+		})
+	}
+}
+
+// GetMySellerApplicationHandler - GET /api/app/seller-application - pengajuan terakhir user
+func GetMySellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		row := db.QueryRow(ctx, `
+			SELECT `+sellerApplicationColumns+`
+			FROM seller_applications sa JOIN users u ON sa.user_id = u.id
+			WHERE sa.user_id = $1
+			ORDER BY sa.created_at DESC, sa.id DESC
+			LIMIT 1`, userID)
+		app, err := scanSellerApplication(row, false)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "No seller application found")
+		}
+
+		return c.JSON(app)
+	}
+}
+
+// ResubmitSellerApplicationHandler - PUT /api/app/seller-application - lengkapi data yang diminta reviewer
+func ResubmitSellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		var input sellerApplicationInput
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+		if err := input.validate(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		tag, err := db.Exec(ctx, `
+			UPDATE seller_applications SET
+				shop_name = $2, shop_description = $3, id_type = $4, id_number = $5,
+				id_document_url = $6, selfie_url = $7, bank_name = $8, bank_account_number = $9, bank_account_name = $10,
+				status = 'pending', submitted_at = NOW(), updated_at = NOW()
+			WHERE user_id = $1 AND status = 'needs_info'`,
+			userID, input.ShopName, input.ShopDescription, input.IDType, input.IDNumber,
+			input.IDDocumentURL, input.SelfieURL, input.BankName, input.BankAccountNumber, input.BankAccountName)
+		if err != nil {
+			log.Printf("ResubmitSellerApplication error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+		if tag.RowsAffected() == 0 {
+			return fiber.NewError(fiber.StatusConflict, "No application waiting for more information")
+		}
+
+		return c.JSON(fiber.Map{"message": "Seller application resubmitted"})
+	}
+}
+
+// ============================================
+// Admin Seller Application Handlers (Dashboard)
+// ============================================
+
+// ListSellerApplicationsHandler - GET /seller-applications - antrian review (default pending, paling lama dulu)
+func ListSellerApplicationsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		baseQuery := `FROM seller_applications sa JOIN users u ON sa.user_id = u.id WHERE 1=1`
+		args := []interface{}{}
+		argCount := 0
+
+		if status := c.Query("status", "pending"); status != "all" {
+			argCount++
+			baseQuery += ` AND sa.status = $` + strconv.Itoa(argCount)
+			args = append(args, status)
+		}
+
+		if search := c.Query("search", ""); search != "" {
+			argCount++
+			baseQuery += ` AND (sa.shop_name ILIKE $` + strconv.Itoa(argCount) + ` OR u.email ILIKE $` + strconv.Itoa(argCount) + `)`
+			args = append(args, "%"+search+"%")
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT ` + sellerApplicationColumns + ` ` + baseQuery +
+			` ORDER BY sa.submitted_at ASC, sa.id ASC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		apps := []SellerApplicationResponse{}
+		for rows.Next() {
+			app, err := scanSellerApplication(rows, true)
+			if err != nil {
+				continue
+			}
+			apps = append(apps, *app)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       apps,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// GetSellerApplicationHandler - GET /seller-applications/:uuid - detail pengajuan + dokumen
+func GetSellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		row := db.QueryRow(ctx, `
+			SELECT `+sellerApplicationColumns+`
+			FROM seller_applications sa JOIN users u ON sa.user_id = u.id
+			WHERE sa.uuid::TEXT = $1`, c.Params("uuid"))
+		app, err := scanSellerApplication(row, true)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(app)
+	}
+}
+
+// ApproveSellerApplicationHandler - POST /seller-applications/:uuid/approve - user mendapat role seller
+func ApproveSellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewerID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		appID, userID, shopName, err := lockSellerApplication(ctx, tx, c.Params("uuid"), "pending")
+		if err != nil {
+			return err
+		}
+
+		var roleID int
+		err = tx.QueryRow(ctx, `SELECT id FROM roles WHERE name = 'seller' AND deleted_at IS NULL`).Scan(&roleID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Role 'seller' not found")
+		}
+
+		if err := assignUserRole(ctx, tx, userID, roleID, reviewerID); err != nil {
+			log.Printf("ApproveSellerApplication assign role error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		// Toko dari data pengajuan (toko yang sudah ada tetap dipakai)
+		var shopDescription *string
+		err = tx.QueryRow(ctx, `SELECT shop_description FROM seller_applications WHERE id = $1`, appID).Scan(&shopDescription)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if _, err := ensureShop(ctx, tx, userID, shopName, shopDescription); err != nil {
+			log.Printf("ApproveSellerApplication create shop error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE seller_applications SET status = 'approved', review_note = NULL, reviewed_by = $1, reviewed_at = NOW(), updated_at = NOW()
+			WHERE id = $2`, reviewerID, appID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		err = notifyApplicant(ctx, tx, userID, "Pengajuan seller disetujui",
+			"Toko "+shopName+" sudah aktif, Anda sekarang bisa mulai berjualan", "success")
+		if err != nil {
+			log.Printf("ApproveSellerApplication notification error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			log.Printf("ApproveSellerApplication commit error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		utils.InvalidateAuthContext(userID)
+
+		return c.JSON(fiber.Map{"message": "Seller application approved"})
+	}
+}
+
+// RejectSellerApplicationHandler - POST /seller-applications/:uuid/reject - tolak pengajuan
+func RejectSellerApplicationHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return reviewSellerApplication(c, db, "rejected")
+	}
+}
+
+// RequestSellerApplicationInfoHandler - POST /seller-applications/:uuid/request-info - minta user melengkapi data
+func RequestSellerApplicationInfoHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return reviewSellerApplication(c, db, "needs_info")
+	}
+}
+
+// reviewSellerApplication - reject / minta info tambahan, keduanya wajib dengan catatan untuk user
+func reviewSellerApplication(c *fiber.Ctx, db *pgxpool.Pool, status string) error {
+	reviewerID := c.Locals("userID").(int)
+
+	type Input struct {
+		Reason string `json:"reason"`
+	}
+	var input Input
+	if err := c.BodyParser(&input); err != nil {
+		return fiber.ErrBadRequest
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Reason is required")
+	}
+
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer tx.Rollback(ctx)
+
+	// Minta info hanya dari pengajuan pending; reject juga bisa saat menunggu user
+	allowed := []string{"pending"}
+	if status == "rejected" {
+		allowed = append(allowed, "needs_info")
+	}
+	appID, userID, shopName, err := lockSellerApplication(ctx, tx, c.Params("uuid"), allowed...)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE seller_applications SET status = $1, review_note = $2, reviewed_by = $3, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $4`, status, input.Reason, reviewerID, appID)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	title, message, notifType := "Pengajuan seller ditolak", "Pengajuan toko "+shopName+" ditolak: "+input.Reason, "warning"
+	if status == "needs_info" {
+		title, message, notifType = "Pengajuan seller perlu dilengkapi", "Mohon lengkapi pengajuan toko "+shopName+": "+input.Reason, "info"
+	}
+	if err := notifyApplicant(ctx, tx, userID, title, message, notifType); err != nil {
+		log.Printf("ReviewSellerApplication notification error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("ReviewSellerApplication commit error: %v", err)
+		return fiber.ErrInternalServerError
+	}
+
+	if status == "needs_info" {
+		return c.JSON(fiber.Map{"message": "More information requested"})
+	}
+	return c.JSON(fiber.Map{"message": "Seller application rejected"})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	utils "shopedia-api/internal/util"
+)
+
+// ============================================
+// Session Response Types
+// ============================================
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName *string   `json:"device_name"`
+	UserAgent  *string   `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"`
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ============================================
+// Session Handlers
+// ============================================
+
+// ListMySessionsHandler - GET /api/app/sessions - list device yang sedang login
+func ListMySessionsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		jti := c.Locals("jti").(string)
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT uuid, device_name, user_agent, ip_address, jti::TEXT = $2,
+				COALESCE(last_seen_at, created_at), created_at
+			FROM active_sessions
+			WHERE user_id = $1 AND mode = 'app'
+			ORDER BY COALESCE(last_seen_at, created_at) DESC`,
+			userID, jti)
+		if err != nil {
+			log.Printf("List sessions error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		sessions := []SessionResponse{}
+		for rows.Next() {
+			var s SessionResponse
+			if err := rows.Scan(&s.ID, &s.DeviceName, &s.UserAgent, &s.IPAddress, &s.Current,
+				&s.LastSeenAt, &s.CreatedAt); err != nil {
+				continue
+			}
+			sessions = append(sessions, s)
+		}
+
+		return c.JSON(fiber.Map{"data": sessions})
+	}
+}
+
+// RevokeMySessionHandler - DELETE /api/app/sessions/:id - logout satu device
+func RevokeMySessionHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		err := utils.RevokeSessionByUUID(ctx, db, userID, c.Params("id"))
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(fiber.Map{"message": "Session revoked"})
+	}
+}
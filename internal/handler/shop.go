@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Shop Response Types
+// ============================================
+
+type ShopResponse struct {
+	UUID          string    `json:"uuid"`
+	Name          string    `json:"name"`
+	Slug          string    `json:"slug"`
+	LogoURL       *string   `json:"logo_url"`
+	Description   *string   `json:"description"`
+	Location      *string   `json:"location"`
+	ProductCount  int       `json:"product_count"`
+	Status        string    `json:"status,omitempty"`
+	SuspendReason *string   `json:"suspend_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+// ensureShop - id toko milik ownerID, dibuat jika belum ada (nama kosong → nama user)
+func ensureShop(ctx context.Context, q querier, ownerID int, name string, description *string) (int, error) {
+	var shopID int
+	err := q.QueryRow(ctx, `SELECT id FROM shops WHERE owner_user_id = $1`, ownerID).Scan(&shopID)
+	if err == nil {
+		return shopID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		err := q.QueryRow(ctx, `SELECT COALESCE(NULLIF(full_name, ''), 'Toko') FROM users WHERE id = $1`, ownerID).Scan(&name)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if r := []rune(name); len(r) > 100 {
+		name = string(r[:100])
+	}
+
+	slug, err := uniqueShopSlug(ctx, q, generateSlug(name), 0)
+	if err != nil {
+		return 0, err
+	}
+
+	// Owner sekaligus dicatat sebagai anggota toko (akses /app/my lewat shop_members)
+	err = q.QueryRow(ctx, `
+		WITH s AS (
+			INSERT INTO shops (owner_user_id, name, slug, description)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (owner_user_id) DO NOTHING
+			RETURNING id
+		), m AS (
+			INSERT INTO shop_members (shop_id, user_id, role, status, joined_at)
+			SELECT id, $1, 'owner', 'active', NOW() FROM s
+		)
+		SELECT id FROM s`,
+		ownerID, name, slug, description).Scan(&shopID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Toko dibuat request lain di antara select dan insert
+		err = q.QueryRow(ctx, `SELECT id FROM shops WHERE owner_user_id = $1`, ownerID).Scan(&shopID)
+	}
+	return shopID, err
+}
+
+// uniqueShopSlug - slug yang belum dipakai toko lain (suffix -2, -3, ... jika bentrok)
+func uniqueShopSlug(ctx context.Context, q querier, base string, shopID int) (string, error) {
+	if base == "" {
+		base = "toko"
+	}
+	if len(base) > 100 {
+		base = strings.Trim(base[:100], "-")
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		var taken bool
+		err := q.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM shops WHERE slug = $1 AND id <> $2)`, slug, shopID).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// ============================================
+// Public Shop Handlers
+// ============================================
+
+// GetPublicShopHandler - GET /shops/:slug - halaman toko (public)
+func GetPublicShopHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		var s ShopResponse
+		err := db.QueryRow(ctx, `
+			SELECT s.uuid::TEXT, s.name, s.slug, s.logo_url, s.description, s.location,
+				(SELECT COUNT(*) FROM products p
+				 WHERE p.shop_id = s.id AND p.deleted_at IS NULL AND p.status = 'active' AND p.is_active = TRUE),
+				s.created_at
+			FROM shops s
+			WHERE s.slug = $1 AND s.status = 'active'`,
+			c.Params("slug")).Scan(&s.UUID, &s.Name, &s.Slug, &s.LogoURL, &s.Description, &s.Location,
+			&s.ProductCount, &s.CreatedAt)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return c.JSON(s)
+	}
+}
+
+// ListShopProductsHandler - GET /shops/:slug/products - produk aktif satu toko (public)
+func ListShopProductsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		var shopID int
+		err := db.QueryRow(ctx, `SELECT id FROM shops WHERE slug = $1 AND status = 'active'`,
+			c.Params("slug")).Scan(&shopID)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		return listPublicProducts(c, db, shopID)
+	}
+}
+
+// ============================================
+// Seller Shop Handlers (App)
+// ============================================
+
+// GetMyShopHandler - GET /my/shop - toko yang sedang diakses (owner / staff)
+func GetMyShopHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+		ctx := context.Background()
+
+		var s ShopResponse
+		err := db.QueryRow(ctx, `
+			SELECT s.uuid::TEXT, s.name, s.slug, s.logo_url, s.description, s.location,
+				(SELECT COUNT(*) FROM products p WHERE p.shop_id = s.id AND p.deleted_at IS NULL),
+				s.status, s.suspend_reason, s.created_at
+			FROM shops s
+			WHERE s.id = $1`,
+			shopID).Scan(&s.UUID, &s.Name, &s.Slug, &s.LogoURL, &s.Description, &s.Location,
+			&s.ProductCount, &s.Status, &s.SuspendReason, &s.CreatedAt)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Shop not found")
+		}
+
+		return c.JSON(s)
+	}
+}
+
+// UpdateMyShopHandler - PUT /my/shop - ubah profil toko (nama, slug, logo, deskripsi, lokasi), owner only
+func UpdateMyShopHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+
+		type Input struct {
+			Name        *string `json:"name"`
+			Slug        *string `json:"slug"`
+			LogoURL     *string `json:"logo_url"`
+			Description *string `json:"description"`
+			Location    *string `json:"location"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+		var err error
+
+		if input.Name != nil {
+			name := strings.TrimSpace(*input.Name)
+			if name == "" || len([]rune(name)) > 100 {
+				return fiber.NewError(fiber.StatusBadRequest, "Name is required (max 100 characters)")
+			}
+			_, err = db.Exec(ctx, `UPDATE shops SET name = $1, updated_at = NOW() WHERE id = $2`, name, shopID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if input.Slug != nil {
+			slug := generateSlug(*input.Slug)
+			if slug == "" || len(slug) > 120 {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid slug")
+			}
+			var taken bool
+			err = db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM shops WHERE slug = $1 AND id <> $2)`, slug, shopID).Scan(&taken)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			if taken {
+				return fiber.NewError(fiber.StatusConflict, "Slug already taken")
+			}
+			_, err = db.Exec(ctx, `UPDATE shops SET slug = $1, updated_at = NOW() WHERE id = $2`, slug, shopID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if input.LogoURL != nil {
+			_, err = db.Exec(ctx, `UPDATE shops SET logo_url = NULLIF($1, ''), updated_at = NOW() WHERE id = $2`, *input.LogoURL, shopID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if input.Description != nil {
+			_, err = db.Exec(ctx, `UPDATE shops SET description = NULLIF($1, ''), updated_at = NOW() WHERE id = $2`, *input.Description, shopID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		if input.Location != nil {
+			if len([]rune(*input.Location)) > 255 {
+				return fiber.NewError(fiber.StatusBadRequest, "Location max 255 characters")
+			}
+			_, err = db.Exec(ctx, `UPDATE shops SET location = NULLIF($1, ''), updated_at = NOW() WHERE id = $2`, *input.Location, shopID)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+		}
+
+		return c.JSON(fiber.Map{"message": "Shop updated successfully"})
+	}
+}
+
+// ============================================
+// Admin Shop Handlers (Dashboard)
+// ============================================
+
+// SuspendShopHandler - POST /admin/shops/:uuid/suspend - sembunyikan toko & produknya dari publik
+func SuspendShopHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			Reason string `json:"reason"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Reason == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Suspend reason is required")
+		}
+
+		ctx := context.Background()
+
+		var shopID, ownerID int
+		var name, status string
+		err := db.QueryRow(ctx, `SELECT id, owner_user_id, name, status FROM shops WHERE uuid::TEXT = $1`,
+			c.Params("uuid")).Scan(&shopID, &ownerID, &name, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status == "suspended" {
+			return fiber.NewError(fiber.StatusConflict, "Shop is already suspended")
+		}
+
+		_, err = db.Exec(ctx, `
+			UPDATE shops SET status = 'suspended', suspend_reason = $1, updated_at = NOW()
+			WHERE id = $2`, input.Reason, shopID)
+		if err != nil {
+			log.Printf("SuspendShop error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(ownerID, "Toko dinonaktifkan", "Toko "+name+" dinonaktifkan: "+input.Reason, "warning")
+
+		return c.JSON(fiber.Map{"message": "Shop suspended successfully"})
+	}
+}
+
+// UnsuspendShopHandler - POST /admin/shops/:uuid/unsuspend - aktifkan kembali toko
+func UnsuspendShopHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		var shopID, ownerID int
+		var name, status string
+		err := db.QueryRow(ctx, `SELECT id, owner_user_id, name, status FROM shops WHERE uuid::TEXT = $1`,
+			c.Params("uuid")).Scan(&shopID, &ownerID, &name, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status != "suspended" {
+			return fiber.NewError(fiber.StatusConflict, "Shop is not suspended")
+		}
+
+		_, err = db.Exec(ctx, `
+			UPDATE shops SET status = 'active', suspend_reason = NULL, updated_at = NOW()
+			WHERE id = $1`, shopID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(ownerID, "Toko aktif kembali", "Toko "+name+" sudah bisa diakses publik lagi", "success")
+
+		return c.JSON(fiber.Map{"message": "Shop unsuspended successfully"})
+	}
+}
@@ -0,0 +1,447 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+
+	"shopedia-api/internal/queue"
+	utils "shopedia-api/internal/util"
+)
+
+// Permission level toko untuk staff (owner otomatis punya semua)
+const (
+	ShopPermProductManage = "product.manage"
+	ShopPermOrderView     = "order.view"
+	ShopPermWalletView    = "wallet.view"
+
+	// shopOwnerOnly - dipakai di shopAccess untuk route yang hanya boleh diakses owner
+	shopOwnerOnly = "owner"
+)
+
+var shopPermissions = []string{ShopPermProductManage, ShopPermOrderView, ShopPermWalletView}
+
+// ============================================
+// Shop Member Response Types
+// ============================================
+
+type ShopMemberUser struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type ShopMemberResponse struct {
+	UUID        string         `json:"uuid"`
+	User        ShopMemberUser `json:"user"`
+	Role        string         `json:"role"`
+	Permissions []string       `json:"permissions"`
+	Status      string         `json:"status"`
+	InvitedAt   *time.Time     `json:"invited_at"`
+	JoinedAt    *time.Time     `json:"joined_at"`
+}
+
+type MyShopMembershipResponse struct {
+	Shop        ProductShop `json:"shop"`
+	Role        string      `json:"role"`
+	Permissions []string    `json:"permissions"`
+}
+
+// ============================================
+// Shop Access (middleware)
+// ============================================
+
+// shopAccess - tentukan toko yang sedang diakses dari keanggotaan user (header X-Shop-UUID
+// jika user anggota beberapa toko, default toko milik sendiri) dan cek permission level toko.
+// perm kosong = semua anggota aktif, shopOwnerOnly = owner saja.
+// c.Locals diisi shopID dan shopOwnerID untuk handler /app/my.
+func shopAccess(db *pgxpool.Pool, perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		shopUUID := c.Get("X-Shop-UUID")
+		ctx := context.Background()
+
+		var shopID, ownerID int
+		var role string
+		var permissions []string
+		err := db.QueryRow(ctx, `
+			SELECT s.id, s.owner_user_id, m.role, m.permissions
+			FROM shop_members m
+			JOIN shops s ON m.shop_id = s.id
+			WHERE m.user_id = $1 AND m.status = 'active' AND ($2 = '' OR s.uuid::TEXT = $2)
+			ORDER BY (m.role = 'owner') DESC, m.joined_at
+			LIMIT 1`,
+			userID, shopUUID).Scan(&shopID, &ownerID, &role, &permissions)
+		if errors.Is(err, pgx.ErrNoRows) && shopUUID == "" && hasRole(c, "seller") {
+			// Seller tanpa toko (mis. role diberikan manual dari dashboard): buat tokonya
+			shopID, err = ensureShop(ctx, db, userID, "", nil)
+			ownerID, role = userID, "owner"
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fiber.NewError(fiber.StatusForbidden, "Not a member of this shop")
+		}
+		if err != nil {
+			log.Printf("Shop access error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if role != "owner" && perm != "" {
+			if perm == shopOwnerOnly || !containsString(permissions, perm) {
+				return fiber.NewError(fiber.StatusForbidden, "Missing shop permission")
+			}
+		}
+
+		c.Locals("shopID", shopID)
+		c.Locals("shopOwnerID", ownerID)
+		return c.Next()
+	}
+}
+
+// hasRole - cek role dari c.Locals("roles") yang diisi JWTProtected / APIKeyProtected
+func hasRole(c *fiber.Ctx, role string) bool {
+	roles, _ := c.Locals("roles").([]string)
+	return containsString(roles, role)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validShopPermissions - normalisasi daftar permission staff (unik, hanya yang dikenal)
+func validShopPermissions(perms []string) ([]string, error) {
+	result := []string{}
+	for _, p := range perms {
+		if !containsString(shopPermissions, p) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid permission: "+p)
+		}
+		if !containsString(result, p) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// ============================================
+// Seller Shop Member Handlers (App)
+// ============================================
+
+// ListMyShopsHandler - GET /my/shops - toko tempat user menjadi owner / staff
+func ListMyShopsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT s.uuid::TEXT, s.name, s.slug, s.logo_url, s.location, m.role, m.permissions
+			FROM shop_members m
+			JOIN shops s ON m.shop_id = s.id
+			WHERE m.user_id = $1 AND m.status = 'active'
+			ORDER BY (m.role = 'owner') DESC, m.joined_at`, userID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		shops := []MyShopMembershipResponse{}
+		for rows.Next() {
+			var m MyShopMembershipResponse
+			err := rows.Scan(&m.Shop.UUID, &m.Shop.Name, &m.Shop.Slug, &m.Shop.LogoURL, &m.Shop.Location,
+				&m.Role, &m.Permissions)
+			if err != nil {
+				continue
+			}
+			if m.Role == "owner" {
+				m.Permissions = shopPermissions
+			}
+			shops = append(shops, m)
+		}
+
+		return c.JSON(fiber.Map{"data": shops})
+	}
+}
+
+// ListShopMembersHandler - GET /my/shop/members - owner + staff toko (owner only)
+func ListShopMembersHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			SELECT m.uuid::TEXT, u.uuid, u.email, COALESCE(u.full_name, ''),
+				m.role, m.permissions, m.status, m.invited_at, m.joined_at
+			FROM shop_members m
+			JOIN users u ON m.user_id = u.id
+			WHERE m.shop_id = $1
+			ORDER BY (m.role = 'owner') DESC, m.created_at`, shopID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		members := []ShopMemberResponse{}
+		for rows.Next() {
+			var m ShopMemberResponse
+			err := rows.Scan(&m.UUID, &m.User.UUID, &m.User.Email, &m.User.Name,
+				&m.Role, &m.Permissions, &m.Status, &m.InvitedAt, &m.JoinedAt)
+			if err != nil {
+				continue
+			}
+			if m.Role == "owner" {
+				m.Permissions = shopPermissions
+			}
+			members = append(members, m)
+		}
+
+		return c.JSON(fiber.Map{"data": members})
+	}
+}
+
+// InviteShopMemberHandler - POST /my/shop/members - undang staff lewat email (owner only).
+// Email yang belum terdaftar dibuatkan user invited seperti InviteUserHandler.
+func InviteShopMemberHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		shopID := c.Locals("shopID").(int)
+
+		type Input struct {
+			Email       string   `json:"email"`
+			Permissions []string `json:"permissions"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+		if input.Email == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Email is required")
+		}
+		permissions, err := validShopPermissions(input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		// User, membership, invite token dan outbox email ditulis dalam satu transaksi
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var shopName string
+		if err := tx.QueryRow(ctx, `SELECT name FROM shops WHERE id = $1`, shopID).Scan(&shopName); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		var memberUserID int
+		err = tx.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, input.Email).Scan(&memberUserID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.QueryRow(ctx,
+				`INSERT INTO users (email, is_active, is_invited, invited_at) VALUES ($1, FALSE, TRUE, NOW()) RETURNING id`,
+				input.Email).Scan(&memberUserID)
+		}
+		if err != nil {
+			log.Printf("InviteShopMember user error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		// Undangan ulang ke anggota yang belum menerima hanya memperbarui permission
+		var memberID int
+		var memberUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO shop_members (shop_id, user_id, role, permissions, status, invited_by, invited_at)
+			VALUES ($1, $2, 'staff', $3, 'invited', $4, NOW())
+			ON CONFLICT (shop_id, user_id) DO UPDATE SET
+				permissions = EXCLUDED.permissions, invited_by = EXCLUDED.invited_by,
+				invited_at = NOW(), updated_at = NOW()
+			WHERE shop_members.status = 'invited'
+			RETURNING id, uuid::TEXT`,
+			shopID, memberUserID, permissions, userID).Scan(&memberID, &memberUUID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fiber.NewError(fiber.StatusConflict, "User is already a member of this shop")
+		}
+		if err != nil {
+			log.Printf("InviteShopMember insert error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		inviteToken := uuid.New().String()
+		expires := time.Now().Add(24 * time.Hour)
+		_, err = tx.Exec(ctx, `
+			INSERT INTO invite_tokens (user_id, token, expires_at, shop_member_id) VALUES ($1, $2, $3, $4)`,
+			memberUserID, inviteToken, expires, memberID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "invite_tokens insert failed")
+		}
+
+		inviteLink := fmt.Sprintf("%s/shop-invite?token=%s", os.Getenv("FRONTEND_URL"), inviteToken)
+		task, err := queue.NewSendShopInviteTask(input.Email, shopName, inviteLink)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if err := queue.AddToOutbox(ctx, tx, task, "default"); err != nil {
+			log.Printf("InviteShopMember outbox error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Invite sent",
+			"uuid":    memberUUID,
+		})
+	}
+}
+
+// UpdateShopMemberHandler - PUT /my/shop/members/:uuid - ubah permission staff (owner only)
+func UpdateShopMemberHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+
+		type Input struct {
+			Permissions []string `json:"permissions"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		permissions, err := validShopPermissions(input.Permissions)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		tag, err := db.Exec(ctx, `
+			UPDATE shop_members SET permissions = $1, updated_at = NOW()
+			WHERE uuid::TEXT = $2 AND shop_id = $3 AND role = 'staff'`,
+			permissions, c.Params("uuid"), shopID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if tag.RowsAffected() == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Staff member not found")
+		}
+
+		return c.JSON(fiber.Map{"message": "Staff permissions updated"})
+	}
+}
+
+// RemoveShopMemberHandler - DELETE /my/shop/members/:uuid - keluarkan staff / batalkan undangan (owner only)
+func RemoveShopMemberHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shopID := c.Locals("shopID").(int)
+		ctx := context.Background()
+
+		// Invite token yang belum dipakai ikut terhapus (ON DELETE CASCADE)
+		tag, err := db.Exec(ctx, `DELETE FROM shop_members WHERE uuid::TEXT = $1 AND shop_id = $2 AND role = 'staff'`,
+			c.Params("uuid"), shopID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		if tag.RowsAffected() == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Staff member not found")
+		}
+
+		return c.JSON(fiber.Map{"message": "Staff member removed"})
+	}
+}
+
+// AcceptShopInviteHandler - POST /app/shop-invites/accept - terima undangan staff toko.
+// Akun baru (dibuat saat invite) wajib mengatur password; akun lama cukup token.
+func AcceptShopInviteHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Input struct {
+			InviteToken string `json:"invite_token"`
+			Password    string `json:"password"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		ctx := context.Background()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var tokenID, userID, memberID int
+		var hasPassword bool
+		err = tx.QueryRow(ctx, `
+			SELECT it.id, it.user_id, it.shop_member_id, u.password_hash IS NOT NULL
+			FROM invite_tokens it
+			JOIN users u ON it.user_id = u.id
+			WHERE it.token::TEXT = $1 AND it.is_used = FALSE AND it.expires_at > NOW() AND it.shop_member_id IS NOT NULL
+			FOR UPDATE OF it`,
+			input.InviteToken).Scan(&tokenID, &userID, &memberID, &hasPassword)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired invite token")
+		}
+
+		if !hasPassword {
+			if len(input.Password) < 8 {
+				return fiber.NewError(fiber.StatusBadRequest, "Password must be at least 8 characters")
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return fiber.ErrInternalServerError
+			}
+			_, err = tx.Exec(ctx, `
+				UPDATE users SET password_hash = $1, is_active = TRUE,
+					email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+				WHERE id = $2`,
+				string(hash), userID)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Update user password failed")
+			}
+		}
+
+		// Staff login lewat app, jadi butuh role scope app
+		if err := assignEndUserRole(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE shop_members SET status = 'active', joined_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, memberID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE invite_tokens SET is_used = TRUE WHERE id = $1`, tokenID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Update invite_token failed")
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		utils.InvalidateAuthContext(userID)
+
+		return c.JSON(fiber.Map{"message": "Invite accepted, you can now manage the shop"})
+	}
+}
@@ -0,0 +1,591 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Ticket Response Types
+// ============================================
+
+type TicketUser struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type TicketMessageResponse struct {
+	UUID       string    `json:"uuid"`
+	SenderName string    `json:"sender_name"`
+	IsStaff    bool      `json:"is_staff"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type TicketResponse struct {
+	UUID             string                  `json:"uuid"`
+	Subject          string                  `json:"subject"`
+	Status           string                  `json:"status"`   // open, in_progress, escalated, closed
+	Priority         string                  `json:"priority"` // normal, high
+	TransactionUUID  *string                 `json:"transaction_uuid"`
+	ProductUUID      *string                 `json:"product_uuid"`
+	User             *TicketUser             `json:"user,omitempty"`        // hanya di dashboard
+	AssignedTo       *TicketUser             `json:"assigned_to,omitempty"` // hanya di dashboard
+	EscalationReason *string                 `json:"escalation_reason,omitempty"`
+	EscalatedAt      *time.Time              `json:"escalated_at,omitempty"`
+	ClosedAt         *time.Time              `json:"closed_at"`
+	LastMessageAt    time.Time               `json:"last_message_at"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+	Messages         []TicketMessageResponse `json:"messages,omitempty"`
+}
+
+// ============================================
+// Helper Functions
+// ============================================
+
+const ticketColumns = `tk.id, tk.uuid, tk.subject, tk.status, tk.priority, t.uuid::TEXT, p.uuid::TEXT,
+	u.uuid, u.email, COALESCE(u.full_name, ''), a.uuid::TEXT, a.email, a.full_name,
+	tk.escalation_reason, tk.escalated_at, tk.closed_at, tk.last_message_at, tk.created_at, tk.updated_at`
+
+const ticketJoins = `FROM tickets tk
+	JOIN users u ON tk.user_id = u.id
+	LEFT JOIN users a ON tk.assigned_to = a.id
+	LEFT JOIN transactions t ON tk.transaction_id = t.id
+	LEFT JOIN products p ON tk.product_id = p.id`
+
+// scanTicket - scan baris hasil query ticketColumns, staff=false menyembunyikan data internal
+func scanTicket(row pgx.Row, staff bool) (int, *TicketResponse, error) {
+	var ticketID int
+	var tk TicketResponse
+	var user TicketUser
+	var assigneeUUID, assigneeEmail, assigneeName *string
+	err := row.Scan(&ticketID, &tk.UUID, &tk.Subject, &tk.Status, &tk.Priority, &tk.TransactionUUID, &tk.ProductUUID,
+		&user.UUID, &user.Email, &user.Name, &assigneeUUID, &assigneeEmail, &assigneeName,
+		&tk.EscalationReason, &tk.EscalatedAt, &tk.ClosedAt, &tk.LastMessageAt, &tk.CreatedAt, &tk.UpdatedAt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if staff {
+		tk.User = &user
+		if assigneeUUID != nil {
+			tk.AssignedTo = &TicketUser{UUID: *assigneeUUID, Email: *assigneeEmail}
+			if assigneeName != nil {
+				tk.AssignedTo.Name = *assigneeName
+			}
+		}
+	} else {
+		tk.EscalationReason = nil
+		tk.EscalatedAt = nil
+	}
+
+	return ticketID, &tk, nil
+}
+
+// getTicketMessages - thread pesan tiket, urut dari yang paling lama
+func getTicketMessages(ctx context.Context, db *pgxpool.Pool, ticketID int) ([]TicketMessageResponse, error) {
+	rows, err := db.Query(ctx, `
+		SELECT m.uuid, COALESCE(u.full_name, ''), m.is_staff, m.message, m.created_at
+		FROM ticket_messages m
+		JOIN users u ON m.sender_user_id = u.id
+		WHERE m.ticket_id = $1
+		ORDER BY m.created_at, m.id`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []TicketMessageResponse{}
+	for rows.Next() {
+		var m TicketMessageResponse
+		if err := rows.Scan(&m.UUID, &m.SenderName, &m.IsStaff, &m.Message, &m.CreatedAt); err != nil {
+			continue
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+// listTickets - query paginated tiket, ownerID 0 = antrian support (dashboard)
+func listTickets(c *fiber.Ctx, db *pgxpool.Pool, ownerID int) error {
+	ctx := context.Background()
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	status := c.Query("status", "")
+	staff := ownerID == 0
+
+	baseQuery := ticketJoins + ` WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	if !staff {
+		argCount++
+		baseQuery += ` AND tk.user_id = $` + strconv.Itoa(argCount)
+		args = append(args, ownerID)
+	}
+
+	if status != "" {
+		argCount++
+		baseQuery += ` AND tk.status = $` + strconv.Itoa(argCount)
+		args = append(args, status)
+	}
+
+	if staff {
+		if priority := c.Query("priority", ""); priority != "" {
+			argCount++
+			baseQuery += ` AND tk.priority = $` + strconv.Itoa(argCount)
+			args = append(args, priority)
+		}
+
+		// assigned: me, unassigned, atau uuid staff
+		switch assigned := c.Query("assigned", ""); assigned {
+		case "":
+		case "unassigned":
+			baseQuery += ` AND tk.assigned_to IS NULL`
+		case "me":
+			argCount++
+			baseQuery += ` AND tk.assigned_to = $` + strconv.Itoa(argCount)
+			args = append(args, c.Locals("userID").(int))
+		default:
+			argCount++
+			baseQuery += ` AND a.uuid::TEXT = $` + strconv.Itoa(argCount)
+			args = append(args, assigned)
+		}
+	}
+
+	var totalItems int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	argCount++
+	limitArg := argCount
+	argCount++
+	offsetArg := argCount
+	args = append(args, limit, offset)
+
+	// Antrian support: escalated & high priority di atas, lalu yang paling lama menunggu
+	orderBy := ` ORDER BY tk.last_message_at DESC`
+	if staff {
+		orderBy = ` ORDER BY (tk.status = 'escalated') DESC, (tk.priority = 'high') DESC, tk.last_message_at ASC`
+	}
+
+	dataQuery := `SELECT ` + ticketColumns + ` ` + baseQuery + orderBy +
+		` LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+	rows, err := db.Query(ctx, dataQuery, args...)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+	defer rows.Close()
+
+	tickets := []TicketResponse{}
+	for rows.Next() {
+		_, tk, err := scanTicket(rows, staff)
+		if err != nil {
+			continue
+		}
+		tickets = append(tickets, *tk)
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+
+	return c.JSON(PaginatedResponse{
+		Data:       tickets,
+		Page:       page,
+		Limit:      limit,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+	})
+}
+
+// addTicketMessage - simpan pesan dan update last_message_at tiket
+func addTicketMessage(ctx context.Context, db *pgxpool.Pool, ticketID, senderID int, isStaff bool, message string) (string, error) {
+	var messageUUID string
+	err := db.QueryRow(ctx, `
+		INSERT INTO ticket_messages (ticket_id, sender_user_id, is_staff, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING uuid`,
+		ticketID, senderID, isStaff, message).Scan(&messageUUID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(ctx, `UPDATE tickets SET last_message_at = NOW(), updated_at = NOW() WHERE id = $1`, ticketID)
+	return messageUUID, err
+}
+
+// ============================================
+// App Ticket Handlers
+// ============================================
+
+// CreateTicketHandler - POST /tickets - buka tiket baru (opsional terkait transaksi/produk)
+func CreateTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Subject         string  `json:"subject"`
+			Message         string  `json:"message"`
+			TransactionUUID *string `json:"transaction_uuid"`
+			ProductUUID     *string `json:"product_uuid"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Subject == "" || input.Message == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Subject and message are required")
+		}
+
+		ctx := context.Background()
+
+		// Transaksi harus milik user (sebagai buyer atau seller)
+		var transactionID *int
+		if input.TransactionUUID != nil && *input.TransactionUUID != "" {
+			var id int
+			err := db.QueryRow(ctx, `
+				SELECT id FROM transactions
+				WHERE uuid = $1 AND (buyer_user_id = $2 OR seller_user_id = $2)`,
+				*input.TransactionUUID, userID).Scan(&id)
+			if err != nil {
+				return fiber.NewError(fiber.StatusNotFound, "Transaction not found")
+			}
+			transactionID = &id
+		}
+
+		var productID *int
+		if input.ProductUUID != nil && *input.ProductUUID != "" {
+			var id int
+			err := db.QueryRow(ctx, `SELECT id FROM products WHERE uuid = $1 AND deleted_at IS NULL`,
+				*input.ProductUUID).Scan(&id)
+			if err != nil {
+				return fiber.NewError(fiber.StatusNotFound, "Product not found")
+			}
+			productID = &id
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer tx.Rollback(ctx)
+
+		var ticketID int
+		var ticketUUID string
+		err = tx.QueryRow(ctx, `
+			INSERT INTO tickets (user_id, subject, transaction_id, product_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, uuid`,
+			userID, input.Subject, transactionID, productID).Scan(&ticketID, &ticketUUID)
+		if err != nil {
+			log.Printf("CreateTicket error: %v", err)
+			return fiber.ErrInternalServerError
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO ticket_messages (ticket_id, sender_user_id, is_staff, message)
+			VALUES ($1, $2, FALSE, $3)`,
+			ticketID, userID, input.Message)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Ticket created successfully",
+			"uuid":    ticketUUID,
+		})
+	}
+}
+
+// ListMyTicketsHandler - GET /tickets - list tiket milik user
+func ListMyTicketsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listTickets(c, db, c.Locals("userID").(int))
+	}
+}
+
+// GetMyTicketHandler - GET /tickets/:uuid - detail tiket + thread pesan
+func GetMyTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		row := db.QueryRow(ctx, `SELECT `+ticketColumns+` `+ticketJoins+` WHERE tk.uuid = $1 AND tk.user_id = $2`,
+			c.Params("uuid"), userID)
+		ticketID, tk, err := scanTicket(row, false)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		tk.Messages, err = getTicketMessages(ctx, db, ticketID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(tk)
+	}
+}
+
+// ReplyMyTicketHandler - POST /tickets/:uuid/messages - balas tiket sendiri
+func ReplyMyTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("userID").(int)
+
+		type Input struct {
+			Message string `json:"message"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Message == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Message is required")
+		}
+
+		ctx := context.Background()
+
+		var ticketID int
+		var status string
+		err := db.QueryRow(ctx, `SELECT id, status FROM tickets WHERE uuid = $1 AND user_id = $2`,
+			c.Params("uuid"), userID).Scan(&ticketID, &status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status == "closed" {
+			return fiber.NewError(fiber.StatusConflict, "Ticket is closed")
+		}
+
+		messageUUID, err := addTicketMessage(ctx, db, ticketID, userID, false, input.Message)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Reply sent successfully",
+			"uuid":    messageUUID,
+		})
+	}
+}
+
+// ============================================
+// Admin Ticket Handlers (Dashboard)
+// ============================================
+
+// ListTicketsHandler - GET /tickets - antrian tiket support
+func ListTicketsHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listTickets(c, db, 0)
+	}
+}
+
+// GetTicketHandler - GET /tickets/:uuid - detail tiket + thread pesan
+func GetTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		row := db.QueryRow(ctx, `SELECT `+ticketColumns+` `+ticketJoins+` WHERE tk.uuid = $1`, c.Params("uuid"))
+		ticketID, tk, err := scanTicket(row, true)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		tk.Messages, err = getTicketMessages(ctx, db, ticketID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(tk)
+	}
+}
+
+// RespondTicketHandler - POST /tickets/:uuid/messages - balasan staff, tiket open menjadi in_progress
+func RespondTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		staffID := c.Locals("userID").(int)
+
+		type Input struct {
+			Message string `json:"message"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Message == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Message is required")
+		}
+
+		ctx := context.Background()
+
+		var ticketID, ownerID int
+		var status, subject string
+		err := db.QueryRow(ctx, `SELECT id, user_id, status, subject FROM tickets WHERE uuid = $1`,
+			c.Params("uuid")).Scan(&ticketID, &ownerID, &status, &subject)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status == "closed" {
+			return fiber.NewError(fiber.StatusConflict, "Ticket is closed")
+		}
+
+		messageUUID, err := addTicketMessage(ctx, db, ticketID, staffID, true, input.Message)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		// Staff pertama yang membalas otomatis menjadi assignee
+		_, err = db.Exec(ctx, `
+			UPDATE tickets SET status = CASE WHEN status = 'open' THEN 'in_progress' ELSE status END,
+				assigned_to = COALESCE(assigned_to, $1), updated_at = NOW()
+			WHERE id = $2`, staffID, ticketID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		notifyUser(ownerID, "Balasan tiket", "Tim support membalas tiket Anda: "+subject, "info")
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Reply sent successfully",
+			"uuid":    messageUUID,
+		})
+	}
+}
+
+// AssignTicketHandler - POST /tickets/:uuid/assign - assign tiket ke staff (default: diri sendiri)
+func AssignTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		staffID := c.Locals("userID").(int)
+
+		type Input struct {
+			UserUUID string `json:"user_uuid"`
+		}
+		var input Input
+		c.BodyParser(&input)
+
+		ctx := context.Background()
+
+		assigneeID := staffID
+		if input.UserUUID != "" {
+			// Assignee harus user dashboard yang aktif
+			err := db.QueryRow(ctx, `
+				SELECT u.id FROM users u
+				WHERE u.uuid = $1 AND u.deleted_at IS NULL AND u.is_active = TRUE
+					AND EXISTS (
+						SELECT 1 FROM user_roles ur JOIN roles r ON ur.role_id = r.id
+						WHERE ur.user_id = u.id AND r.scope = 'dashboard' AND r.deleted_at IS NULL
+					)`,
+				input.UserUUID).Scan(&assigneeID)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Assignee must be an active dashboard user")
+			}
+		}
+
+		result, err := db.Exec(ctx, `
+			UPDATE tickets SET assigned_to = $1,
+				status = CASE WHEN status = 'open' THEN 'in_progress' ELSE status END, updated_at = NOW()
+			WHERE uuid = $2 AND status != 'closed'`,
+			assigneeID, c.Params("uuid"))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		if result.RowsAffected() == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Ticket not found or already closed")
+		}
+
+		return c.JSON(fiber.Map{"message": "Ticket assigned successfully"})
+	}
+}
+
+// EscalateTicketHandler - POST /tickets/:uuid/escalate - eskalasi tiket (priority high)
+func EscalateTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		staffID := c.Locals("userID").(int)
+
+		type Input struct {
+			Reason string `json:"reason"`
+		}
+		var input Input
+		if err := c.BodyParser(&input); err != nil {
+			return fiber.ErrBadRequest
+		}
+
+		if input.Reason == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Reason is required")
+		}
+
+		ctx := context.Background()
+
+		var status string
+		err := db.QueryRow(ctx, `SELECT status FROM tickets WHERE uuid = $1`, c.Params("uuid")).Scan(&status)
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+
+		if status == "closed" || status == "escalated" {
+			return fiber.NewError(fiber.StatusConflict, "Ticket cannot be escalated in status "+status)
+		}
+
+		_, err = db.Exec(ctx, `
+			UPDATE tickets SET status = 'escalated', priority = 'high', escalation_reason = $1,
+				escalated_by = $2, escalated_at = NOW(), updated_at = NOW()
+			WHERE uuid = $3`,
+			input.Reason, staffID, c.Params("uuid"))
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		return c.JSON(fiber.Map{"message": "Ticket escalated successfully"})
+	}
+}
+
+// CloseTicketHandler - POST /tickets/:uuid/close - tutup tiket
+func CloseTicketHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		staffID := c.Locals("userID").(int)
+		ctx := context.Background()
+
+		var ownerID int
+		var subject string
+		err := db.QueryRow(ctx, `
+			UPDATE tickets SET status = 'closed', closed_by = $1, closed_at = NOW(), updated_at = NOW()
+			WHERE uuid = $2 AND status != 'closed'
+			RETURNING user_id, subject`,
+			staffID, c.Params("uuid")).Scan(&ownerID, &subject)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Ticket not found or already closed")
+		}
+
+		notifyUser(ownerID, "Tiket ditutup", "Tiket Anda telah ditutup: "+subject, "info")
+
+		return c.JSON(fiber.Map{"message": "Ticket closed successfully"})
+	}
+}
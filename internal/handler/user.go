@@ -398,6 +398,7 @@ func DeleteUserHandler(db *pgxpool.Pool) fiber.Handler {
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
+		utils.InvalidateAuthContext(targetUserID)
 
 		return c.JSON(fiber.Map{
 			"message": "User deleted successfully",
@@ -438,6 +439,7 @@ func ActivateUserHandler(db *pgxpool.Pool) fiber.Handler {
 		if result.RowsAffected() == 0 {
 			return fiber.ErrNotFound
 		}
+		utils.InvalidateAuthContext(targetUserID)
 
 		return c.JSON(fiber.Map{
 			"message": "User activated successfully",
@@ -485,6 +487,7 @@ func DeactivateUserHandler(db *pgxpool.Pool) fiber.Handler {
 		if result.RowsAffected() == 0 {
 			return fiber.ErrNotFound
 		}
+		utils.InvalidateAuthContext(targetUserID)
 
 		// Clear active session (force logout)
 		_ = utils.ClearActiveSession(ctx, db, targetUserID, "")
@@ -685,6 +688,7 @@ func BanEndUserHandler(db *pgxpool.Pool) fiber.Handler {
 		if result.RowsAffected() == 0 {
 			return fiber.ErrNotFound
 		}
+		utils.InvalidateAuthContext(targetUserID)
 
 		// Clear active session (force logout)
 		_ = utils.ClearActiveSession(ctx, db, targetUserID, "")
@@ -728,6 +732,7 @@ func UnbanEndUserHandler(db *pgxpool.Pool) fiber.Handler {
 		if result.RowsAffected() == 0 {
 			return fiber.ErrNotFound
 		}
+		utils.InvalidateAuthContext(targetUserID)
 
 		return c.JSON(fiber.Map{
 			"message": "User unbanned successfully",
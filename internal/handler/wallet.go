@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Wallet Response Types
+// ============================================
+
+type WalletResponse struct {
+	Balance          int64      `json:"balance"`
+	PendingPayout    int64      `json:"pending_payout"`
+	AvailableBalance int64      `json:"available_balance"`
+	UpdatedAt        *time.Time `json:"updated_at"`
+}
+
+type LedgerEntryResponse struct {
+	UUID            string    `json:"uuid"`
+	Amount          int64     `json:"amount"`
+	Type            string    `json:"type"`   // in, out
+	Status          string    `json:"status"` // pending, completed, cancelled
+	Description     *string   `json:"description"`
+	TransactionUUID *string   `json:"transaction_uuid"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type BalanceMismatch struct {
+	UserUUID      string `json:"user_uuid"`
+	Email         string `json:"email"`
+	Balance       int64  `json:"balance"`
+	LedgerBalance int64  `json:"ledger_balance"`
+	Difference    int64  `json:"difference"`
+}
+
+// ============================================
+// Ledger Helper Functions
+// ============================================
+
+// creditBalance - tambah saldo user + tulis entry 'in' di ledger (harus dalam DB transaction)
+func creditBalance(ctx context.Context, tx pgx.Tx, userID int, amount int64, transactionID *int, description string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO balances (user_id, amount) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET amount = balances.amount + EXCLUDED.amount, updated_at = NOW()`,
+		userID, amount)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO balance_logs (user_id, transaction_id, amount, type, description)
+		VALUES ($1, $2, $3, 'in', $4)`,
+		userID, transactionID, amount, description)
+	return err
+}
+
+// debitBalance - kurangi saldo user + tulis entry 'out' di ledger (harus dalam DB transaction).
+// Saldo boleh negatif, misalnya refund setelah dana seller sudah ditarik.
+func debitBalance(ctx context.Context, tx pgx.Tx, userID int, amount int64, transactionID *int, description string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO balances (user_id, amount) VALUES ($1, -$2::BIGINT)
+		ON CONFLICT (user_id) DO UPDATE SET amount = balances.amount - $2::BIGINT, updated_at = NOW()`,
+		userID, amount)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO balance_logs (user_id, transaction_id, amount, type, description)
+		VALUES ($1, $2, $3, 'out', $4)`,
+		userID, transactionID, amount, description)
+	return err
+}
+
+// querier - dipenuhi oleh *pgxpool.Pool maupun pgx.Tx
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// getPendingHold - total dana yang sedang ditahan (entry 'out' berstatus pending)
+func getPendingHold(ctx context.Context, q querier, userID int) (int64, error) {
+	var pending int64
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM balance_logs
+		WHERE user_id = $1 AND type = 'out' AND status = 'pending'`,
+		userID).Scan(&pending)
+	return pending, err
+}
+
+// ============================================
+// Seller Wallet Handlers (App)
+// ============================================
+
+// GetMyWalletHandler - GET /my/wallet - saldo seller
+func GetMyWalletHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ownerID := c.Locals("shopOwnerID").(int) // saldo milik owner toko, staff hanya melihat
+		ctx := context.Background()
+
+		var wallet WalletResponse
+		err := db.QueryRow(ctx, `SELECT amount, updated_at FROM balances WHERE user_id = $1`,
+			ownerID).Scan(&wallet.Balance, &wallet.UpdatedAt)
+		if err == pgx.ErrNoRows {
+			return c.JSON(wallet)
+		}
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		wallet.PendingPayout, err = getPendingHold(ctx, db, ownerID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		wallet.AvailableBalance = wallet.Balance - wallet.PendingPayout
+
+		return c.JSON(wallet)
+	}
+}
+
+// ListMyLedgerHandler - GET /my/wallet/ledger - riwayat mutasi saldo seller
+func ListMyLedgerHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ownerID := c.Locals("shopOwnerID").(int)
+		ctx := context.Background()
+
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		limit, _ := strconv.Atoi(c.Query("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := (page - 1) * limit
+
+		typeFilter := c.Query("type", "")     // in, out
+		statusFilter := c.Query("status", "") // pending, completed, cancelled
+
+		baseQuery := `FROM balance_logs bl
+			LEFT JOIN transactions t ON bl.transaction_id = t.id
+			WHERE bl.user_id = $1`
+		args := []interface{}{ownerID}
+		argCount := 1
+
+		if typeFilter != "" {
+			argCount++
+			baseQuery += ` AND bl.type = $` + strconv.Itoa(argCount)
+			args = append(args, typeFilter)
+		}
+
+		if statusFilter != "" {
+			argCount++
+			baseQuery += ` AND bl.status = $` + strconv.Itoa(argCount)
+			args = append(args, statusFilter)
+		}
+
+		var totalItems int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) `+baseQuery, args...).Scan(&totalItems)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		argCount++
+		limitArg := argCount
+		argCount++
+		offsetArg := argCount
+		args = append(args, limit, offset)
+
+		dataQuery := `SELECT bl.uuid, bl.amount, bl.type, bl.status, bl.description, t.uuid, bl.created_at ` +
+			baseQuery + ` ORDER BY bl.created_at DESC, bl.id DESC LIMIT $` + strconv.Itoa(limitArg) + ` OFFSET $` + strconv.Itoa(offsetArg)
+
+		rows, err := db.Query(ctx, dataQuery, args...)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		entries := []LedgerEntryResponse{}
+		for rows.Next() {
+			var e LedgerEntryResponse
+			if err := rows.Scan(&e.UUID, &e.Amount, &e.Type, &e.Status, &e.Description, &e.TransactionUUID, &e.CreatedAt); err != nil {
+				continue
+			}
+			entries = append(entries, e)
+		}
+
+		totalPages := (totalItems + limit - 1) / limit
+
+		return c.JSON(PaginatedResponse{
+			Data:       entries,
+			Page:       page,
+			Limit:      limit,
+			TotalItems: totalItems,
+			TotalPages: totalPages,
+		})
+	}
+}
+
+// ============================================
+// Admin Wallet Handlers (Dashboard)
+// ============================================
+
+// ReconcileBalancesHandler - GET /wallets/reconcile - cek balances.amount == total ledger
+// (hanya entry completed, hold pending belum mengurangi balances) dan buyer_credits == buyer_credit_logs
+func ReconcileBalancesHandler(db *pgxpool.Pool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := context.Background()
+
+		rows, err := db.Query(ctx, `
+			WITH ledger AS (
+				SELECT user_id, SUM(CASE WHEN type = 'in' THEN amount ELSE -amount END) AS amount
+				FROM balance_logs
+				WHERE status = 'completed'
+				GROUP BY user_id
+			)
+			SELECT u.uuid, u.email, COALESCE(b.amount, 0), COALESCE(l.amount, 0)
+			FROM balances b
+			FULL OUTER JOIN ledger l ON l.user_id = b.user_id
+			JOIN users u ON u.id = COALESCE(b.user_id, l.user_id)
+			WHERE COALESCE(b.amount, 0) <> COALESCE(l.amount, 0)
+			ORDER BY u.id`)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		mismatches := []BalanceMismatch{}
+		for rows.Next() {
+			var m BalanceMismatch
+			if err := rows.Scan(&m.UserUUID, &m.Email, &m.Balance, &m.LedgerBalance); err != nil {
+				continue
+			}
+			m.Difference = m.Balance - m.LedgerBalance
+			mismatches = append(mismatches, m)
+		}
+		rows.Close()
+
+		// Saldo refund buyer dicek terhadap ledger-nya sendiri
+		rows, err = db.Query(ctx, `
+			WITH ledger AS (
+				SELECT user_id, SUM(CASE WHEN type = 'in' THEN amount ELSE -amount END) AS amount
+				FROM buyer_credit_logs
+				GROUP BY user_id
+			)
+			SELECT u.uuid, u.email, COALESCE(b.amount, 0), COALESCE(l.amount, 0)
+			FROM buyer_credits b
+			FULL OUTER JOIN ledger l ON l.user_id = b.user_id
+			JOIN users u ON u.id = COALESCE(b.user_id, l.user_id)
+			WHERE COALESCE(b.amount, 0) <> COALESCE(l.amount, 0)
+			ORDER BY u.id`)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		defer rows.Close()
+
+		buyerMismatches := []BalanceMismatch{}
+		for rows.Next() {
+			var m BalanceMismatch
+			if err := rows.Scan(&m.UserUUID, &m.Email, &m.Balance, &m.LedgerBalance); err != nil {
+				continue
+			}
+			m.Difference = m.Balance - m.LedgerBalance
+			buyerMismatches = append(buyerMismatches, m)
+		}
+
+		return c.JSON(fiber.Map{
+			"is_reconciled":           len(mismatches) == 0 && len(buyerMismatches) == 0,
+			"mismatches":              mismatches,
+			"buyer_credit_mismatches": buyerMismatches,
+			"checked_at":              time.Now().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/cache"
+	utils "shopedia-api/internal/util"
+)
+
+// APIKeyProtected - auth dengan header "Authorization: ApiKey <key>". Key harus punya scope
+// yang diminta route; c.Locals diisi sama seperti JWTProtected (plus apiKeyID).
+func APIKeyProtected(db *pgxpool.Pool, scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parts := strings.SplitN(c.Get("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "ApiKey" || !strings.HasPrefix(parts[1], utils.APIKeyPrefix) {
+			return fiber.ErrUnauthorized
+		}
+
+		var keyID, userID, rateLimit int
+		var userUUID string
+		var scopes []string
+		var expiresAt time.Time
+		err := db.QueryRow(c.Context(), `
+			SELECT k.id, k.user_id, u.uuid, k.scopes, k.rate_limit, k.expires_at
+			FROM api_keys k
+			JOIN users u ON u.id = k.user_id
+			WHERE k.key_hash = $1 AND k.revoked_at IS NULL`,
+			utils.HashAPIKey(parts[1])).Scan(&keyID, &userID, &userUUID, &scopes, &rateLimit, &expiresAt)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+		}
+
+		if time.Now().After(expiresAt) {
+			return fiber.NewError(fiber.StatusUnauthorized, "API key expired")
+		}
+
+		ac, err := utils.GetAuthContext(c.Context(), db, userID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+		}
+		if err := checkUserStatus(ac); err != nil {
+			return err
+		}
+		// API key hanya untuk route app, role dashboard tidak ikut
+		ac = ac.ForScope("app")
+
+		hasScope := false
+		for _, s := range scopes {
+			if s == scope {
+				hasScope = true
+				break
+			}
+		}
+		if !hasScope {
+			return fiber.NewError(fiber.StatusForbidden, "API key does not have scope "+scope)
+		}
+
+		// Rate limit per key (bukan per IP, ERP biasanya dari banyak worker)
+		if cache.Client != nil {
+			result, err := cache.CheckRateLimit(fmt.Sprintf("apikey:%d", keyID), rateLimit, time.Minute)
+			if err == nil {
+				c.Set("X-RateLimit-Limit", strconv.Itoa(rateLimit))
+				c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+				c.Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+				if !result.Allowed {
+					c.Set("Retry-After", strconv.FormatInt(int64(time.Until(result.ResetAt).Seconds()), 10))
+					return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+						"error":       "Rate limit exceeded",
+						"retry_after": int(time.Until(result.ResetAt).Seconds()),
+					})
+				}
+			}
+		}
+
+		// Last used: tulis paling banyak sekali per menit per key
+		_, err = db.Exec(c.Context(), `
+			UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`,
+			keyID, c.IP())
+		if err != nil {
+			log.Printf("API key last used update error: %v", err)
+		}
+
+		// Inject ke ctx (sama seperti JWTProtected)
+		c.Locals("userID", userID)
+		c.Locals("userUUID", userUUID)
+		c.Locals("roles", ac.Roles)
+		c.Locals("jti", "")
+		c.Locals("tokenExp", expiresAt)
+		c.Locals("authContext", ac)
+		c.Locals("apiKeyID", keyID)
+
+		return c.Next()
+	}
+}
+
+// JWTOrAPIKey - route yang bisa diakses dengan access token (Bearer) maupun API key (ApiKey)
+func JWTOrAPIKey(db *pgxpool.Pool, scope string) fiber.Handler {
+	jwtAuth := JWTProtected(db)
+	apiKeyAuth := APIKeyProtected(db, scope)
+
+	return func(c *fiber.Ctx) error {
+		if strings.HasPrefix(c.Get("Authorization"), "ApiKey ") {
+			return apiKeyAuth(c)
+		}
+		return jwtAuth(c)
+	}
+}
@@ -9,8 +9,16 @@ import (
 	utils "shopedia-api/internal/util"
 )
 
+// JWTProtected - validasi access token dan isi c.Locals. Group dengan prefix yang sama
+// (mis. beberapa api.Group("/admin").Use(...)) ikut menjalankan middleware ini berulang
+// untuk satu request; token yang sudah divalidasi di request ini tidak dicek ulang.
 func JWTProtected(db *pgxpool.Pool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// jti kosong = diisi APIKeyProtected, route JWT tetap wajib Bearer token
+		if jti, ok := c.Locals("jti").(string); ok && jti != "" {
+			return c.Next()
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return fiber.ErrUnauthorized
@@ -34,90 +42,77 @@ func JWTProtected(db *pgxpool.Pool) fiber.Handler {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid token type")
 		}
 
-		// Cek apakah token sudah di-revoke
+		// Cek token belum di-revoke dan masih active session
 		jti := claims.ID
-		if utils.IsTokenRevoked(c.Context(), db, jti) {
+		switch utils.CheckAccessToken(c.Context(), db, claims.UserID, jti) {
+		case nil:
+		case utils.ErrTokenRevoked:
 			return fiber.NewError(fiber.StatusUnauthorized, "Token has been revoked")
-		}
-
-		// Cek apakah token adalah active session (Single Active Session)
-		if !utils.IsActiveSession(c.Context(), db, claims.UserID, jti) {
+		default:
 			return fiber.NewError(fiber.StatusUnauthorized, "Session expired, please login again")
 		}
 
-		// Cek status user di database (is_active, is_banned, deleted_at)
-		var isActive bool
-		var isBanned bool
-		var isDeleted bool
-		err = db.QueryRow(c.Context(), `
-			SELECT is_active, COALESCE(is_banned, FALSE), (deleted_at IS NOT NULL)
-			FROM users WHERE id = $1`,
-			claims.UserID).Scan(&isActive, &isBanned, &isDeleted)
+		// Status user, role, scope & permission (cache Redis, fallback DB)
+		ac, err := utils.GetAuthContext(c.Context(), db, claims.UserID)
 		if err != nil {
 			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
 		}
-
-		if isDeleted {
-			return fiber.NewError(fiber.StatusUnauthorized, "Account has been deleted")
+		if err := checkUserStatus(ac); err != nil {
+			return err
 		}
 
-		if isBanned {
-			return fiber.NewError(fiber.StatusUnauthorized, "Account has been banned")
-		}
-
-		if !isActive {
-			return fiber.NewError(fiber.StatusUnauthorized, "Account is not active")
+		// Token hanya membawa role dari scope login-nya (token lama tanpa scope: semua role)
+		if claims.Scope != "" {
+			ac = ac.ForScope(claims.Scope)
 		}
 
 		// Inject ke ctx
 		c.Locals("userID", claims.UserID)
 		c.Locals("userUUID", claims.UserUUID)
-		c.Locals("roles", claims.Roles)
+		c.Locals("roles", ac.Roles)
 		c.Locals("jti", jti)
 		c.Locals("tokenExp", claims.ExpiresAt.Time)
+		c.Locals("authContext", ac)
 
 		return c.Next()
 	}
 }
 
+// checkUserStatus - tolak user yang dihapus, di-ban atau tidak aktif
+func checkUserStatus(ac *utils.AuthContext) error {
+	if ac.IsDeleted {
+		return fiber.NewError(fiber.StatusUnauthorized, "Account has been deleted")
+	}
+
+	if ac.IsBanned {
+		return fiber.NewError(fiber.StatusUnauthorized, "Account has been banned")
+	}
+
+	if !ac.IsActive {
+		return fiber.NewError(fiber.StatusUnauthorized, "Account is not active")
+	}
+	return nil
+}
+
+// authContext - auth context dari JWTProtected / APIKeyProtected, atau load jika belum ada
+func authContext(c *fiber.Ctx, db *pgxpool.Pool) (*utils.AuthContext, error) {
+	if ac, ok := c.Locals("authContext").(*utils.AuthContext); ok {
+		return ac, nil
+	}
+	return utils.GetAuthContext(c.Context(), db, c.Locals("userID").(int))
+}
+
 // RoleRequired middleware - check if user has any of the allowed roles
 func RoleRequired(db *pgxpool.Pool, allowedRoles []string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID").(int)
-
-		// Get all user roles
-		rows, err := db.Query(c.Context(),
-			`SELECT r.name FROM user_roles ur
-			 JOIN roles r ON ur.role_id = r.id
-			 WHERE ur.user_id = $1 AND r.deleted_at IS NULL`,
-			userID)
+		ac, err := authContext(c, db)
 		if err != nil {
 			return fiber.ErrForbidden
 		}
-		defer rows.Close()
-
-		userRoles := []string{}
-		for rows.Next() {
-			var roleName string
-			if rows.Scan(&roleName) == nil {
-				userRoles = append(userRoles, roleName)
-			}
-		}
 
 		// super_admin has access to everything
-		for _, role := range userRoles {
-			if role == "super_admin" {
-				return c.Next()
-			}
-		}
-
-		// Check if user has any of the allowed roles
-		for _, userRole := range userRoles {
-			for _, allowedRole := range allowedRoles {
-				if userRole == allowedRole {
-					return c.Next()
-				}
-			}
+		if ac.IsSuperAdmin() || ac.HasRole(allowedRoles...) {
+			return c.Next()
 		}
 
 		return fiber.ErrForbidden
@@ -127,54 +122,20 @@ func RoleRequired(db *pgxpool.Pool, allowedRoles []string) fiber.Handler {
 // PermissionRequired middleware - check if user has any of the required permissions
 func PermissionRequired(db *pgxpool.Pool, requiredPermissions []string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID").(int)
-
-		// Check if user is super_admin (has all permissions)
-		var isSuperAdmin bool
-		err := db.QueryRow(c.Context(),
-			`SELECT EXISTS(
-				SELECT 1 FROM user_roles ur
-				JOIN roles r ON ur.role_id = r.id
-				WHERE ur.user_id = $1 AND r.name = 'super_admin' AND r.deleted_at IS NULL
-			)`, userID).Scan(&isSuperAdmin)
+		ac, err := authContext(c, db)
 		if err != nil {
 			return fiber.ErrForbidden
 		}
 
-		if isSuperAdmin {
+		// super_admin has all permissions
+		if ac.IsSuperAdmin() {
 			return c.Next()
 		}
 
-		// Get user permissions through roles
-		rows, err := db.Query(c.Context(),
-			`SELECT DISTINCT p.name FROM user_roles ur
-			 JOIN role_permissions rp ON ur.role_id = rp.role_id
-			 JOIN permissions p ON rp.permission_id = p.id
-			 WHERE ur.user_id = $1 AND p.deleted_at IS NULL`,
-			userID)
-		if err != nil {
-			return fiber.ErrForbidden
-		}
-		defer rows.Close()
-
-		userPermissions := make(map[string]bool)
-		for rows.Next() {
-			var permName string
-			if rows.Scan(&permName) == nil {
-				userPermissions[permName] = true
-			}
-		}
-
 		// Check if user has any of the required permissions
+		// (wildcard: user having "finance.*" passes "finance.view")
 		for _, reqPerm := range requiredPermissions {
-			if userPermissions[reqPerm] {
-				return c.Next()
-			}
-
-			// Support wildcard matching: if required is "finance.view",
-			// user having "finance.*" should pass
-			module := strings.Split(reqPerm, ".")[0]
-			if userPermissions[module+".*"] {
+			if ac.HasPermission(reqPerm) {
 				return c.Next()
 			}
 		}
@@ -184,26 +145,24 @@ func PermissionRequired(db *pgxpool.Pool, requiredPermissions []string) fiber.Ha
 }
 
 // ScopeRequired middleware - check if user's role is in the required scope
+// (sekali per request, seperti JWTProtected)
 func ScopeRequired(db *pgxpool.Pool, scope string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		userID := c.Locals("userID").(int)
+		if checked, ok := c.Locals("checkedScope").(string); ok && checked == scope {
+			return c.Next()
+		}
 
-		// Check if user has any role in the required scope
-		var hasScope bool
-		err := db.QueryRow(c.Context(),
-			`SELECT EXISTS(
-				SELECT 1 FROM user_roles ur
-				JOIN roles r ON ur.role_id = r.id
-				WHERE ur.user_id = $1 AND r.scope = $2 AND r.deleted_at IS NULL
-			)`, userID, scope).Scan(&hasScope)
+		ac, err := authContext(c, db)
 		if err != nil {
 			return fiber.ErrForbidden
 		}
 
-		if !hasScope {
+		// Check if user has any role in the required scope
+		if !ac.HasScope(scope) {
 			return fiber.NewError(fiber.StatusForbidden, "Access denied for this scope")
 		}
 
+		c.Locals("checkedScope", scope)
 		return c.Next()
 	}
 }
@@ -91,7 +91,7 @@ func RateLimitByUser(max int, window time.Duration) fiber.Handler {
 		Max:    max,
 		Window: window,
 		KeyGenerator: func(c *fiber.Ctx) string {
-			userID := c.Locals("user_id")
+			userID := c.Locals("userID")
 			if userID != nil {
 				return fmt.Sprintf("user:%d", userID.(int))
 			}
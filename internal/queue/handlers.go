@@ -1,12 +1,16 @@
 package queue
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/smtp"
 	"os"
+	"time"
+
+	"shopedia-api/internal/report"
 
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -125,7 +129,7 @@ Kami menerima permintaan untuk reset password akun Anda.
 Klik link berikut untuk reset password:
 %s
 
-Link ini berlaku selama 1 jam.
+Link ini berlaku selama 15 menit.
 Jika Anda tidak meminta reset password, abaikan email ini.
 
 Salam,
@@ -142,6 +146,120 @@ Tim Shopedia
 	return nil
 }
 
+func (h *TaskHandler) HandleSendInvite(ctx context.Context, t *asynq.Task) error {
+	var payload SendInvitePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("[Invite] Sending invite to: %s", payload.Email)
+
+	subject := "Undangan Dashboard - Shopedia"
+	body := fmt.Sprintf(`
+Halo,
+
+Anda diundang untuk bergabung ke dashboard Shopedia.
+
+Klik link berikut untuk mengatur password dan mengaktifkan akun:
+%s
+
+Link ini berlaku selama 24 jam.
+
+Salam,
+Tim Shopedia
+`, payload.InviteLink)
+
+	err := sendEmail(payload.Email, subject, body)
+	if err != nil {
+		log.Printf("[Invite] Failed to send: %v", err)
+		return err
+	}
+
+	log.Printf("[Invite] Successfully sent to: %s", payload.Email)
+	return nil
+}
+
+func (h *TaskHandler) HandleSendShopInvite(ctx context.Context, t *asynq.Task) error {
+	var payload SendShopInvitePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("[ShopInvite] Sending invite to: %s", payload.Email)
+
+	subject := fmt.Sprintf("Undangan Staff %s - Shopedia", payload.ShopName)
+	body := fmt.Sprintf(`
+Halo,
+
+Anda diundang untuk bergabung sebagai staff toko %s di Shopedia.
+
+Klik link berikut untuk menerima undangan (dan mengatur password jika belum punya akun):
+%s
+
+Link ini berlaku selama 24 jam.
+
+Salam,
+Tim Shopedia
+`, payload.ShopName, payload.InviteLink)
+
+	err := sendEmail(payload.Email, subject, body)
+	if err != nil {
+		log.Printf("[ShopInvite] Failed to send: %v", err)
+		return err
+	}
+
+	log.Printf("[ShopInvite] Successfully sent to: %s", payload.Email)
+	return nil
+}
+
+func (h *TaskHandler) HandleSendLoginAlert(ctx context.Context, t *asynq.Task) error {
+	var payload SendLoginAlertPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("[LoginAlert] Sending login alert to: %s", payload.Email)
+
+	reason := "perangkat baru"
+	if payload.NewCountry && !payload.NewDevice {
+		reason = "negara baru"
+	} else if payload.NewCountry {
+		reason = "perangkat dan negara baru"
+	}
+
+	country := payload.Country
+	if country == "" {
+		country = "-"
+	}
+
+	subject := "Login Baru di Akun Shopedia Anda"
+	body := fmt.Sprintf(`
+Halo %s,
+
+Kami mendeteksi login ke akun Shopedia Anda dari %s.
+
+Waktu     : %s
+Perangkat : %s
+Alamat IP : %s
+Negara    : %s
+
+Jika ini Anda, abaikan email ini.
+Jika bukan, segera ganti password dan logout dari semua perangkat.
+
+Salam,
+Tim Shopedia
+`, payload.Name, reason, payload.LoginAt, payload.DeviceName, payload.IPAddress, country)
+
+	err := sendEmail(payload.Email, subject, body)
+	if err != nil {
+		log.Printf("[LoginAlert] Failed to send: %v", err)
+		return err
+	}
+
+	log.Printf("[LoginAlert] Successfully sent to: %s", payload.Email)
+	return nil
+}
+
 // ============================================
 // Notification Handler
 // ============================================
@@ -183,22 +301,94 @@ func (h *TaskHandler) HandleProductIndexing(ctx context.Context, t *asynq.Task)
 
 	log.Printf("[ProductIndex] Action: %s, ProductID: %d, UUID: %s", payload.Action, payload.ProductID, payload.ProductUUID)
 
-	// TODO: Implement search indexing logic (Elasticsearch, Meilisearch, etc.)
-	// For now, just log it
-
+	// search_vector dipakai pencarian produk (migration 031); produk terhapus dikeluarkan dari index
+	var err error
 	switch payload.Action {
-	case "create":
-		log.Printf("[ProductIndex] Indexing new product: %s", payload.ProductUUID)
-	case "update":
-		log.Printf("[ProductIndex] Updating product index: %s", payload.ProductUUID)
+	case "create", "update":
+		_, err = h.DB.Exec(ctx, `
+			UPDATE products SET search_vector = product_search_vector(title, description)
+			WHERE id = $1 AND deleted_at IS NULL`, payload.ProductID)
 	case "delete":
-		log.Printf("[ProductIndex] Removing product from index: %s", payload.ProductUUID)
+		_, err = h.DB.Exec(ctx, `UPDATE products SET search_vector = NULL WHERE id = $1`, payload.ProductID)
+	default:
+		log.Printf("[ProductIndex] Unknown action: %s", payload.Action)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
 	}
 
 	log.Printf("[ProductIndex] Successfully processed: %s", payload.ProductUUID)
 	return nil
 }
 
+// ============================================
+// Finance Handler
+// ============================================
+
+func (h *TaskHandler) HandleFinanceExport(ctx context.Context, t *asynq.Task) error {
+	var payload FinanceExportPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	log.Printf("[FinanceExport] Export: %s, Format: %s, Range: %s - %s", payload.ExportUUID, payload.Format, payload.From, payload.To)
+
+	from, err := time.Parse("2006-01-02", payload.From)
+	if err != nil {
+		return fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", payload.To)
+	if err != nil {
+		return fmt.Errorf("invalid to date: %w", err)
+	}
+	filter := report.FinanceFilter{From: from, To: to, CategoryID: payload.CategoryID, SellerID: payload.SellerID}
+
+	// Retry setelah email gagal: file sudah tersimpan, cukup kirim ulang email
+	var fileName string
+	var generated bool
+	err = h.DB.QueryRow(ctx, `
+		SELECT COALESCE(file_name, ''), status = 'completed' AND file_data IS NOT NULL
+		FROM finance_exports WHERE id = $1`,
+		payload.ExportID).Scan(&fileName, &generated)
+	if err != nil {
+		return fmt.Errorf("failed to load export: %w", err)
+	}
+
+	if !generated {
+		var buf bytes.Buffer
+		if err := report.WriteFinanceExport(ctx, h.DB, &buf, payload.Format, filter); err != nil {
+			log.Printf("[FinanceExport] Failed to generate: %v", err)
+			h.DB.Exec(ctx, `UPDATE finance_exports SET status = 'failed', error_message = $1 WHERE id = $2`,
+				err.Error(), payload.ExportID)
+			return err
+		}
+
+		fileName = report.FinanceExportFileName(payload.Format, filter)
+		_, err = h.DB.Exec(ctx, `
+			UPDATE finance_exports SET status = 'completed', file_name = $1, file_data = $2,
+				error_message = NULL, completed_at = NOW()
+			WHERE id = $3`,
+			fileName, buf.Bytes(), payload.ExportID)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("[FinanceExport] File already generated, resending email: %s", payload.ExportUUID)
+	}
+
+	downloadURL := fmt.Sprintf("%s/finance/exports/%s", os.Getenv("FRONTEND_URL"), payload.ExportUUID)
+	body := fmt.Sprintf("Export finance %s (%s s/d %s) sudah selesai.\n\nUnduh di: %s\n\nLink berlaku 7 hari.",
+		fileName, payload.From, payload.To, downloadURL)
+	if err := sendEmail(payload.Email, "Export Finance Siap Diunduh", body); err != nil {
+		log.Printf("[FinanceExport] Failed to send email: %v", err)
+		return err
+	}
+
+	log.Printf("[FinanceExport] Successfully processed: %s", payload.ExportUUID)
+	return nil
+}
+
 // ============================================
 // Helper Functions
 // ============================================
@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	OutboxBatchSize   = 100
+	OutboxMaxAttempts = 10
+	OutboxMaxBackoff  = 5 * time.Minute
+	OutboxRetention   = 7 * 24 * time.Hour
+)
+
+// Execer - dipenuhi oleh pgx.Tx maupun *pgxpool.Pool
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// AddToOutbox - simpan task ke tabel outbox. Panggil dengan tx yang sama
+// dengan perubahan bisnisnya supaya task hanya terkirim kalau tx commit.
+func AddToOutbox(ctx context.Context, db Execer, task *asynq.Task, queueName string) error {
+	if queueName == "" {
+		queueName = "default"
+	}
+	_, err := db.Exec(ctx, `
+		INSERT INTO outbox (task_type, payload, queue_name)
+		VALUES ($1, $2, $3)`,
+		task.Type(), task.Payload(), queueName)
+	return err
+}
+
+// RunOutboxRelay - publish outbox pending ke asynq secara berkala sampai ctx selesai
+func RunOutboxRelay(ctx context.Context, db *pgxpool.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+
+	for {
+		if time.Since(lastPurge) >= time.Hour {
+			if err := PurgePublishedOutbox(ctx, db); err != nil {
+				log.Printf("[Outbox] Purge failed: %v", err)
+			}
+			lastPurge = time.Now()
+		}
+
+		for {
+			n, err := RelayOutboxBatch(ctx, db)
+			if err != nil {
+				log.Printf("[Outbox] Relay failed: %v", err)
+				break
+			}
+			// Batch penuh → kemungkinan masih ada sisa, lanjut tanpa menunggu
+			if n < OutboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOutboxBatch - ambil satu batch outbox pending dan enqueue ke asynq.
+// FOR UPDATE SKIP LOCKED supaya beberapa instance API bisa relay bersamaan.
+func RelayOutboxBatch(ctx context.Context, db *pgxpool.Pool) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, task_type, payload, queue_name, attempts
+		FROM outbox
+		WHERE status = 'pending' AND available_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, OutboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	type outboxRow struct {
+		id        int64
+		taskType  string
+		payload   []byte
+		queueName string
+		attempts  int
+	}
+	var batch []outboxRow
+	for rows.Next() {
+		var r outboxRow
+		if err := rows.Scan(&r.id, &r.taskType, &r.payload, &r.queueName, &r.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, r := range batch {
+		// TaskID dari id outbox → kalau relay crash setelah enqueue tapi sebelum
+		// commit, publish ulang ditolak asynq dan tidak jadi email dobel
+		_, err := Enqueue(asynq.NewTask(r.taskType, r.payload),
+			asynq.Queue(r.queueName), asynq.TaskID(fmt.Sprintf("outbox:%d", r.id)))
+		if err == nil || errors.Is(err, asynq.ErrTaskIDConflict) {
+			_, err = tx.Exec(ctx, `
+				UPDATE outbox SET status = 'published', published_at = NOW(), last_error = NULL
+				WHERE id = $1`, r.id)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		attempts := r.attempts + 1
+		if attempts >= OutboxMaxAttempts {
+			log.Printf("[Outbox] Giving up on %d (%s) after %d attempts: %v", r.id, r.taskType, attempts, err)
+			_, err = tx.Exec(ctx, `
+				UPDATE outbox SET status = 'failed', attempts = $1, last_error = $2
+				WHERE id = $3`, attempts, err.Error(), r.id)
+		} else {
+			_, err = tx.Exec(ctx, `
+				UPDATE outbox SET attempts = $1, last_error = $2, available_at = $3
+				WHERE id = $4`, attempts, err.Error(), time.Now().Add(outboxBackoff(attempts)), r.id)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+// PurgePublishedOutbox - hapus outbox yang sudah published lebih dari OutboxRetention
+func PurgePublishedOutbox(ctx context.Context, db *pgxpool.Pool) error {
+	_, err := db.Exec(ctx, `
+		DELETE FROM outbox WHERE status = 'published' AND published_at < $1`,
+		time.Now().Add(-OutboxRetention))
+	return err
+}
+
+// outboxBackoff - 2s, 4s, 8s, ... dibatasi OutboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Duration(1<<attempts) * time.Second
+	if d > OutboxMaxBackoff {
+		return OutboxMaxBackoff
+	}
+	return d
+}
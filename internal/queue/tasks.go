@@ -12,8 +12,12 @@ const (
 	TypeSendOTP          = "email:otp"
 	TypeSendWelcome      = "email:welcome"
 	TypeSendPasswordReset = "email:password_reset"
+	TypeSendInvite       = "email:invite"
+	TypeSendShopInvite   = "email:shop_invite"
+	TypeSendLoginAlert   = "email:login_alert"
 	TypeNotification     = "notification:send"
 	TypeProductIndexing  = "product:index"
+	TypeFinanceExport    = "finance:export"
 )
 
 // ============================================
@@ -90,6 +94,59 @@ func NewSendPasswordResetTask(email, name, resetLink string) (*asynq.Task, error
 	return asynq.NewTask(TypeSendPasswordReset, payload), nil
 }
 
+type SendInvitePayload struct {
+	Email      string `json:"email"`
+	InviteLink string `json:"invite_link"`
+}
+
+func NewSendInviteTask(email, inviteLink string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendInvitePayload{
+		Email:      email,
+		InviteLink: inviteLink,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeSendInvite, payload), nil
+}
+
+type SendShopInvitePayload struct {
+	Email      string `json:"email"`
+	ShopName   string `json:"shop_name"`
+	InviteLink string `json:"invite_link"`
+}
+
+func NewSendShopInviteTask(email, shopName, inviteLink string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SendShopInvitePayload{
+		Email:      email,
+		ShopName:   shopName,
+		InviteLink: inviteLink,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeSendShopInvite, payload), nil
+}
+
+type SendLoginAlertPayload struct {
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	DeviceName string `json:"device_name"`
+	IPAddress  string `json:"ip_address"`
+	Country    string `json:"country"`
+	LoginAt    string `json:"login_at"`
+	NewDevice  bool   `json:"new_device"`
+	NewCountry bool   `json:"new_country"`
+}
+
+func NewSendLoginAlertTask(p SendLoginAlertPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeSendLoginAlert, payload), nil
+}
+
 // ============================================
 // Notification Tasks
 // ============================================
@@ -135,3 +192,26 @@ func NewProductIndexTask(productID int, productUUID, action string) (*asynq.Task
 	}
 	return asynq.NewTask(TypeProductIndexing, payload), nil
 }
+
+// ============================================
+// Finance Tasks
+// ============================================
+
+type FinanceExportPayload struct {
+	ExportID   int    `json:"export_id"`
+	ExportUUID string `json:"export_uuid"`
+	Email      string `json:"email"`
+	Format     string `json:"format"` // csv, xlsx
+	From       string `json:"from"`   // YYYY-MM-DD
+	To         string `json:"to"`     // YYYY-MM-DD
+	CategoryID *int   `json:"category_id"`
+	SellerID   *int   `json:"seller_id"`
+}
+
+func NewFinanceExportTask(p FinanceExportPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeFinanceExport, payload), nil
+}
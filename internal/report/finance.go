@@ -0,0 +1,158 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// Finance Export
+// ============================================
+
+// FinanceFilter - filter laporan finance, From/To inklusif (per tanggal)
+type FinanceFilter struct {
+	From       time.Time
+	To         time.Time
+	CategoryID *int
+	SellerID   *int
+}
+
+var financeExportHeader = []string{
+	"transaction_uuid", "order_uuid", "created_at", "status",
+	"product", "category", "seller_email", "buyer_email",
+	"qty", "unit_price", "total_price", "refunded_qty", "refunded_amount",
+}
+
+const financeExportWhere = `
+	FROM transactions t
+	JOIN products p ON t.product_id = p.id
+	LEFT JOIN product_categories pc ON p.category_id = pc.id
+	LEFT JOIN orders o ON t.order_id = o.id
+	LEFT JOIN users s ON t.seller_user_id = s.id
+	JOIN users b ON t.buyer_user_id = b.id
+	WHERE t.created_at >= $1 AND t.created_at < $2
+		AND ($3::INT IS NULL OR p.category_id = $3)
+		AND ($4::INT IS NULL OR t.seller_user_id = $4)`
+
+// SettledStatuses - status transaksi yang dihitung sebagai penjualan (GMV): order sudah completed
+// dan saldo seller sudah dikreditkan; refund dihitung terpisah dari tabel refunds
+const SettledStatuses = `('completed', 'partially_refunded', 'refunded')`
+
+func (f FinanceFilter) args() []interface{} {
+	return []interface{}{f.From, f.To.AddDate(0, 0, 1), f.CategoryID, f.SellerID}
+}
+
+// CountFinanceRows - jumlah baris yang akan di-export, dipakai untuk memilih export sync/async
+func CountFinanceRows(ctx context.Context, db *pgxpool.Pool, f FinanceFilter) (int, error) {
+	var total int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) `+financeExportWhere, f.args()...).Scan(&total)
+	return total, err
+}
+
+// WriteFinanceExport - tulis baris transaksi ke w dalam format csv atau xlsx
+func WriteFinanceExport(ctx context.Context, db *pgxpool.Pool, w io.Writer, format string, f FinanceFilter) error {
+	var writeRow func(cells []interface{}) error
+	var flush func() error
+
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		writeRow = func(cells []interface{}) error {
+			record := make([]string, len(cells))
+			for i, cell := range cells {
+				if text, ok := cell.(string); ok {
+					record[i] = csvSafe(text)
+				} else {
+					record[i] = fmt.Sprint(cell)
+				}
+			}
+			return cw.Write(record)
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case "xlsx":
+		xw, err := newXLSXWriter(w, "Transactions")
+		if err != nil {
+			return err
+		}
+		writeRow = xw.WriteRow
+		flush = xw.Close
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	header := make([]interface{}, len(financeExportHeader))
+	for i, h := range financeExportHeader {
+		header[i] = h
+	}
+	if err := writeRow(header); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT t.uuid::TEXT, COALESCE(o.uuid::TEXT, ''), t.created_at, t.status,
+			p.title, COALESCE(pc.name, ''), COALESCE(s.email, ''), b.email,
+			t.qty, t.unit_price, t.total_price, t.refunded_qty, t.refunded_amount `+
+		financeExportWhere+` ORDER BY t.created_at, t.id`, f.args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionUUID, orderUUID, status, product, category, sellerEmail, buyerEmail string
+		var createdAt time.Time
+		var qty, refundedQty int
+		var unitPrice, totalPrice, refundedAmount int64
+		err := rows.Scan(&transactionUUID, &orderUUID, &createdAt, &status,
+			&product, &category, &sellerEmail, &buyerEmail,
+			&qty, &unitPrice, &totalPrice, &refundedQty, &refundedAmount)
+		if err != nil {
+			return err
+		}
+
+		err = writeRow([]interface{}{
+			transactionUUID, orderUUID, createdAt.Format(time.RFC3339), status,
+			product, category, sellerEmail, buyerEmail,
+			qty, unitPrice, totalPrice, refundedQty, refundedAmount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// csvSafe - cegah formula injection: teks dari seller / buyer (title produk, kategori, email)
+// yang diawali karakter formula dianggap teks oleh Excel / Sheets dengan prefix '
+func csvSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// FinanceExportFileName - nama file export, contoh: finance_2026-01-01_2026-01-31.csv
+func FinanceExportFileName(format string, f FinanceFilter) string {
+	return "finance_" + f.From.Format("2006-01-02") + "_" + f.To.Format("2006-01-02") + "." + format
+}
+
+// FinanceExportContentType - content type untuk format export
+func FinanceExportContentType(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// ============================================
+// API Keys (integrasi seller)
+// ============================================
+
+const APIKeyPrefix = "shp_"
+
+// APIKeyScopes - scope yang bisa diberikan ke API key
+var APIKeyScopes = []string{
+	"product.read",
+	"product.write",
+	"wallet.read",
+}
+
+// ValidAPIKeyScope - scope dikenal
+func ValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey - buat key baru "shp_<8 hex>_<secret>", return key, prefix tampilan dan hash-nya.
+// Yang disimpan di database hanya hash-nya.
+func GenerateAPIKey() (string, string, string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix := APIKeyPrefix + hex.EncodeToString(id)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey - sha256 hex dari API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/cache"
+)
+
+// ============================================
+// Auth Context (status, roles, scopes, permissions)
+// ============================================
+
+// AuthContext - data otorisasi user yang dipakai middleware, di-cache di Redis
+// dan ditandai versi user + versi global untuk invalidasi
+type AuthContext struct {
+	UserID        int      `json:"user_id"`
+	IsActive      bool     `json:"is_active"`
+	IsBanned      bool     `json:"is_banned"`
+	IsDeleted     bool     `json:"is_deleted"`
+	Roles         []string `json:"roles"`
+	Scopes        []string `json:"scopes"`
+	Permissions   []string `json:"permissions"`
+	UserVersion   int64    `json:"user_version"`
+	GlobalVersion int64    `json:"global_version"`
+
+	// Untuk mempersempit ke scope token (ForScope)
+	RoleScopes       map[string]string   `json:"role_scopes"`
+	ScopePermissions map[string][]string `json:"scope_permissions"`
+}
+
+// GetAuthContext - ambil auth context dari Redis; cache miss atau versi berubah → load dari DB
+func GetAuthContext(ctx context.Context, db *pgxpool.Pool, userID int) (*AuthContext, error) {
+	if cache.Client == nil {
+		return loadAuthContext(ctx, db, userID)
+	}
+
+	var cached AuthContext
+	found, userVersion, globalVersion, err := cache.GetAuthContext(userID, &cached)
+	if err != nil {
+		return loadAuthContext(ctx, db, userID)
+	}
+	if found && cached.UserVersion == userVersion && cached.GlobalVersion == globalVersion {
+		return &cached, nil
+	}
+
+	// Versi dibaca sebelum load: invalidasi yang terjadi selama load membuat entry ini langsung basi
+	ac, err := loadAuthContext(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	ac.UserVersion = userVersion
+	ac.GlobalVersion = globalVersion
+	if err := cache.SetAuthContext(userID, ac); err != nil {
+		log.Printf("Cache auth context error: %v", err)
+	}
+	return ac, nil
+}
+
+func loadAuthContext(ctx context.Context, db *pgxpool.Pool, userID int) (*AuthContext, error) {
+	ac := &AuthContext{
+		UserID:           userID,
+		Roles:            []string{},
+		Scopes:           []string{},
+		Permissions:      []string{},
+		RoleScopes:       map[string]string{},
+		ScopePermissions: map[string][]string{},
+	}
+
+	err := db.QueryRow(ctx, `
+		SELECT is_active, COALESCE(is_banned, FALSE), (deleted_at IS NOT NULL)
+		FROM users WHERE id = $1`,
+		userID).Scan(&ac.IsActive, &ac.IsBanned, &ac.IsDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT r.name, COALESCE(r.scope, '') FROM user_roles ur
+		JOIN roles r ON ur.role_id = r.id
+		WHERE ur.user_id = $1 AND r.deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := map[string]bool{}
+	for rows.Next() {
+		var role, scope string
+		if err := rows.Scan(&role, &scope); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ac.Roles = append(ac.Roles, role)
+		ac.RoleScopes[role] = scope
+		if scope != "" && !scopes[scope] {
+			scopes[scope] = true
+			ac.Scopes = append(ac.Scopes, scope)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(ctx, `
+		SELECT DISTINCT COALESCE(r.scope, ''), p.name FROM user_roles ur
+		JOIN roles r ON ur.role_id = r.id
+		JOIN role_permissions rp ON ur.role_id = rp.role_id
+		JOIN permissions p ON rp.permission_id = p.id
+		WHERE ur.user_id = $1 AND r.deleted_at IS NULL AND p.deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	perms := map[string]bool{}
+	for rows.Next() {
+		var scope, perm string
+		if err := rows.Scan(&scope, &perm); err != nil {
+			return nil, err
+		}
+		ac.ScopePermissions[scope] = append(ac.ScopePermissions[scope], perm)
+		if !perms[perm] {
+			perms[perm] = true
+			ac.Permissions = append(ac.Permissions, perm)
+		}
+	}
+	return ac, rows.Err()
+}
+
+// ForScope - auth context yang hanya berisi role & permission dari satu scope.
+// Token login app tidak ikut membawa role dashboard user (dan sebaliknya).
+func (ac *AuthContext) ForScope(scope string) *AuthContext {
+	narrowed := *ac
+	narrowed.Roles = []string{}
+	narrowed.Scopes = []string{}
+	for _, role := range ac.Roles {
+		if ac.RoleScopes[role] == scope {
+			narrowed.Roles = append(narrowed.Roles, role)
+		}
+	}
+	if len(narrowed.Roles) > 0 {
+		narrowed.Scopes = []string{scope}
+	}
+	narrowed.Permissions = ac.ScopePermissions[scope]
+	if narrowed.Permissions == nil {
+		narrowed.Permissions = []string{}
+	}
+	return &narrowed
+}
+
+// IsSuperAdmin - super_admin punya semua akses
+func (ac *AuthContext) IsSuperAdmin() bool {
+	return ac.HasRole("super_admin")
+}
+
+// HasRole - user punya salah satu role
+func (ac *AuthContext) HasRole(roles ...string) bool {
+	for _, have := range ac.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasScope - user punya role dengan scope ini (app / dashboard)
+func (ac *AuthContext) HasScope(scope string) bool {
+	for _, s := range ac.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission - user punya permission, termasuk wildcard "module.*"
+func (ac *AuthContext) HasPermission(permission string) bool {
+	module := strings.Split(permission, ".")[0]
+	for _, p := range ac.Permissions {
+		if p == permission || p == module+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidateAuthContext - panggil setelah status atau role user berubah
+func InvalidateAuthContext(userID int) {
+	if cache.Client == nil {
+		return
+	}
+	if err := cache.BumpUserAuthVersion(userID); err != nil {
+		log.Printf("Invalidate auth context error: %v", err)
+	}
+}
+
+// InvalidateAllAuthContexts - panggil setelah definisi role / permission berubah
+func InvalidateAllAuthContexts() {
+	if cache.Client == nil {
+		return
+	}
+	if err := cache.BumpGlobalAuthVersion(); err != nil {
+		log.Printf("Invalidate auth contexts error: %v", err)
+	}
+}
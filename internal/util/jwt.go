@@ -8,6 +8,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"shopedia-api/internal/cache"
@@ -18,6 +19,7 @@ type TokenType string
 const (
 	TokenTypeAccess   TokenType = "access"
 	TokenTypeRegister TokenType = "register"
+	TokenTypeMFA      TokenType = "mfa_pending"
 )
 
 type TokenClaims struct {
@@ -25,16 +27,22 @@ type TokenClaims struct {
 	UserID   int       `json:"user_id"`
 	UserUUID string    `json:"user_uuid"`
 	Roles    []string  `json:"roles,omitempty"`
+	Scopes   []string  `json:"scopes,omitempty"`
+	Scope    string    `json:"scope,omitempty"` // scope login token ini (app / dashboard)
 	Type     TokenType `json:"type"`
 }
 
-// GenerateAccessToken - untuk login, dipakai di semua endpoint yang butuh authorization
-func GenerateAccessToken(userID int, userUUID string, roles []string, expires time.Time) (string, string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", "", errors.New("JWT_SECRET not set")
+// LoginScope - scope role yang boleh login di mode ini ("app" → app, "admin" → dashboard)
+func LoginScope(mode string) string {
+	if mode == "admin" {
+		return "dashboard"
 	}
+	return "app"
+}
 
+// GenerateAccessToken - untuk login, dipakai di semua endpoint yang butuh authorization.
+// roles & scopes berisi semua role user; scope membatasi role yang berlaku untuk token ini.
+func GenerateAccessToken(userID int, userUUID string, roles, scopes []string, scope string, expires time.Time) (string, string, error) {
 	now := time.Now()
 	jti := uuid.New().String()
 
@@ -48,11 +56,12 @@ func GenerateAccessToken(userID int, userUUID string, roles []string, expires ti
 		UserID:   userID,
 		UserUUID: userUUID,
 		Roles:    roles,
+		Scopes:   scopes,
+		Scope:    scope,
 		Type:     TokenTypeAccess,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -62,11 +71,32 @@ func GenerateAccessToken(userID int, userUUID string, roles []string, expires ti
 
 // GenerateRegisterToken - untuk register, hanya dipakai untuk validasi/request OTP
 func GenerateRegisterToken(userID int, userUUID string, expires time.Time) (string, string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", "", errors.New("JWT_SECRET not set")
+	now := time.Now()
+	jti := uuid.New().String()
+
+	claims := TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+		UserID:   userID,
+		UserUUID: userUUID,
+		Type:     TokenTypeRegister,
+	}
+
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return "", "", err
 	}
 
+	return tokenString, jti, nil
+}
+
+// GenerateMFAPendingToken - untuk login dashboard yang password-nya sudah valid
+// tapi masih harus verifikasi TOTP; hanya diterima endpoint login 2FA
+func GenerateMFAPendingToken(userID int, userUUID string, expires time.Time) (string, string, error) {
 	now := time.Now()
 	jti := uuid.New().String()
 
@@ -79,11 +109,10 @@ func GenerateRegisterToken(userID int, userUUID string, expires time.Time) (stri
 		},
 		UserID:   userID,
 		UserUUID: userUUID,
-		Type:     TokenTypeRegister,
+		Type:     TokenTypeMFA,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -91,14 +120,49 @@ func GenerateRegisterToken(userID int, userUUID string, expires time.Time) (stri
 	return tokenString, jti, nil
 }
 
-// ParseToken - parse dan validasi token
-func ParseToken(tokenString string) (*TokenClaims, error) {
+// signToken - tanda tangani dengan key aktif (RS256/EdDSA + header kid).
+// Token baru tidak pernah ditandatangani HS256.
+func signToken(claims TokenClaims) (string, error) {
+	key := activeSigningKey()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
+// legacyHS256Secret - JWT_SECRET untuk verifikasi token HS256 lama, hanya sampai batas
+// JWT_LEGACY_HS256_UNTIL (RFC3339); tanpa batas atau sesudahnya HS256 ditolak
+func legacyHS256Secret() (string, error) {
 	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return nil, errors.New("JWT_SECRET not set")
+	until, err := time.Parse(time.RFC3339, os.Getenv("JWT_LEGACY_HS256_UNTIL"))
+	if secret == "" || err != nil || time.Now().After(until) {
+		return "", errors.New("legacy HS256 tokens are no longer accepted")
 	}
+	return secret, nil
+}
 
+// ParseToken - parse dan validasi token
+// Token dengan kid diverifikasi dengan public key dari jwt_keys (aktif atau retired yang masih berlaku).
+// Token tanpa kid (HS256 lama) hanya diterima sampai JWT_LEGACY_HS256_UNTIL.
+func ParseToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			key := verificationKey(kid)
+			if key == nil {
+				return nil, errors.New("unknown signing key")
+			}
+			if token.Method.Alg() != key.alg {
+				return nil, errors.New("unexpected signing method")
+			}
+			return key.publicKey, nil
+		}
+
+		secret, err := legacyHS256Secret()
+		if err != nil {
+			return nil, err
+		}
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
@@ -116,20 +180,13 @@ func ParseToken(tokenString string) (*TokenClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// IsTokenRevoked - cek apakah token sudah di-revoke
-func IsTokenRevoked(ctx context.Context, db *pgxpool.Pool, jti string) bool {
-	var exists bool
-	err := db.QueryRow(ctx,
-		`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`,
-		jti).Scan(&exists)
-	if err != nil {
-		return true // fail-safe: anggap revoked jika error
-	}
-	return exists
-}
-
-// RevokeToken - revoke token dengan menyimpan jti ke database
+// RevokeToken - revoke token dengan menyimpan jti ke database.
+// Session Redis untuk jti ini selalu ikut dihapus: CheckAccessToken menganggap
+// token dengan session Redis aktif belum di-revoke.
 func RevokeToken(ctx context.Context, db *pgxpool.Pool, jti string, userID int, expiresAt time.Time) error {
+	if cache.Client != nil {
+		_ = cache.DeleteSession(jti)
+	}
 	_, err := db.Exec(ctx,
 		`INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3)
 		 ON CONFLICT (jti) DO NOTHING`,
@@ -137,6 +194,41 @@ func RevokeToken(ctx context.Context, db *pgxpool.Pool, jti string, userID int,
 	return err
 }
 
+var (
+	ErrTokenRevoked   = errors.New("token has been revoked")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// CheckAccessToken - token belum di-revoke dan session masih aktif.
+// Session di Redis cukup (revoke selalu menghapusnya); cache miss → satu query ke DB.
+func CheckAccessToken(ctx context.Context, db *pgxpool.Pool, userID int, jti string) error {
+	if cache.Client != nil {
+		session, err := cache.GetSession(jti)
+		if err == nil && session != nil {
+			if session.UserID != userID {
+				return ErrSessionExpired
+			}
+			return nil
+		}
+	}
+
+	var revoked, active bool
+	err := db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $2),
+		       EXISTS(SELECT 1 FROM active_sessions WHERE user_id = $1 AND jti = $2)`,
+		userID, jti).Scan(&revoked, &active)
+	if err != nil {
+		return ErrTokenRevoked // fail-safe: anggap revoked jika error
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	if !active {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
 // RevokeAllUserTokens - revoke semua token user (untuk force logout)
 func RevokeAllUserTokens(ctx context.Context, db *pgxpool.Pool, userID int) error {
 	_, err := db.Exec(ctx,
@@ -149,40 +241,110 @@ func RevokeAllUserTokens(ctx context.Context, db *pgxpool.Pool, userID int) erro
 func CleanupExpiredTokens(ctx context.Context, db *pgxpool.Pool) error {
 	_, err := db.Exec(ctx,
 		`DELETE FROM revoked_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx,
+		`DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx,
+		`DELETE FROM oidc_states WHERE expires_at < NOW()`)
+	if err != nil {
+		return err
+	}
+
+	// Login gagal hanya disimpan 90 hari; login sukses disimpan sebagai riwayat device/negara
+	_, err = db.Exec(ctx,
+		`DELETE FROM login_events WHERE success = FALSE AND created_at < NOW() - INTERVAL '90 days'`)
+	if err != nil {
+		return err
+	}
+
+	// Session yang access token-nya expired dan tidak punya refresh token hidup
+	_, err = db.Exec(ctx, `
+		DELETE FROM active_sessions s
+		WHERE s.expires_at < NOW() AND NOT EXISTS (
+			SELECT 1 FROM refresh_tokens r
+			WHERE r.family_id = s.family_id AND r.revoked_at IS NULL AND r.rotated_at IS NULL AND r.expires_at > NOW()
+		)`)
 	return err
 }
 
 // ============================================
-// Single Active Session Functions
+// Active Session Functions (multi-device)
 // ============================================
 
-// SetActiveSession - simpan session aktif baru dan revoke session lama
-func SetActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, jti string, expiresAt time.Time, email string, role string) error {
-	// Ambil JTI lama jika ada
-	var oldJTI string
-	var oldExpiresAt time.Time
-	err := db.QueryRow(ctx,
-		`SELECT jti, expires_at FROM active_sessions WHERE user_id = $1`,
-		userID).Scan(&oldJTI, &oldExpiresAt)
-
-	// Jika ada session lama, revoke tokennya dan hapus dari Redis
-	if err == nil && oldJTI != "" {
-		_ = RevokeToken(ctx, db, oldJTI, userID, oldExpiresAt)
-		// Delete old session from Redis
-		if cache.Client != nil {
-			_ = cache.DeleteSession(oldJTI)
+// SessionInfo - info device yang disimpan per session
+type SessionInfo struct {
+	FamilyID   string // family refresh token milik session ini
+	Mode       string // app, admin
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// activeSessionRow - session yang akan di-revoke
+type activeSessionRow struct {
+	id        int
+	jti       string
+	familyID  *string
+	expiresAt time.Time
+}
+
+// CreateSession - simpan session baru lalu tegakkan batas jumlah session.
+// Mode "admin" selalu single-session; mode "app" memakai roles.max_sessions terbesar dari role app user.
+// Session paling lama di atas batas di-revoke (access token + refresh family).
+func CreateSession(ctx context.Context, db *pgxpool.Pool, userID int, jti string, expiresAt time.Time, email string, roles []string, info SessionInfo) error {
+	maxSessions := 1
+	if info.Mode != "admin" {
+		err := db.QueryRow(ctx,
+			`SELECT COALESCE(MAX(max_sessions), 1) FROM roles
+			 WHERE name = ANY($1) AND scope = 'app' AND deleted_at IS NULL`,
+			roles).Scan(&maxSessions)
+		if err != nil || maxSessions < 1 {
+			maxSessions = 1
 		}
 	}
 
-	// Insert atau update session baru di database
-	_, err = db.Exec(ctx,
-		`INSERT INTO active_sessions (user_id, jti, expires_at)
-		 VALUES ($1, $2, $3)
-		 ON CONFLICT (user_id) DO UPDATE SET jti = $2, expires_at = $3, created_at = NOW()`,
-		userID, jti, expiresAt)
+	// Buang session basi (access token & refresh token sudah mati) supaya tidak ikut dihitung
+	_, _ = db.Exec(ctx, `
+		DELETE FROM active_sessions s
+		WHERE s.user_id = $1 AND s.expires_at < NOW() AND NOT EXISTS (
+			SELECT 1 FROM refresh_tokens r
+			WHERE r.family_id = s.family_id AND r.revoked_at IS NULL AND r.rotated_at IS NULL AND r.expires_at > NOW()
+		)`, userID)
+
+	_, err := db.Exec(ctx,
+		`INSERT INTO active_sessions (user_id, jti, expires_at, family_id, mode, device_name, user_agent, ip_address, last_seen_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())`,
+		userID, jti, expiresAt, info.FamilyID, info.Mode, info.DeviceName, info.UserAgent, info.IPAddress)
+	if err != nil {
+		return err
+	}
+
+	// Session di luar batas (per mode, urut terbaru dulu) di-revoke
+	rows, err := db.Query(ctx, `
+		SELECT id, jti::TEXT, family_id::TEXT, expires_at FROM active_sessions
+		WHERE user_id = $1 AND mode = $2
+		ORDER BY created_at DESC, id DESC
+		OFFSET $3`,
+		userID, info.Mode, maxSessions)
+	if err != nil {
+		return err
+	}
+	evicted, err := scanActiveSessions(rows)
 	if err != nil {
 		return err
 	}
+	for _, s := range evicted {
+		if err := revokeActiveSession(ctx, db, userID, s); err != nil {
+			return err
+		}
+	}
 
 	// Store session in Redis for fast lookup
 	if cache.Client != nil {
@@ -190,7 +352,8 @@ func SetActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, jti str
 			UserID:    userID,
 			JTI:       jti,
 			Email:     email,
-			Role:      role,
+			Roles:     roles,
+			Scope:     LoginScope(info.Mode),
 			CreatedAt: time.Now(),
 		}
 		_ = cache.SetSession(jti, sessionData)
@@ -199,46 +362,140 @@ func SetActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, jti str
 	return nil
 }
 
-// IsActiveSession - cek apakah JTI adalah session yang aktif
-func IsActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, jti string) bool {
-	// Check Redis first for faster lookup
+// RotateSession - ganti access token session milik family (dipanggil saat refresh).
+// Access token lama di-revoke, device & created_at session tetap.
+func RotateSession(ctx context.Context, db *pgxpool.Pool, userID int, familyID string, jti string, expiresAt time.Time, email string, roles []string, mode string, ipAddress string) error {
+	var oldJTI string
+	var oldExpiresAt time.Time
+	err := db.QueryRow(ctx, `
+		SELECT jti::TEXT, expires_at FROM active_sessions
+		WHERE user_id = $1 AND family_id = $2`,
+		userID, familyID).Scan(&oldJTI, &oldExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `
+		UPDATE active_sessions SET jti = $1, expires_at = $2, ip_address = $3, last_seen_at = NOW()
+		WHERE user_id = $4 AND family_id = $5`,
+		jti, expiresAt, ipAddress, userID, familyID)
+	if err != nil {
+		return err
+	}
+
+	_ = RevokeToken(ctx, db, oldJTI, userID, oldExpiresAt)
+
 	if cache.Client != nil {
-		session, err := cache.GetSession(jti)
-		if err == nil && session != nil {
-			return session.UserID == userID
+		_ = cache.DeleteSession(oldJTI)
+		sessionData := &cache.SessionData{
+			UserID:    userID,
+			JTI:       jti,
+			Email:     email,
+			Roles:     roles,
+			Scope:     LoginScope(mode),
+			CreatedAt: time.Now(),
 		}
+		_ = cache.SetSession(jti, sessionData)
 	}
 
-	// Fallback to database
-	var activeJTI string
-	err := db.QueryRow(ctx,
-		`SELECT jti FROM active_sessions WHERE user_id = $1`,
-		userID).Scan(&activeJTI)
+	return nil
+}
+
+// RevokeSessionByUUID - revoke satu session user (DELETE /sessions/:id)
+func RevokeSessionByUUID(ctx context.Context, db *pgxpool.Pool, userID int, sessionUUID string) error {
+	rows, err := db.Query(ctx, `
+		SELECT id, jti::TEXT, family_id::TEXT, expires_at FROM active_sessions
+		WHERE user_id = $1 AND uuid = $2`,
+		userID, sessionUUID)
+	if err != nil {
+		return err
+	}
+	sessions, err := scanActiveSessions(rows)
 	if err != nil {
-		return false
+		return err
 	}
-	return activeJTI == jti
+	if len(sessions) == 0 {
+		return pgx.ErrNoRows
+	}
+	return revokeActiveSession(ctx, db, userID, sessions[0])
 }
 
 // ClearActiveSession - hapus session aktif (untuk logout)
-// jti parameter is optional - if empty, will query from database
+// jti kosong = hapus semua session user (logout-all, ban, reset password)
+// Refresh token ikut di-revoke: family milik jti, atau semua milik user jika jti kosong
 func ClearActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, jti string) error {
-	// If JTI not provided, try to get it from database for Redis cleanup
+	var err error
+	if jti == "" {
+		err = RevokeUserRefreshTokens(ctx, db, userID)
+	} else {
+		err = RevokeRefreshByAccessJTI(ctx, db, jti)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Ambil JTI session untuk cleanup Redis
 	if cache.Client != nil {
-		sessionJTI := jti
-		if sessionJTI == "" {
-			_ = db.QueryRow(ctx,
-				`SELECT jti FROM active_sessions WHERE user_id = $1`,
-				userID).Scan(&sessionJTI)
-		}
-		if sessionJTI != "" {
-			_ = cache.DeleteSession(sessionJTI)
+		if jti != "" {
+			_ = cache.DeleteSession(jti)
+		} else {
+			rows, err := db.Query(ctx,
+				`SELECT jti::TEXT FROM active_sessions WHERE user_id = $1`, userID)
+			if err == nil {
+				var jtis []string
+				for rows.Next() {
+					var sessionJTI string
+					if rows.Scan(&sessionJTI) == nil {
+						jtis = append(jtis, sessionJTI)
+					}
+				}
+				rows.Close()
+				for _, sessionJTI := range jtis {
+					_ = cache.DeleteSession(sessionJTI)
+				}
+			}
 		}
 	}
 
 	// Delete from database
-	_, err := db.Exec(ctx,
-		`DELETE FROM active_sessions WHERE user_id = $1`,
-		userID)
+	if jti == "" {
+		_, err = db.Exec(ctx,
+			`DELETE FROM active_sessions WHERE user_id = $1`,
+			userID)
+	} else {
+		_, err = db.Exec(ctx,
+			`DELETE FROM active_sessions WHERE user_id = $1 AND jti = $2`,
+			userID, jti)
+	}
+	return err
+}
+
+func scanActiveSessions(rows pgx.Rows) ([]activeSessionRow, error) {
+	defer rows.Close()
+	var sessions []activeSessionRow
+	for rows.Next() {
+		var s activeSessionRow
+		if err := rows.Scan(&s.id, &s.jti, &s.familyID, &s.expiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// revokeActiveSession - revoke access token, refresh family, dan hapus session
+func revokeActiveSession(ctx context.Context, db *pgxpool.Pool, userID int, s activeSessionRow) error {
+	if err := RevokeToken(ctx, db, s.jti, userID, s.expiresAt); err != nil {
+		return err
+	}
+	if s.familyID != nil {
+		if err := RevokeRefreshFamily(ctx, db, *s.familyID); err != nil {
+			return err
+		}
+	}
+	if cache.Client != nil {
+		_ = cache.DeleteSession(s.jti)
+	}
+	_, err := db.Exec(ctx, `DELETE FROM active_sessions WHERE id = $1`, s.id)
 	return err
 }
@@ -0,0 +1,439 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ============================================
+// JWT Signing Keys (RS256 / EdDSA)
+// ============================================
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// DefaultKeyRetention - key lama tetap dipakai untuk verifikasi selama ini setelah rotasi.
+	// Harus lebih lama dari umur token JWT terpanjang (access token 15 menit).
+	DefaultKeyRetention = 24 * time.Hour
+
+	keyReloadInterval = time.Minute
+	keyReloadCooldown = 10 * time.Second
+
+	// encryptedKeyPrefix - private_key di jwt_keys yang sudah dienkripsi dengan KEK (AES-256-GCM)
+	encryptedKeyPrefix = "enc:v1:"
+)
+
+type signingKey struct {
+	kid        string
+	alg        string
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+type keyStore struct {
+	mu       sync.RWMutex
+	db       *pgxpool.Pool
+	active   *signingKey
+	keys     map[string]*signingKey
+	loadedAt time.Time
+}
+
+var signingKeys = &keyStore{keys: map[string]*signingKey{}}
+
+// SigningAlgorithm - algoritma untuk key baru dari env JWT_SIGNING_ALG (default EdDSA)
+func SigningAlgorithm() string {
+	if os.Getenv("JWT_SIGNING_ALG") == AlgRS256 {
+		return AlgRS256
+	}
+	return AlgEdDSA
+}
+
+// InitSigningKeys - load key dari jwt_keys; buat key pertama jika belum ada yang aktif
+func InitSigningKeys(ctx context.Context, db *pgxpool.Pool) error {
+	signingKeys.mu.Lock()
+	signingKeys.db = db
+	signingKeys.mu.Unlock()
+
+	if err := ReloadSigningKeys(ctx); err != nil {
+		return err
+	}
+
+	signingKeys.mu.RLock()
+	hasActive := signingKeys.active != nil
+	signingKeys.mu.RUnlock()
+	if hasActive {
+		return nil
+	}
+
+	kid, err := RotateSigningKey(ctx, db, SigningAlgorithm(), DefaultKeyRetention)
+	if err != nil {
+		// Instance lain mungkin membuat key di saat bersamaan (unique index status aktif)
+		log.Printf("Create initial signing key failed, reloading: %v", err)
+	} else {
+		log.Printf("Created initial JWT signing key %s", kid)
+	}
+	if err := ReloadSigningKeys(ctx); err != nil {
+		return err
+	}
+
+	// Tanpa key aktif tidak ada token yang bisa dibuat (tidak ada fallback HS256)
+	if activeSigningKey() == nil {
+		return errors.New("no active JWT signing key (check JWT_KEY_ENCRYPTION_KEY)")
+	}
+	return nil
+}
+
+// RunSigningKeyRefresh - reload key berkala supaya rotasi dari instance/CLI lain ikut terpakai
+func RunSigningKeyRefresh(ctx context.Context) {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ReloadSigningKeys(ctx); err != nil {
+				log.Printf("Reload signing keys failed: %v", err)
+			}
+		}
+	}
+}
+
+// ReloadSigningKeys - baca ulang key aktif + key retired yang masih dalam masa verifikasi
+func ReloadSigningKeys(ctx context.Context) error {
+	signingKeys.mu.RLock()
+	db := signingKeys.db
+	signingKeys.mu.RUnlock()
+	if db == nil {
+		return errors.New("signing keys not initialized")
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT kid, algorithm, private_key, status
+		FROM jwt_keys
+		WHERE status = 'active' OR verify_until > NOW()
+		ORDER BY created_at`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keys := map[string]*signingKey{}
+	var active *signingKey
+	plaintext := map[string]string{} // kid → PEM yang belum dienkripsi (key dari sebelum KEK)
+	for rows.Next() {
+		var kid, alg, storedKey, status string
+		if err := rows.Scan(&kid, &alg, &storedKey, &status); err != nil {
+			return err
+		}
+		privatePEM, err := decryptPrivateKey(kid, storedKey)
+		if err != nil {
+			log.Printf("Skip signing key %s: %v", kid, err)
+			continue
+		}
+		key, err := parseSigningKey(kid, alg, privatePEM)
+		if err != nil {
+			log.Printf("Skip invalid signing key %s: %v", kid, err)
+			continue
+		}
+		keys[kid] = key
+		if status == "active" {
+			active = key
+		}
+		if !strings.HasPrefix(storedKey, encryptedKeyPrefix) {
+			plaintext[kid] = storedKey
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	// Key lama yang masih tersimpan plaintext dienkripsi di tempat
+	for kid, privatePEM := range plaintext {
+		encrypted, err := encryptPrivateKey(kid, privatePEM)
+		if err != nil {
+			log.Printf("Signing key %s is stored unencrypted: %v", kid, err)
+			continue
+		}
+		_, err = db.Exec(ctx, `UPDATE jwt_keys SET private_key = $1 WHERE kid = $2 AND private_key = $3`,
+			encrypted, kid, privatePEM)
+		if err != nil {
+			log.Printf("Encrypt signing key %s failed: %v", kid, err)
+		}
+	}
+
+	signingKeys.mu.Lock()
+	signingKeys.keys = keys
+	signingKeys.active = active
+	signingKeys.loadedAt = time.Now()
+	signingKeys.mu.Unlock()
+	return nil
+}
+
+// RotateSigningKey - buat key aktif baru; key aktif lama jadi retired dan tetap
+// bisa memverifikasi token sampai verify_until, jadi tidak ada user yang ter-logout
+func RotateSigningKey(ctx context.Context, db *pgxpool.Pool, alg string, retention time.Duration) (string, error) {
+	privatePEM, publicPEM, err := GenerateSigningKey(alg)
+	if err != nil {
+		return "", err
+	}
+	kid := uuid.New().String()
+
+	// Private key hanya disimpan terenkripsi, dump / backup DB tidak membuka signing key
+	encryptedKey, err := encryptPrivateKey(kid, privatePEM)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE jwt_keys SET status = 'retired', retired_at = NOW(), verify_until = $1
+		WHERE status = 'active'`,
+		time.Now().Add(retention))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO jwt_keys (kid, algorithm, private_key, public_key, status)
+		VALUES ($1, $2, $3, $4, 'active')`,
+		kid, alg, encryptedKey, publicPEM)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// GenerateSigningKey - buat key pair baru, return private key (PKCS#8) dan public key (PKIX) dalam PEM
+func GenerateSigningKey(alg string) (string, string, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", "", fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return string(privatePEM), string(publicPEM), nil
+}
+
+// keyEncryptionKey - KEK AES-256 dari env JWT_KEY_ENCRYPTION_KEY (32 byte, base64)
+func keyEncryptionKey() ([]byte, error) {
+	encoded := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY not set")
+	}
+	kek, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(kek) != 32 {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+	return kek, nil
+}
+
+// keyCipher - AES-256-GCM dari KEK
+func keyCipher() (cipher.AEAD, error) {
+	kek, err := keyEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptPrivateKey - enkripsi PEM private key dengan KEK; kid dipakai sebagai associated data
+// sehingga ciphertext tidak bisa dipindah ke baris key lain
+func encryptPrivateKey(kid, privatePEM string) (string, error) {
+	gcm, err := keyCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(privatePEM), []byte(kid))
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPrivateKey - kebalikan encryptPrivateKey; nilai tanpa prefix (key lama) dikembalikan apa adanya
+func decryptPrivateKey(kid, storedKey string) (string, error) {
+	if !strings.HasPrefix(storedKey, encryptedKeyPrefix) {
+		return storedKey, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(storedKey, encryptedKeyPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := keyCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted key too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	privatePEM, err := gcm.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return "", errors.New("decrypt private key failed (wrong JWT_KEY_ENCRYPTION_KEY?)")
+	}
+	return string(privatePEM), nil
+}
+
+func parseSigningKey(kid, alg, privatePEM string) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key is not a signer")
+	}
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			return nil, errors.New("algorithm does not match key type")
+		}
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			return nil, errors.New("algorithm does not match key type")
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return &signingKey{kid: kid, alg: alg, privateKey: signer, publicKey: signer.Public()}, nil
+}
+
+func (k *signingKey) method() jwt.SigningMethod {
+	if k.alg == AlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// activeSigningKey - key untuk menandatangani token baru (nil = belum ada key yang di-load)
+func activeSigningKey() *signingKey {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+	return signingKeys.active
+}
+
+// verificationKey - cari key berdasarkan kid; kid tidak dikenal → reload dari DB
+// (dibatasi cooldown) karena bisa jadi baru dirotasi oleh instance lain
+func verificationKey(kid string) *signingKey {
+	signingKeys.mu.RLock()
+	key := signingKeys.keys[kid]
+	stale := time.Since(signingKeys.loadedAt) > keyReloadCooldown
+	hasDB := signingKeys.db != nil
+	signingKeys.mu.RUnlock()
+
+	if key != nil || !stale || !hasDB {
+		return key
+	}
+
+	if err := ReloadSigningKeys(context.Background()); err != nil {
+		log.Printf("Reload signing keys failed: %v", err)
+		return nil
+	}
+
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+	return signingKeys.keys[kid]
+}
+
+// ============================================
+// JWKS
+// ============================================
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS - public key semua key yang masih bisa memverifikasi token
+func JWKS() []JWK {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	jwks := []JWK{}
+	for _, k := range signingKeys.keys {
+		jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.alg}
+		switch pub := k.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/cache"
+)
+
+// ============================================
+// Per-account Login Lockout
+// ============================================
+
+const (
+	// LoginLockThreshold - jumlah gagal login (dalam 24 jam) sebelum akun mulai dikunci
+	LoginLockThreshold = 5
+
+	loginLockBase = time.Minute
+	loginLockMax  = 24 * time.Hour
+)
+
+// LoginLockDuration - exponential backoff: gagal ke-5 → 1 menit, ke-6 → 2 menit, ... maks 24 jam
+func LoginLockDuration(failures int) time.Duration {
+	if failures < LoginLockThreshold {
+		return 0
+	}
+	d := loginLockBase
+	for i := LoginLockThreshold; i < failures; i++ {
+		d *= 2
+		if d >= loginLockMax {
+			return loginLockMax
+		}
+	}
+	return d
+}
+
+func loginLockKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginLockedUntil - cek lock akun: Redis dulu (berlaku juga untuk email yang tidak
+// terdaftar, jadi lockout tidak membocorkan email mana yang ada), lalu users.locked_until
+func LoginLockedUntil(ctx context.Context, db *pgxpool.Pool, email string) (time.Time, bool) {
+	key := loginLockKey(email)
+
+	if cache.Client != nil {
+		until, err := cache.GetLoginLock(key)
+		if err == nil && time.Now().Before(until) {
+			return until, true
+		}
+	}
+
+	var until *time.Time
+	err := db.QueryRow(ctx,
+		`SELECT locked_until FROM users WHERE LOWER(email) = $1`, key).Scan(&until)
+	if err == nil && until != nil && time.Now().Before(*until) {
+		return *until, true
+	}
+	return time.Time{}, false
+}
+
+// RecordLoginFailure - tambah hitungan gagal login (Redis per email, Postgres per user)
+// dan kunci akun jika sudah melewati threshold. Return waktu lock berakhir (zero jika tidak dikunci).
+// userID 0 = email tidak terdaftar, hanya dihitung di Redis.
+func RecordLoginFailure(ctx context.Context, db *pgxpool.Pool, userID int, email string) time.Time {
+	key := loginLockKey(email)
+	failures := 0
+
+	if cache.Client != nil {
+		count, err := cache.IncrementLoginFailures(key)
+		if err != nil {
+			log.Printf("Login failure counter error: %v", err)
+		}
+		failures = count
+	}
+
+	if userID > 0 {
+		// Hitungan di-reset jika gagal login terakhir sudah lebih dari 24 jam lalu
+		var count int
+		err := db.QueryRow(ctx, `
+			UPDATE users SET
+				failed_login_attempts = CASE
+					WHEN last_failed_login_at IS NULL OR last_failed_login_at < NOW() - INTERVAL '24 hours' THEN 1
+					ELSE failed_login_attempts + 1
+				END,
+				last_failed_login_at = NOW()
+			WHERE id = $1
+			RETURNING failed_login_attempts`, userID).Scan(&count)
+		if err != nil {
+			log.Printf("Login failure update error: %v", err)
+		} else if count > failures {
+			failures = count
+		}
+	}
+
+	d := LoginLockDuration(failures)
+	if d == 0 {
+		return time.Time{}
+	}
+	until := time.Now().Add(d)
+
+	if cache.Client != nil {
+		if err := cache.SetLoginLock(key, until); err != nil {
+			log.Printf("Login lock cache error: %v", err)
+		}
+	}
+	if userID > 0 {
+		if _, err := db.Exec(ctx, `UPDATE users SET locked_until = $1 WHERE id = $2`, until, userID); err != nil {
+			log.Printf("Login lock update error: %v", err)
+		}
+	}
+	return until
+}
+
+// ResetLoginFailures - hapus hitungan gagal login & lock (login sukses, reset password)
+func ResetLoginFailures(ctx context.Context, db *pgxpool.Pool, userID int, email string) {
+	if cache.Client != nil {
+		if err := cache.ClearLoginFailures(loginLockKey(email)); err != nil {
+			log.Printf("Clear login failures cache error: %v", err)
+		}
+	}
+	_, err := db.Exec(ctx, `
+		UPDATE users SET failed_login_attempts = 0, last_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1 AND (failed_login_attempts > 0 OR locked_until IS NOT NULL)`, userID)
+	if err != nil {
+		log.Printf("Reset login failures error: %v", err)
+	}
+}
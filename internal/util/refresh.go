@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shopedia-api/internal/cache"
+)
+
+const (
+	AccessTokenTTL       = 15 * time.Minute
+	AppRefreshTokenTTL   = 30 * 24 * time.Hour
+	AdminRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// RefreshTokenTTL - masa berlaku refresh token per mode login ("app" / "admin")
+func RefreshTokenTTL(mode string) time.Duration {
+	if mode == "admin" {
+		return AdminRefreshTokenTTL
+	}
+	return AppRefreshTokenTTL
+}
+
+// GenerateRefreshToken - refresh token opaque (random 32 byte), return token dan hash-nya.
+// Yang disimpan di database hanya hash-nya.
+func GenerateRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken - sha256 hex dari refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RevokeRefreshFamily - revoke semua refresh token dalam satu family beserta
+// access token yang diterbitkan bersamanya (dipakai saat reuse terdeteksi)
+func RevokeRefreshFamily(ctx context.Context, db *pgxpool.Pool, familyID string) error {
+	rows, err := db.Query(ctx, `
+		SELECT user_id, access_jti::TEXT, access_expires_at
+		FROM refresh_tokens
+		WHERE family_id = $1 AND access_expires_at > NOW()`,
+		familyID)
+	if err != nil {
+		return err
+	}
+
+	type accessToken struct {
+		userID    int
+		jti       string
+		expiresAt time.Time
+	}
+	var tokens []accessToken
+	for rows.Next() {
+		var t accessToken
+		if err := rows.Scan(&t.userID, &t.jti, &t.expiresAt); err != nil {
+			rows.Close()
+			return err
+		}
+		tokens = append(tokens, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		if err := RevokeToken(ctx, db, t.jti, t.userID, t.expiresAt); err != nil {
+			return err
+		}
+		_, _ = db.Exec(ctx, `DELETE FROM active_sessions WHERE jti = $1`, t.jti)
+		if cache.Client != nil {
+			_ = cache.DeleteSession(t.jti)
+		}
+	}
+
+	_, err = db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID)
+	return err
+}
+
+// RevokeRefreshByAccessJTI - revoke family refresh token milik access token (untuk logout)
+func RevokeRefreshByAccessJTI(ctx context.Context, db *pgxpool.Pool, jti string) error {
+	_, err := db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND family_id IN (
+			SELECT family_id FROM refresh_tokens WHERE access_jti = $1
+		)`,
+		jti)
+	return err
+}
+
+// RevokeUserRefreshTokens - revoke semua refresh token user (logout-all, ban, reset password)
+func RevokeUserRefreshTokens(ctx context.Context, db *pgxpool.Pool, userID int) error {
+	_, err := db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID)
+	return err
+}
@@ -13,6 +13,7 @@ import (
 
 	"shopedia-api/internal/cache"
 	"shopedia-api/internal/handler"
+	"shopedia-api/internal/oidc"
 	"shopedia-api/internal/queue"
 	"shopedia-api/internal/repository"
 	utils "shopedia-api/internal/util"
@@ -38,6 +39,18 @@ func main() {
 		log.Fatal("Migration failed:", err)
 	}
 
+	// Load JWT signing keys (buat key pertama jika belum ada)
+	if err := utils.InitSigningKeys(context.Background(), db); err != nil {
+		log.Fatal("JWT signing keys failed:", err)
+	}
+	go utils.RunSigningKeyRefresh(context.Background())
+	fmt.Println("JWT signing keys loaded")
+
+	// Load OIDC providers (social login)
+	if providers := oidc.LoadFromEnv(); len(providers) > 0 {
+		fmt.Println("OIDC providers loaded:", providers)
+	}
+
 	// Initialize queue client
 	queue.InitClient()
 	defer queue.CloseClient()
@@ -55,6 +68,12 @@ func main() {
 	// Start cleanup goroutine
 	go startTokenCleanup(db)
 
+	// Start order expiry goroutine (batalkan order pending yang kedaluwarsa)
+	go startOrderExpiry(db)
+
+	// Start outbox relay (outbox → asynq)
+	go queue.RunOutboxRelay(context.Background(), db, 2*time.Second)
+
 	// Fiber instance
 	app := fiber.New()
 
@@ -88,3 +107,18 @@ func startTokenCleanup(db *pgxpool.Pool) {
 		}
 	}
 }
+
+// startOrderExpiry - goroutine untuk membatalkan order pending yang kedaluwarsa setiap 5 menit
+func startOrderExpiry(db *pgxpool.Pool) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := handler.ExpirePendingOrders(context.Background(), db)
+		if err != nil {
+			log.Printf("Order expiry failed: %v", err)
+		} else if expired > 0 {
+			log.Printf("Order expiry: %d order dibatalkan", expired)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+-- Migration: Orders
+-- Checkout grouping on top of transactions (1 transaction = 1 product line)
+
+-- ================================
+-- ORDERS
+-- ================================
+CREATE TABLE IF NOT EXISTS orders (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  buyer_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  total_price BIGINT NOT NULL DEFAULT 0 CHECK (total_price >= 0),
+  status VARCHAR(50) NOT NULL DEFAULT 'pending', -- pending, completed, cancelled
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_uuid ON orders(uuid);
+CREATE INDEX IF NOT EXISTS idx_orders_buyer_user_id ON orders(buyer_user_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+
+-- ================================
+-- UPDATE TRANSACTIONS TABLE
+-- ================================
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS uuid UUID DEFAULT gen_random_uuid();
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS seller_user_id INTEGER REFERENCES users(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS unit_price BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_uuid ON transactions(uuid);
+CREATE INDEX IF NOT EXISTS idx_transactions_order_id ON transactions(order_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_seller_user_id ON transactions(seller_user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
+
+COMMENT ON TABLE orders IS 'Satu checkout buyer, berisi satu atau lebih baris transactions';
+COMMENT ON COLUMN transactions.unit_price IS 'Harga produk saat checkout (snapshot)';
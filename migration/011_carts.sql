@@ -0,0 +1,34 @@
+-- Migration: Shopping Cart
+-- Server-side cart, 1 cart per user
+
+-- ================================
+-- CARTS
+-- ================================
+CREATE TABLE IF NOT EXISTS carts (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_uuid ON carts(uuid);
+
+-- ================================
+-- CART_ITEMS
+-- ================================
+CREATE TABLE IF NOT EXISTS cart_items (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  qty INTEGER NOT NULL CHECK (qty > 0),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(cart_id, product_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_uuid ON cart_items(uuid);
+CREATE INDEX IF NOT EXISTS idx_cart_items_cart_id ON cart_items(cart_id);
+
+COMMENT ON TABLE cart_items IS 'Item cart hanya menyimpan product + qty, harga dan stock selalu dibaca dari products';
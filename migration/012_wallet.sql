@@ -0,0 +1,19 @@
+-- Migration: Seller Wallet & Ledger
+-- balances = saldo seller, balance_logs = ledger (sumber kebenaran saldo)
+
+-- ================================
+-- UPDATE BALANCE_LOGS TABLE
+-- ================================
+ALTER TABLE balance_logs ADD COLUMN IF NOT EXISTS uuid UUID DEFAULT gen_random_uuid();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_logs_uuid ON balance_logs(uuid);
+CREATE INDEX IF NOT EXISTS idx_balance_logs_user_id ON balance_logs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_balance_logs_transaction_id ON balance_logs(transaction_id);
+
+-- ================================
+-- UPDATE ORDERS TABLE
+-- ================================
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+
+COMMENT ON COLUMN balance_logs.type IS 'in = saldo masuk, out = saldo keluar';
+COMMENT ON COLUMN balance_logs.amount IS 'Selalu positif, arah ditentukan oleh type';
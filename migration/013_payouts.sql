@@ -0,0 +1,36 @@
+-- Migration: Seller Payouts
+-- Request penarikan saldo seller + approval oleh finance
+
+-- ================================
+-- UPDATE BALANCE_LOGS TABLE
+-- ================================
+-- pending = dana ditahan (belum mengurangi balances), completed = sudah dibukukan, cancelled = hold dibatalkan
+ALTER TABLE balance_logs ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed';
+
+CREATE INDEX IF NOT EXISTS idx_balance_logs_status ON balance_logs(user_id, status);
+
+-- ================================
+-- PAYOUTS
+-- ================================
+CREATE TABLE IF NOT EXISTS payouts (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  balance_log_id INTEGER REFERENCES balance_logs(id) ON DELETE SET NULL,
+  amount BIGINT NOT NULL CHECK (amount > 0),
+  bank_name VARCHAR(100) NOT NULL,
+  bank_account_number VARCHAR(50) NOT NULL,
+  bank_account_name VARCHAR(255) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, approved, rejected
+  reject_reason TEXT,
+  reviewed_by INTEGER REFERENCES users(id),
+  reviewed_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payouts_uuid ON payouts(uuid);
+CREATE INDEX IF NOT EXISTS idx_payouts_user_id ON payouts(user_id);
+CREATE INDEX IF NOT EXISTS idx_payouts_status ON payouts(status);
+
+COMMENT ON TABLE payouts IS 'Request penarikan saldo seller, diproses oleh user dengan permission finance.payout';
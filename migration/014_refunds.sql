@@ -0,0 +1,28 @@
+-- Migration: Refunds
+-- Full/partial refund transaksi oleh finance
+
+-- ================================
+-- UPDATE TRANSACTIONS TABLE
+-- ================================
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded_qty INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
+
+-- ================================
+-- REFUNDS
+-- ================================
+CREATE TABLE IF NOT EXISTS refunds (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+  qty INTEGER NOT NULL CHECK (qty > 0),
+  amount BIGINT NOT NULL CHECK (amount >= 0),
+  reason TEXT NOT NULL,
+  refunded_by INTEGER REFERENCES users(id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_uuid ON refunds(uuid);
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction_id ON refunds(transaction_id);
+
+COMMENT ON TABLE refunds IS 'Riwayat refund per transaksi (satu transaksi bisa di-refund beberapa kali sampai qty habis)';
+COMMENT ON COLUMN transactions.refunded_qty IS 'Total qty yang sudah di-refund';
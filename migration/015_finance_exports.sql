@@ -0,0 +1,26 @@
+-- Migration: Finance Exports
+-- Hasil export finance yang diproses worker (export besar), file disimpan di DB
+-- agar bisa diunduh dari instance API mana pun
+
+-- ================================
+-- FINANCE_EXPORTS
+-- ================================
+CREATE TABLE IF NOT EXISTS finance_exports (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  requested_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  format VARCHAR(10) NOT NULL, -- csv, xlsx
+  filters JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, completed, failed
+  file_name VARCHAR(255),
+  file_data BYTEA,
+  error_message TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL '7 days')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_finance_exports_uuid ON finance_exports(uuid);
+CREATE INDEX IF NOT EXISTS idx_finance_exports_requested_by ON finance_exports(requested_by);
+
+COMMENT ON TABLE finance_exports IS 'Export finance async, link download dikirim via email saat selesai';
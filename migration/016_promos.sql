@@ -0,0 +1,73 @@
+-- Migration: Promotions & Vouchers
+-- Kode voucher (percentage/fixed), scoping kategori/produk, dan riwayat redemption
+
+-- ================================
+-- PROMOS
+-- ================================
+CREATE TABLE IF NOT EXISTS promos (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  code VARCHAR(50) NOT NULL, -- disimpan uppercase
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+  discount_value BIGINT NOT NULL CHECK (discount_value > 0),
+  max_discount BIGINT CHECK (max_discount > 0), -- batas atas untuk percentage
+  min_spend BIGINT NOT NULL DEFAULT 0 CHECK (min_spend >= 0),
+  usage_limit INTEGER CHECK (usage_limit > 0), -- NULL = unlimited
+  usage_per_user INTEGER CHECK (usage_per_user > 0), -- NULL = unlimited
+  used_count INTEGER NOT NULL DEFAULT 0,
+  starts_at TIMESTAMP,
+  ends_at TIMESTAMP,
+  is_active BOOLEAN DEFAULT TRUE,
+  created_by INTEGER REFERENCES users(id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promos_uuid ON promos(uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promos_code ON promos(code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_promos_deleted_at ON promos(deleted_at);
+
+-- ================================
+-- PROMO SCOPES
+-- ================================
+-- Tanpa baris scope = berlaku untuk semua produk
+CREATE TABLE IF NOT EXISTS promo_categories (
+  promo_id INTEGER NOT NULL REFERENCES promos(id) ON DELETE CASCADE,
+  category_id INTEGER NOT NULL REFERENCES product_categories(id) ON DELETE CASCADE,
+  PRIMARY KEY (promo_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS promo_products (
+  promo_id INTEGER NOT NULL REFERENCES promos(id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  PRIMARY KEY (promo_id, product_id)
+);
+
+-- ================================
+-- PROMO REDEMPTIONS
+-- ================================
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+  id SERIAL PRIMARY KEY,
+  promo_id INTEGER NOT NULL REFERENCES promos(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+  discount_amount BIGINT NOT NULL CHECK (discount_amount >= 0),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_promo_user ON promo_redemptions(promo_id, user_id);
+
+-- ================================
+-- UPDATE ORDERS TABLE
+-- ================================
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal_price BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_id INTEGER REFERENCES promos(id);
+
+UPDATE orders SET subtotal_price = total_price WHERE subtotal_price = 0;
+
+COMMENT ON TABLE promos IS 'Voucher code, redemption dikunci per promo (FOR UPDATE) saat checkout';
+COMMENT ON COLUMN orders.discount_amount IS 'Diskon voucher ditanggung platform, saldo seller tetap dari transactions.total_price';
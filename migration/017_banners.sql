@@ -0,0 +1,28 @@
+-- Migration: Banners
+-- Homepage banner dengan jadwal tayang, dikelola marketing
+
+-- ================================
+-- BANNERS
+-- ================================
+CREATE TABLE IF NOT EXISTS banners (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  title VARCHAR(255) NOT NULL,
+  image_url TEXT NOT NULL,
+  target_url TEXT,
+  position VARCHAR(50) NOT NULL DEFAULT 'home_top', -- home_top, home_middle, home_bottom, ...
+  priority INTEGER NOT NULL DEFAULT 0, -- lebih besar tampil lebih dulu
+  starts_at TIMESTAMP,
+  ends_at TIMESTAMP,
+  is_active BOOLEAN DEFAULT TRUE,
+  created_by INTEGER REFERENCES users(id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_banners_uuid ON banners(uuid);
+CREATE INDEX IF NOT EXISTS idx_banners_position ON banners(position, priority DESC);
+CREATE INDEX IF NOT EXISTS idx_banners_schedule ON banners(starts_at, ends_at) WHERE deleted_at IS NULL;
+
+COMMENT ON TABLE banners IS 'Homepage banner, public list di-cache Redis sampai batas jadwal berikutnya';
@@ -0,0 +1,48 @@
+-- Migration: Support Tickets
+-- Tiket bantuan end user dengan thread pesan, dikerjakan role support
+
+-- ================================
+-- TICKETS
+-- ================================
+CREATE TABLE IF NOT EXISTS tickets (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  subject VARCHAR(255) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, in_progress, escalated, closed
+  priority VARCHAR(20) NOT NULL DEFAULT 'normal', -- normal, high
+  transaction_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL,
+  product_id INTEGER REFERENCES products(id) ON DELETE SET NULL,
+  assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  escalation_reason TEXT,
+  escalated_by INTEGER REFERENCES users(id),
+  escalated_at TIMESTAMP,
+  closed_by INTEGER REFERENCES users(id),
+  closed_at TIMESTAMP,
+  last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_uuid ON tickets(uuid);
+CREATE INDEX IF NOT EXISTS idx_tickets_user_id ON tickets(user_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_status ON tickets(status, priority);
+CREATE INDEX IF NOT EXISTS idx_tickets_assigned_to ON tickets(assigned_to);
+
+-- ================================
+-- TICKET_MESSAGES
+-- ================================
+CREATE TABLE IF NOT EXISTS ticket_messages (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+  sender_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  is_staff BOOLEAN NOT NULL DEFAULT FALSE,
+  message TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ticket_messages_uuid ON ticket_messages(uuid);
+CREATE INDEX IF NOT EXISTS idx_ticket_messages_ticket_id ON ticket_messages(ticket_id, created_at);
+
+COMMENT ON TABLE tickets IS 'Tiket support, transisi dijaga permission support.respond/escalate/close';
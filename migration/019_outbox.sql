@@ -0,0 +1,25 @@
+-- Migration: Transactional Outbox
+-- Task asynq ditulis di transaksi yang sama dengan perubahan bisnis,
+-- lalu dipublish ke Redis oleh relay di API server
+
+-- ================================
+-- OUTBOX
+-- ================================
+CREATE TABLE IF NOT EXISTS outbox (
+  id BIGSERIAL PRIMARY KEY,
+  task_type VARCHAR(100) NOT NULL,
+  payload JSONB NOT NULL,
+  queue_name VARCHAR(20) NOT NULL DEFAULT 'default', -- critical, default, low
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, published, failed
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  available_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(available_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at ON outbox(published_at) WHERE status = 'published';
+
+COMMENT ON TABLE outbox IS 'Task asynq yang menunggu dipublish oleh outbox relay';
+COMMENT ON COLUMN outbox.available_at IS 'Relay baru mencoba publish setelah waktu ini (backoff saat Redis gagal)';
@@ -0,0 +1,29 @@
+-- Migration: Refresh Tokens
+-- Refresh token opaque (disimpan sebagai hash), dirotasi setiap dipakai.
+-- Satu family = satu rantai rotasi sejak login; reuse token lama me-revoke seluruh family.
+
+-- ================================
+-- REFRESH TOKENS
+-- ================================
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id UUID NOT NULL,
+  token_hash VARCHAR(64) NOT NULL, -- sha256 hex dari refresh token
+  mode VARCHAR(20) NOT NULL, -- app, admin
+  access_jti UUID NOT NULL, -- JTI access token yang diterbitkan bersama refresh token ini
+  access_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  rotated_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_access_jti ON refresh_tokens(access_jti);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+COMMENT ON TABLE refresh_tokens IS 'Refresh token DB-backed dengan rotasi dan reuse detection per family';
+COMMENT ON COLUMN refresh_tokens.rotated_at IS 'Diisi saat token ditukar; token yang sudah dirotasi lalu dipakai lagi = reuse';
@@ -0,0 +1,39 @@
+-- Migration: Multi-device Sessions
+-- active_sessions tidak lagi 1 row per user; satu row = satu device (satu refresh family).
+-- Batas jumlah session diatur per role lewat roles.max_sessions, login dashboard tetap single-session.
+
+-- ================================
+-- ACTIVE SESSIONS
+-- ================================
+ALTER TABLE active_sessions DROP CONSTRAINT IF EXISTS active_sessions_user_id_key;
+
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS uuid UUID DEFAULT gen_random_uuid();
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS family_id UUID;
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS mode VARCHAR(20) NOT NULL DEFAULT 'app'; -- app, admin
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS device_name VARCHAR(100);
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+ALTER TABLE active_sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- Session lama milik user dashboard dianggap login admin
+UPDATE active_sessions s SET mode = 'admin'
+WHERE EXISTS (
+  SELECT 1 FROM user_roles ur JOIN roles r ON ur.role_id = r.id
+  WHERE ur.user_id = s.user_id AND r.scope = 'dashboard'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_active_sessions_uuid ON active_sessions(uuid);
+CREATE INDEX IF NOT EXISTS idx_active_sessions_family_id ON active_sessions(family_id);
+
+COMMENT ON TABLE active_sessions IS 'Session aktif per device; batas jumlah per role (roles.max_sessions)';
+COMMENT ON COLUMN active_sessions.jti IS 'JTI access token terbaru session ini, diganti setiap refresh';
+
+-- ================================
+-- ROLES
+-- ================================
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS max_sessions INTEGER NOT NULL DEFAULT 5;
+
+-- Role dashboard tetap single-session (juga dipaksa di kode untuk login admin)
+UPDATE roles SET max_sessions = 1 WHERE scope = 'dashboard';
+
+COMMENT ON COLUMN roles.max_sessions IS 'Jumlah maksimal device login bersamaan untuk login app';
@@ -0,0 +1,38 @@
+-- Migration: Two-Factor Authentication (TOTP)
+-- TOTP RFC 6238 untuk akun dashboard, recovery code sekali pakai,
+-- dan flag per role untuk mewajibkan 2FA
+
+-- ================================
+-- USER TOTP
+-- ================================
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret VARCHAR(64) NOT NULL, -- base32
+  is_enabled BOOLEAN NOT NULL DEFAULT FALSE, -- TRUE setelah kode pertama dikonfirmasi
+  last_used_step BIGINT NOT NULL DEFAULT 0, -- time step terakhir yang dipakai, cegah replay kode yang sama
+  confirmed_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE user_totp IS 'Secret TOTP per user; baris dengan is_enabled FALSE = enrollment belum dikonfirmasi';
+
+-- ================================
+-- RECOVERY CODES
+-- ================================
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash VARCHAR(64) NOT NULL, -- sha256 hex
+  used_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id, code_hash);
+
+-- ================================
+-- ROLES
+-- ================================
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN roles.mfa_required IS 'User dengan role ini wajib 2FA untuk login dashboard';
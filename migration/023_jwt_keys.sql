@@ -0,0 +1,25 @@
+-- Migration: JWT Signing Keys
+-- Key pair RS256/EdDSA untuk tanda tangan JWT, diidentifikasi lewat header kid.
+-- Satu key aktif untuk signing; key retired tetap dipublish di JWKS sampai verify_until.
+
+-- ================================
+-- JWT KEYS
+-- ================================
+CREATE TABLE IF NOT EXISTS jwt_keys (
+  id SERIAL PRIMARY KEY,
+  kid VARCHAR(64) NOT NULL,
+  algorithm VARCHAR(10) NOT NULL, -- RS256, EdDSA
+  private_key TEXT NOT NULL, -- PEM PKCS#8
+  public_key TEXT NOT NULL, -- PEM PKIX
+  status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, retired
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  retired_at TIMESTAMP,
+  verify_until TIMESTAMP -- diisi saat retired; setelah ini token dengan kid ini ditolak
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jwt_keys_kid ON jwt_keys(kid);
+
+-- Hanya boleh ada satu key aktif
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jwt_keys_single_active ON jwt_keys(status) WHERE status = 'active';
+
+COMMENT ON TABLE jwt_keys IS 'Key pair penandatangan JWT; rotasi lewat go run ./cmd/jwtkeys rotate';
@@ -0,0 +1,37 @@
+-- Migration: OIDC Social Login
+-- Identitas eksternal (Google, Apple, dll) yang terhubung ke user,
+-- dan state authorization request (state, nonce, PKCE verifier)
+
+-- ================================
+-- USER IDENTITIES
+-- ================================
+CREATE TABLE IF NOT EXISTS user_identities (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider VARCHAR(50) NOT NULL, -- nama provider di OIDC_PROVIDERS
+  subject VARCHAR(255) NOT NULL, -- claim sub dari ID token
+  email VARCHAR(255), -- email dari provider saat terakhir login
+  last_login_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+COMMENT ON TABLE user_identities IS 'Akun OIDC yang ter-link ke user; link via email hanya jika provider menyatakan email_verified';
+
+-- ================================
+-- OIDC STATES
+-- ================================
+CREATE TABLE IF NOT EXISTS oidc_states (
+  state VARCHAR(64) PRIMARY KEY,
+  provider VARCHAR(50) NOT NULL,
+  nonce VARCHAR(64) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL, -- PKCE
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_states_expires_at ON oidc_states(expires_at);
+
+COMMENT ON TABLE oidc_states IS 'Authorization request yang sedang berjalan; dihapus saat callback (sekali pakai)';
@@ -0,0 +1,32 @@
+-- Migration: Login Security
+-- Lockout per akun (exponential backoff) dan riwayat semua percobaan login
+
+-- ================================
+-- USERS
+-- ================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+COMMENT ON COLUMN users.failed_login_attempts IS 'Gagal login berturut-turut dalam 24 jam; mulai 5 akun dikunci 1, 2, 4, ... menit (maks 24 jam)';
+
+-- ================================
+-- LOGIN EVENTS
+-- ================================
+CREATE TABLE IF NOT EXISTS login_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL jika email tidak terdaftar
+  email VARCHAR(255) NOT NULL,
+  mode VARCHAR(10) NOT NULL, -- app, admin
+  success BOOLEAN NOT NULL,
+  failure_reason VARCHAR(50), -- invalid_credentials, inactive, forbidden_role, locked, invalid_2fa
+  ip_address VARCHAR(45),
+  user_agent TEXT,
+  device_name VARCHAR(100),
+  country VARCHAR(2), -- ISO 3166-1 alpha-2 dari header proxy/CDN
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id ON login_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_events_user_success ON login_events(user_id) WHERE success = TRUE;
+CREATE INDEX IF NOT EXISTS idx_login_events_ip ON login_events(ip_address, created_at DESC);
@@ -0,0 +1,21 @@
+-- Migration: API Keys
+-- API key per user untuk integrasi (ERP, sync stock/harga) dengan scope eksplisit,
+-- masa berlaku, pelacakan pemakaian terakhir dan rate limit per key
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(20) NOT NULL, -- bagian awal key untuk ditampilkan, mis. shp_1a2b3c4d
+  key_hash VARCHAR(64) NOT NULL UNIQUE, -- sha256 hex, key asli hanya ditampilkan sekali
+  scopes TEXT[] NOT NULL DEFAULT '{}', -- product.read, product.write, wallet.read
+  rate_limit INTEGER NOT NULL DEFAULT 60, -- request per menit
+  expires_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP,
+  last_used_ip VARCHAR(45),
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,61 @@
+-- Migration: Seller Applications (KYC)
+-- End user mengajukan diri jadi seller (data toko, dokumen identitas, rekening),
+-- direview dashboard; approve → role seller lewat user_roles
+
+-- ================================
+-- SELLER_APPLICATIONS
+-- ================================
+CREATE TABLE IF NOT EXISTS seller_applications (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  shop_name VARCHAR(100) NOT NULL,
+  shop_description TEXT,
+  id_type VARCHAR(20) NOT NULL, -- ktp, passport, kitas
+  id_number VARCHAR(50) NOT NULL,
+  id_document_url TEXT NOT NULL, -- referensi file hasil upload (object storage)
+  selfie_url TEXT NOT NULL,      -- selfie dengan dokumen identitas
+  bank_name VARCHAR(100) NOT NULL,
+  bank_account_number VARCHAR(50) NOT NULL,
+  bank_account_name VARCHAR(255) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, needs_info, approved, rejected
+  review_note TEXT, -- alasan reject / info yang diminta
+  reviewed_by INTEGER REFERENCES users(id),
+  reviewed_at TIMESTAMP,
+  submitted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_seller_applications_uuid ON seller_applications(uuid);
+CREATE INDEX IF NOT EXISTS idx_seller_applications_user_id ON seller_applications(user_id);
+CREATE INDEX IF NOT EXISTS idx_seller_applications_status ON seller_applications(status, submitted_at);
+
+-- Satu pengajuan terbuka per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_seller_applications_open
+  ON seller_applications(user_id) WHERE status IN ('pending', 'needs_info');
+
+COMMENT ON TABLE seller_applications IS 'Pengajuan seller + KYC, direview user dengan permission seller.review';
+
+-- ================================
+-- PERMISSIONS
+-- ================================
+INSERT INTO permissions (name, description, module) VALUES
+  ('seller.view', 'View seller applications', 'seller'),
+  ('seller.review', 'Approve/reject seller applications', 'seller')
+ON CONFLICT (name) DO UPDATE SET
+  description = EXCLUDED.description,
+  module = EXCLUDED.module,
+  updated_at = NOW();
+
+-- Ops mereview pengajuan seller
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'ops' AND p.name LIKE 'seller.%'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Admin: view all
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'seller.view'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
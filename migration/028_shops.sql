@@ -0,0 +1,60 @@
+-- Migration: Shops
+-- Storefront milik seller (satu toko per seller); produk terhubung ke toko lewat shop_id
+
+-- ================================
+-- SHOPS
+-- ================================
+CREATE TABLE IF NOT EXISTS shops (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  owner_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  slug VARCHAR(120) NOT NULL,
+  logo_url TEXT,
+  description TEXT,
+  location VARCHAR(255), -- kota / kabupaten asal pengiriman
+  status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, suspended
+  suspend_reason TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shops_uuid ON shops(uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shops_slug ON shops(slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shops_owner_user_id ON shops(owner_user_id);
+CREATE INDEX IF NOT EXISTS idx_shops_status ON shops(status);
+
+COMMENT ON TABLE shops IS 'Toko seller, halaman publik di /api/shops/:slug';
+
+-- ================================
+-- UPDATE PRODUCTS TABLE
+-- ================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS shop_id INTEGER REFERENCES shops(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_shop_id ON products(shop_id, status);
+
+-- ================================
+-- BACKFILL: toko untuk seller yang sudah ada
+-- ================================
+-- Nama dari pengajuan seller yang disetujui, fallback ke nama user; slug diberi suffix user id agar unik
+INSERT INTO shops (owner_user_id, name, slug, description)
+SELECT o.owner_user_id, o.name,
+       COALESCE(NULLIF(TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(o.name), '[^a-z0-9]+', '-', 'g')), ''), 'toko')
+         || '-' || o.owner_user_id,
+       o.description
+FROM (
+  SELECT DISTINCT ON (u.id) u.id AS owner_user_id,
+         LEFT(COALESCE(sa.shop_name, NULLIF(u.full_name, ''), 'Toko'), 100) AS name,
+         sa.shop_description AS description
+  FROM users u
+  LEFT JOIN seller_applications sa ON sa.user_id = u.id AND sa.status = 'approved'
+  WHERE EXISTS (SELECT 1 FROM products p WHERE p.owner_user_id = u.id)
+     OR EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON ur.role_id = r.id
+                WHERE ur.user_id = u.id AND r.name = 'seller')
+  ORDER BY u.id, sa.reviewed_at DESC
+) o
+ON CONFLICT DO NOTHING;
+
+UPDATE products p SET shop_id = s.id
+FROM shops s
+WHERE s.owner_user_id = p.owner_user_id AND p.shop_id IS NULL;
@@ -0,0 +1,40 @@
+-- Migration: Shop Members
+-- Staff toko diundang owner lewat email (invite_tokens), dengan permission level toko
+
+-- ================================
+-- SHOP_MEMBERS
+-- ================================
+CREATE TABLE IF NOT EXISTS shop_members (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  shop_id INTEGER NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(20) NOT NULL DEFAULT 'staff', -- owner, staff
+  permissions TEXT[] NOT NULL DEFAULT '{}', -- product.manage, order.view, wallet.view (owner: semua)
+  status VARCHAR(20) NOT NULL DEFAULT 'invited', -- invited, active
+  invited_by INTEGER REFERENCES users(id),
+  invited_at TIMESTAMP,
+  joined_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shop_members_uuid ON shop_members(uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shop_members_shop_user ON shop_members(shop_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_shop_members_user_id ON shop_members(user_id, status);
+
+COMMENT ON TABLE shop_members IS 'Owner + staff toko; akses route /app/my ditentukan dari keanggotaan ini';
+
+-- ================================
+-- UPDATE INVITE_TOKENS TABLE
+-- ================================
+-- Token undangan staff toko memakai tabel yang sama dengan undangan dashboard
+ALTER TABLE invite_tokens ADD COLUMN IF NOT EXISTS shop_member_id INTEGER REFERENCES shop_members(id) ON DELETE CASCADE;
+
+-- ================================
+-- BACKFILL: owner toko yang sudah ada
+-- ================================
+INSERT INTO shop_members (shop_id, user_id, role, status, joined_at)
+SELECT s.id, s.owner_user_id, 'owner', 'active', s.created_at
+FROM shops s
+ON CONFLICT (shop_id, user_id) DO NOTHING;
@@ -0,0 +1,70 @@
+-- Migration: Product Variants
+-- Option type per produk (ukuran, warna) + varian dengan SKU, harga, stock dan gambar sendiri.
+-- products.price / price_max / stock menjadi agregat varian aktif (dihitung ulang setiap matrix disimpan)
+
+-- ================================
+-- UPDATE PRODUCTS TABLE
+-- ================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS has_variants BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_max BIGINT; -- NULL jika tanpa varian
+
+COMMENT ON COLUMN products.price IS 'Harga produk, atau harga varian aktif termurah jika has_variants';
+COMMENT ON COLUMN products.stock IS 'Stock produk, atau total stock varian aktif jika has_variants';
+
+-- ================================
+-- PRODUCT_OPTIONS
+-- ================================
+CREATE TABLE IF NOT EXISTS product_options (
+  id SERIAL PRIMARY KEY,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,      -- mis. Ukuran, Warna
+  option_values TEXT[] NOT NULL,  -- mis. {S,M,L,XL}
+  position SMALLINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(product_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_options_product_id ON product_options(product_id, position);
+
+-- ================================
+-- PRODUCT_VARIANTS
+-- ================================
+CREATE TABLE IF NOT EXISTS product_variants (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  sku VARCHAR(64) NOT NULL,
+  options JSONB NOT NULL DEFAULT '{}', -- {"Ukuran": "M", "Warna": "Merah"}
+  price BIGINT NOT NULL CHECK (price >= 0),
+  stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+  images TEXT[] NOT NULL DEFAULT '{}',
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  position INTEGER NOT NULL DEFAULT 0,
+  deleted_at TIMESTAMP, -- soft delete, varian lama tetap dirujuk transactions
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_uuid ON product_variants(uuid);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_sku
+  ON product_variants(product_id, sku) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_options
+  ON product_variants(product_id, options) WHERE deleted_at IS NULL;
+
+COMMENT ON TABLE product_variants IS 'Kombinasi option produk dengan SKU, harga & stock sendiri';
+
+-- ================================
+-- UPDATE CART_ITEMS TABLE
+-- ================================
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE;
+
+-- Satu baris per produk + varian
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_cart_id_product_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_cart_product_variant
+  ON cart_items(cart_id, product_id, (COALESCE(variant_id, 0)));
+
+-- ================================
+-- UPDATE TRANSACTIONS TABLE
+-- ================================
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id);
@@ -0,0 +1,49 @@
+-- Migration: Product Search
+-- Full-text search produk (title bobot A, description bobot B) dengan stemming bahasa Indonesia,
+-- plus pg_trgm untuk toleransi typo pada title. search_vector diisi worker product:index.
+
+-- ================================
+-- EXTENSIONS
+-- ================================
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- ================================
+-- TEXT SEARCH CONFIGURATION
+-- ================================
+-- Snowball stemmer indonesian (me-/di-/-kan/-nya, dst.); fallback ke simple jika tidak tersedia
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'shopedia_id') THEN
+    IF EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'indonesian') THEN
+      CREATE TEXT SEARCH CONFIGURATION shopedia_id (COPY = pg_catalog.indonesian);
+    ELSE
+      CREATE TEXT SEARCH CONFIGURATION shopedia_id (COPY = pg_catalog.simple);
+    END IF;
+  END IF;
+END
+$$;
+
+-- Dipakai migration (backfill) dan worker agar bobot dokumen selalu sama
+CREATE OR REPLACE FUNCTION product_search_vector(title TEXT, description TEXT)
+RETURNS tsvector
+LANGUAGE SQL IMMUTABLE
+AS $$
+  SELECT setweight(to_tsvector('shopedia_id', COALESCE(title, '')), 'A') ||
+         setweight(to_tsvector('shopedia_id', COALESCE(description, '')), 'B')
+$$;
+
+-- ================================
+-- UPDATE PRODUCTS TABLE
+-- ================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+COMMENT ON COLUMN products.search_vector IS 'Dokumen full-text (title A, description B), diperbarui worker product:index';
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_title_trgm ON products USING GIN (title gin_trgm_ops);
+
+-- ================================
+-- BACKFILL
+-- ================================
+UPDATE products SET search_vector = product_search_vector(title, description)
+WHERE deleted_at IS NULL AND search_vector IS NULL;
@@ -0,0 +1,27 @@
+-- Migration: Product Catalog Filters
+-- Kolom agregat untuk sort katalog public: jumlah terjual (best-selling)
+
+-- ================================
+-- UPDATE PRODUCTS TABLE
+-- ================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sold_count INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN products.sold_count IS 'Qty terjual dari transaksi completed, dikurangi qty yang di-refund';
+
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_products_sold_count ON products(sold_count DESC) WHERE deleted_at IS NULL;
+
+-- Filter lokasi toko (ILIKE, pg_trgm dari migration 031)
+CREATE INDEX IF NOT EXISTS idx_shops_location_trgm ON shops USING GIN (location gin_trgm_ops);
+
+-- ================================
+-- BACKFILL: jumlah terjual
+-- ================================
+UPDATE products p SET sold_count = t.sold
+FROM (
+  SELECT product_id, SUM(qty - refunded_qty) AS sold
+  FROM transactions
+  WHERE status IN ('completed', 'partially_refunded', 'refunded')
+  GROUP BY product_id
+) t
+WHERE p.id = t.product_id;
@@ -0,0 +1,22 @@
+-- Migration: User Email Verified
+-- Membedakan registrasi yang belum verifikasi OTP dari akun yang dinonaktifkan admin
+-- (keduanya is_active = FALSE); social login hanya boleh mengaktifkan yang pertama
+
+-- ================================
+-- UPDATE USERS TABLE
+-- ================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP; -- NULL = registrasi belum verifikasi
+
+-- ================================
+-- BACKFILL
+-- ================================
+-- Akun aktif, atau yang punya role selain end_user (user dashboard / seller yang dinonaktifkan)
+UPDATE users u SET email_verified_at = COALESCE(u.updated_at, u.created_at)
+WHERE u.email_verified_at IS NULL AND (
+  u.is_active = TRUE
+  OR EXISTS (
+    SELECT 1 FROM user_roles ur
+    JOIN roles r ON ur.role_id = r.id
+    WHERE ur.user_id = u.id AND r.name <> 'end_user'
+  )
+);
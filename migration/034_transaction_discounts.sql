@@ -0,0 +1,28 @@
+-- Migration: Transaction Discounts
+-- Diskon promo dialokasikan per baris transaksi saat checkout (hanya baris yang masuk scope promo),
+-- sehingga refund buyer memakai diskon baris itu sendiri, bukan rata-rata diskon order
+
+-- ================================
+-- UPDATE TRANSACTIONS TABLE
+-- ================================
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN transactions.discount_amount IS 'Bagian diskon promo order untuk baris ini (ditanggung platform)';
+
+-- ================================
+-- BACKFILL
+-- ================================
+-- Order lama tidak menyimpan item yang masuk scope: diskon dibagi proporsional ke semua baris
+-- (perilaku refund sebelumnya), sisa pembulatan ke baris terakhir
+UPDATE transactions t
+SET discount_amount = a.share + CASE WHEN a.rn = 1 THEN a.order_discount - a.total_share ELSE 0 END
+FROM (
+  SELECT t.id, o.discount_amount AS order_discount,
+    o.discount_amount * t.total_price / o.subtotal_price AS share,
+    SUM(o.discount_amount * t.total_price / o.subtotal_price) OVER (PARTITION BY t.order_id) AS total_share,
+    ROW_NUMBER() OVER (PARTITION BY t.order_id ORDER BY t.id DESC) AS rn
+  FROM transactions t
+  JOIN orders o ON t.order_id = o.id
+  WHERE o.discount_amount > 0 AND o.subtotal_price > 0
+) a
+WHERE t.id = a.id AND t.discount_amount = 0;
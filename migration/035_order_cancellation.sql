@@ -0,0 +1,13 @@
+-- Migration: Order Cancellation
+-- Order pending bisa dibatalkan buyer atau kedaluwarsa otomatis; stock & promo dikembalikan
+
+-- ================================
+-- UPDATE ORDERS TABLE
+-- ================================
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancel_reason VARCHAR(20); -- buyer, expired
+
+-- Job expiry hanya memindai order yang masih pending
+CREATE INDEX IF NOT EXISTS idx_orders_pending_created_at ON orders(created_at) WHERE status = 'pending';
+
+COMMENT ON COLUMN orders.cancel_reason IS 'buyer = dibatalkan buyer, expired = melewati batas waktu order pending';
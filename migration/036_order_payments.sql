@@ -0,0 +1,29 @@
+-- Migration: Order Payments
+-- Order harus dibayar (dikonfirmasi finance / payment callback) sebelum buyer bisa menyelesaikannya;
+-- saldo seller hanya dikreditkan dari order yang sudah dibayar.
+-- Alur status: pending -> paid -> completed (pending juga bisa -> cancelled)
+
+-- ================================
+-- UPDATE ORDERS TABLE
+-- ================================
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS paid_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS paid_by INTEGER REFERENCES users(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_reference VARCHAR(255);
+
+COMMENT ON COLUMN orders.paid_by IS 'User dashboard yang mengonfirmasi pembayaran';
+COMMENT ON COLUMN orders.payment_reference IS 'Referensi pembayaran (no. mutasi bank / id transaksi payment gateway)';
+
+-- ================================
+-- PERMISSIONS
+-- ================================
+INSERT INTO permissions (name, description, module) VALUES
+  ('finance.payment', 'Confirm order payments', 'finance')
+ON CONFLICT (name) DO UPDATE SET
+  description = EXCLUDED.description,
+  module = EXCLUDED.module,
+  updated_at = NOW();
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'finance' AND p.name = 'finance.payment'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
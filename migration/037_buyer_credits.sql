@@ -0,0 +1,57 @@
+-- Migration: Buyer Credits
+-- Saldo refund buyer dipisah dari saldo penjualan seller (balances), sehingga refund
+-- tidak ikut bisa ditarik lewat payout dan terlihat oleh buyer di /app/credits
+
+-- ================================
+-- BUYER_CREDITS
+-- ================================
+CREATE TABLE IF NOT EXISTS buyer_credits (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  amount BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ================================
+-- BUYER_CREDIT_LOGS
+-- ================================
+CREATE TABLE IF NOT EXISTS buyer_credit_logs (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  transaction_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL,
+  amount BIGINT NOT NULL CHECK (amount >= 0),
+  type VARCHAR(20) NOT NULL, -- 'in' atau 'out'
+  description TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_buyer_credit_logs_uuid ON buyer_credit_logs(uuid);
+CREATE INDEX IF NOT EXISTS idx_buyer_credit_logs_user_id ON buyer_credit_logs(user_id, created_at DESC);
+
+COMMENT ON TABLE buyer_credits IS 'Saldo refund buyer, terpisah dari balances (saldo penjualan seller)';
+
+-- ================================
+-- BACKFILL: pindahkan refund buyer dari balance_logs
+-- ================================
+-- Entry 'in' milik buyer transaksi itu sendiri = kredit refund (seller tidak bisa membeli produknya sendiri)
+CREATE TEMP TABLE moved_buyer_refunds AS
+SELECT bl.id, bl.uuid, bl.user_id, bl.transaction_id, bl.amount, bl.description, bl.created_at
+FROM balance_logs bl
+JOIN transactions t ON bl.transaction_id = t.id
+WHERE bl.type = 'in' AND bl.user_id = t.buyer_user_id;
+
+INSERT INTO buyer_credit_logs (uuid, user_id, transaction_id, amount, type, description, created_at)
+SELECT uuid, user_id, transaction_id, amount, 'in', description, created_at FROM moved_buyer_refunds;
+
+INSERT INTO buyer_credits (user_id, amount)
+SELECT user_id, SUM(amount) FROM moved_buyer_refunds GROUP BY user_id
+ON CONFLICT (user_id) DO UPDATE SET amount = buyer_credits.amount + EXCLUDED.amount, updated_at = NOW();
+
+UPDATE balances b SET amount = b.amount - m.amount, updated_at = NOW()
+FROM (SELECT user_id, SUM(amount) AS amount FROM moved_buyer_refunds GROUP BY user_id) m
+WHERE b.user_id = m.user_id;
+
+DELETE FROM balance_logs WHERE id IN (SELECT id FROM moved_buyer_refunds);
+
+DROP TABLE moved_buyer_refunds;
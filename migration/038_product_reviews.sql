@@ -0,0 +1,32 @@
+-- Migration: Product Reviews
+-- Ulasan buyer per transaksi yang sudah completed; agregat rating disimpan di products
+-- untuk filter `rating` katalog public
+
+-- ================================
+-- PRODUCT_REVIEWS
+-- ================================
+CREATE TABLE IF NOT EXISTS product_reviews (
+  id SERIAL PRIMARY KEY,
+  uuid UUID DEFAULT gen_random_uuid(),
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  comment TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_reviews_uuid ON product_reviews(uuid);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_id ON product_reviews(product_id, created_at DESC);
+
+COMMENT ON TABLE product_reviews IS 'Ulasan buyer, satu per transaksi completed';
+
+-- ================================
+-- UPDATE PRODUCTS TABLE
+-- ================================
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_avg NUMERIC(2,1); -- NULL jika belum ada ulasan
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN products.rating_avg IS 'Rata-rata rating product_reviews (1.0 - 5.0)';
+
+CREATE INDEX IF NOT EXISTS idx_products_rating_avg ON products(rating_avg) WHERE deleted_at IS NULL;